@@ -0,0 +1,94 @@
+package checkin
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"skulla-api/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RotationInterval is how long a check-in token is displayed before the next
+// one replaces it. Tokens stay valid for one extra interval so a scan started
+// just before rotation still succeeds.
+const RotationInterval = 20 * time.Second
+
+var (
+	ErrInvalidToken = errors.New("invalid check-in token")
+	ErrExpiredToken = errors.New("check-in token expired")
+)
+
+// NewSecret returns a random key for signing a window's tokens.
+func NewSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func rotation(at time.Time) int64 {
+	return at.Unix() / int64(RotationInterval/time.Second)
+}
+
+func sign(secret string, windowID uint, step int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%d", windowID, step)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Token returns the token to display for window at now and the time it is
+// replaced by the next one. Tokens have the form <window>.<rotation>.<signature>.
+func Token(window db.CheckInWindow, now time.Time) (string, time.Time) {
+	step := rotation(now)
+	token := fmt.Sprintf("%d.%d.%s", window.ID, step, sign(window.Secret, window.ID, step))
+	expiresAt := time.Unix((step+1)*int64(RotationInterval/time.Second), 0)
+	return token, expiresAt
+}
+
+func parse(token string) (uint, int64, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, 0, "", ErrInvalidToken
+	}
+	windowID, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, "", ErrInvalidToken
+	}
+	step, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, "", ErrInvalidToken
+	}
+	return uint(windowID), step, parts[2], nil
+}
+
+// WindowID returns the window a token claims to belong to, without verifying
+// its signature.
+func WindowID(token string) (uint, error) {
+	windowID, _, _, err := parse(token)
+	return windowID, err
+}
+
+// Verify checks that token was signed for window and belongs to the current
+// or previous rotation at now.
+func Verify(window db.CheckInWindow, token string, now time.Time) error {
+	windowID, step, signature, err := parse(token)
+	if err != nil {
+		return err
+	}
+	if windowID != window.ID || !hmac.Equal([]byte(signature), []byte(sign(window.Secret, windowID, step))) {
+		return ErrInvalidToken
+	}
+
+	current := rotation(now)
+	if step > current || step < current-1 {
+		return ErrExpiredToken
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package checkin
+
+import (
+	"skulla-api/db"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenRotation(t *testing.T) {
+	window := db.CheckInWindow{ID: 7, Secret: "secret"}
+	now := time.Unix(1700000000, 0)
+
+	token, expiresAt := Token(window, now)
+	if !strings.HasPrefix(token, "7.") {
+		t.Errorf("Expected token to start with window ID, got %s", token)
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > RotationInterval {
+		t.Errorf("Unexpected expiry %v for %v", expiresAt, now)
+	}
+
+	if err := Verify(window, token, now); err != nil {
+		t.Errorf("Expected current token to verify, got %v", err)
+	}
+	if err := Verify(window, token, now.Add(RotationInterval)); err != nil {
+		t.Errorf("Expected previous rotation to verify, got %v", err)
+	}
+	if err := Verify(window, token, now.Add(2*RotationInterval)); err != ErrExpiredToken {
+		t.Errorf("Expected ErrExpiredToken, got %v", err)
+	}
+	if err := Verify(window, token, now.Add(-RotationInterval)); err != ErrExpiredToken {
+		t.Errorf("Expected future token to be rejected, got %v", err)
+	}
+
+	next, _ := Token(window, now.Add(RotationInterval))
+	if next == token {
+		t.Error("Expected token to rotate")
+	}
+}
+
+func TestVerifyRejectsForgedTokens(t *testing.T) {
+	window := db.CheckInWindow{ID: 7, Secret: "secret"}
+	now := time.Unix(1700000000, 0)
+	token, _ := Token(window, now)
+
+	otherWindow := db.CheckInWindow{ID: 8, Secret: "secret"}
+	forged, _ := Token(db.CheckInWindow{ID: 7, Secret: "other"}, now)
+
+	testCases := []struct {
+		window db.CheckInWindow
+		token  string
+	}{
+		{window, forged},
+		{window, "7.1.abc"},
+		{window, "not-a-token"},
+		{otherWindow, token},
+	}
+	for _, tc := range testCases {
+		if err := Verify(tc.window, tc.token, now); err != ErrInvalidToken {
+			t.Errorf("Expected ErrInvalidToken for %q, got %v", tc.token, err)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg, err := SVG("7.85000000.signature")
+	if err != nil {
+		t.Fatalf("SVG failed: %v", err)
+	}
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "<path d=\"M") {
+		t.Errorf("Unexpected SVG output: %s", svg[:50])
+	}
+}
@@ -0,0 +1,451 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+
+	defaultDBUsername = "admin"
+	defaultDBPassword = "admin"
+
+	redacted = "********"
+)
+
+// Config is the whole configuration of the server. Every setting has a
+// default, which CONFIG_FILE and then the environment variable of its env
+// tag override. Settings tagged secret are redacted by Redacted.
+type Config struct {
+	Environment   string        `yaml:"environment" env:"APP_ENV"`
+	Server        Server        `yaml:"server"`
+	Metrics       Metrics       `yaml:"metrics"`
+	Tracing       Tracing       `yaml:"tracing"`
+	Database      Database      `yaml:"database"`
+	Auth          Auth          `yaml:"auth"`
+	Tenant        Tenant        `yaml:"tenant"`
+	Idempotency   Idempotency   `yaml:"idempotency"`
+	Alerts        Alerts        `yaml:"alerts"`
+	Notifications Notifications `yaml:"notifications"`
+	Webhooks      Webhooks      `yaml:"webhooks"`
+	Live          Live          `yaml:"live"`
+	Sync          Sync          `yaml:"sync"`
+}
+
+// Server configures the HTTP server. On SIGTERM in-flight requests get
+// ShutdownTimeout to finish.
+type Server struct {
+	Port            int           `yaml:"port" env:"SERVER_PORT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// Metrics configures the port /metrics is served on, apart from the API so
+// it is only reachable where that port is exposed.
+type Metrics struct {
+	Port int `yaml:"port" env:"METRICS_PORT"`
+}
+
+// Tracing configures OpenTelemetry. Spans are exported over OTLP/HTTP to
+// Endpoint, the collector's base URL, and not recorded when it is empty.
+// SampleRatio is the share of new traces kept; traces started upstream follow
+// the caller's decision.
+type Tracing struct {
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+// Database selects the driver and where to connect. URL, when set, replaces
+// the other connection settings. Connecting is attempted ConnectAttempts
+// times, waiting ConnectBackoff after the first failure and twice as long
+// after each next one.
+type Database struct {
+	Driver          string        `yaml:"driver" env:"DB_DRIVER"`
+	URL             string        `yaml:"url" env:"DATABASE_URL" secret:"true"`
+	Host            string        `yaml:"host" env:"DB_HOST"`
+	Port            int           `yaml:"port" env:"DB_PORT"`
+	Name            string        `yaml:"name" env:"DB_NAME"`
+	Username        string        `yaml:"username" env:"DB_USERNAME"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	ConnectAttempts int           `yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+}
+
+// Auth configures token verification. TestMode accepts tokens signed with the
+// fixed "test-secret" instead of Supabase's keys.
+type Auth struct {
+	SupabaseURL string `yaml:"supabase_url" env:"SUPABASE_URL"`
+	TestMode    bool   `yaml:"test_mode" env:"TEST_MODE"`
+}
+
+type Tenant struct {
+	BaseDomain string `yaml:"base_domain" env:"TENANT_BASE_DOMAIN"`
+}
+
+type Idempotency struct {
+	KeyTTL          time.Duration `yaml:"key_ttl" env:"IDEMPOTENCY_KEY_TTL"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"IDEMPOTENCY_CLEANUP_INTERVAL"`
+	InFlightLease   time.Duration `yaml:"in_flight_lease" env:"IDEMPOTENCY_IN_FLIGHT_LEASE"`
+}
+
+type Alerts struct {
+	EvaluationInterval  time.Duration `yaml:"evaluation_interval" env:"ALERT_EVALUATION_INTERVAL"`
+	WindowDays          int           `yaml:"window_days" env:"ALERT_WINDOW_DAYS"`
+	MinSessions         int           `yaml:"min_sessions" env:"ALERT_MIN_SESSIONS"`
+	MinPercentage       float64       `yaml:"min_percentage" env:"ALERT_MIN_PERCENTAGE"`
+	CriticalPercentage  float64       `yaml:"critical_percentage" env:"ALERT_CRITICAL_PERCENTAGE"`
+	ConsecutiveAbsences int           `yaml:"consecutive_absences" env:"ALERT_CONSECUTIVE_ABSENCES"`
+	MonthlyLates        int           `yaml:"monthly_lates" env:"ALERT_MONTHLY_LATES"`
+}
+
+// Notifications configures guardian notifications. A channel is enabled when
+// its host or URL is set.
+type Notifications struct {
+	Interval    time.Duration `yaml:"interval" env:"NOTIFICATION_INTERVAL"`
+	DigestHour  int           `yaml:"digest_hour" env:"NOTIFICATION_DIGEST_HOUR"`
+	MaxAttempts int           `yaml:"max_attempts" env:"NOTIFICATION_MAX_ATTEMPTS"`
+	MaxAgeDays  int           `yaml:"max_age_days" env:"NOTIFICATION_MAX_AGE_DAYS"`
+	SMTP        SMTP          `yaml:"smtp"`
+	SMS         Gateway       `yaml:"sms"`
+	WhatsApp    Gateway       `yaml:"whatsapp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+}
+
+// Gateway is an HTTP notification gateway. Its env tags are prefixed with
+// the channel's, e.g. SMS_GATEWAY_URL.
+type Gateway struct {
+	URL    string `yaml:"url" env:"URL"`
+	APIKey string `yaml:"api_key" env:"API_KEY" secret:"true"`
+}
+
+type Webhooks struct {
+	Interval    time.Duration `yaml:"interval" env:"WEBHOOK_INTERVAL"`
+	MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	// AllowPrivateHosts lets subscriptions point at loopback and private
+	// addresses, for receivers running next to a development server.
+	AllowPrivateHosts bool `yaml:"allow_private_hosts" env:"WEBHOOK_ALLOW_PRIVATE_HOSTS"`
+}
+
+type Live struct {
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" env:"LIVE_SNAPSHOT_INTERVAL"`
+}
+
+// Sync configures offline sync. Changes younger than ChangeLag are held back
+// from the change feed until the transactions that wrote earlier changes
+// have committed.
+type Sync struct {
+	ChangeLag time.Duration `yaml:"change_lag" env:"SYNC_CHANGE_LAG"`
+}
+
+// envPrefixes prefixes the env tags of nested structs whose fields are
+// shared between sections.
+var envPrefixes = map[string]string{
+	"SMS":      "SMS_GATEWAY_",
+	"WhatsApp": "WHATSAPP_WEBHOOK_",
+}
+
+// Default returns the configuration used when nothing overrides it. Its
+// database credentials are only accepted outside production.
+func Default() Config {
+	return Config{
+		Environment: EnvironmentDevelopment,
+		Server:      Server{Port: 8080, ShutdownTimeout: 30 * time.Second},
+		Metrics:     Metrics{Port: 9090},
+		Tracing:     Tracing{ServiceName: "omniscience-api", SampleRatio: 1},
+		Database: Database{
+			Host:            "localhost",
+			Name:            "omniscience",
+			Username:        defaultDBUsername,
+			Password:        defaultDBPassword,
+			ConnectAttempts: 10,
+			ConnectBackoff:  time.Second,
+		},
+		Auth:        Auth{SupabaseURL: "https://ovqjkfjuzpxhvrjuagpf.supabase.co"},
+		Idempotency: Idempotency{KeyTTL: 24 * time.Hour, CleanupInterval: time.Hour, InFlightLease: time.Minute},
+		Alerts: Alerts{
+			EvaluationInterval:  time.Hour,
+			WindowDays:          30,
+			MinSessions:         5,
+			MinPercentage:       90,
+			CriticalPercentage:  75,
+			ConsecutiveAbsences: 3,
+			MonthlyLates:        5,
+		},
+		Notifications: Notifications{
+			Interval:    time.Minute,
+			DigestHour:  16,
+			MaxAttempts: 5,
+			SMTP:        SMTP{Port: 587},
+		},
+		Webhooks: Webhooks{Interval: 10 * time.Second, MaxAttempts: 8},
+		Live:     Live{SnapshotInterval: 30 * time.Second},
+		Sync:     Sync{ChangeLag: 10 * time.Second},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file named by
+// CONFIG_FILE if set, and then the environment. It does not validate it.
+func Load() (Config, error) {
+	config := Default()
+
+	if path, ok := os.LookupEnv("CONFIG_FILE"); ok {
+		file, err := os.Open(path)
+		if err != nil {
+			return config, fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+		}
+		defer file.Close()
+
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return config, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(&config).Elem(), ""); err != nil {
+		return config, err
+	}
+
+	config.Database.resolveDefaults()
+	return config, nil
+}
+
+// resolveDefaults fills in the driver implied by the scheme of URL, defaulting
+// to MySQL, and the driver's port.
+func (database *Database) resolveDefaults() {
+	database.Driver = strings.ToLower(database.Driver)
+	if database.Driver == "" {
+		database.Driver = DriverMySQL
+		if strings.HasPrefix(database.URL, "postgres://") || strings.HasPrefix(database.URL, "postgresql://") {
+			database.Driver = DriverPostgres
+		}
+	}
+	if database.Port == 0 {
+		database.Port = 3306
+		if database.Driver == DriverPostgres {
+			database.Port = 5432
+		}
+	}
+}
+
+// applyEnv overrides the fields of section with the environment variables of
+// their env tags.
+func applyEnv(section reflect.Value, prefix string) error {
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Field(i)
+		structField := section.Type().Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, prefix+envPrefixes[structField.Name]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := prefix + structField.Tag.Get("env")
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, val); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, val string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(val)
+	case bool:
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case int:
+		parsed, err := strconv.Atoi(val)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+	case float64:
+		parsed, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case time.Duration:
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate returns every problem with the configuration joined in one error,
+// or nil. Production refuses test mode and the default database credentials.
+func (config Config) Validate() error {
+	config.Database.resolveDefaults()
+
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(config.Environment == EnvironmentDevelopment || config.Environment == EnvironmentProduction,
+		"environment must be %s or %s, got %q", EnvironmentDevelopment, EnvironmentProduction, config.Environment)
+	check(validPort(config.Server.Port), "server.port must be between 1 and 65535, got %d", config.Server.Port)
+	check(config.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(validPort(config.Metrics.Port), "metrics.port must be between 1 and 65535, got %d", config.Metrics.Port)
+	check(config.Metrics.Port != config.Server.Port, "metrics.port must differ from server.port")
+	check(config.Tracing.Endpoint == "" || strings.HasPrefix(config.Tracing.Endpoint, "http://") || strings.HasPrefix(config.Tracing.Endpoint, "https://"),
+		"tracing.endpoint must be an http:// or https:// URL")
+	check(config.Tracing.ServiceName != "", "tracing.service_name is required")
+	check(validRatio(config.Tracing.SampleRatio), "tracing.sample_ratio must be between 0 and 1")
+
+	database := config.Database
+	check(database.Driver == DriverMySQL || database.Driver == DriverPostgres,
+		"database.driver must be %s or %s, got %q", DriverMySQL, DriverPostgres, database.Driver)
+	if database.URL == "" {
+		check(database.Host != "", "database.host is required without database.url")
+		check(validPort(database.Port), "database.port must be between 1 and 65535, got %d", database.Port)
+		check(database.Name != "", "database.name is required without database.url")
+	}
+	check(database.ConnectAttempts > 0, "database.connect_attempts must be positive")
+	check(database.ConnectBackoff > 0, "database.connect_backoff must be positive")
+
+	check(config.Auth.TestMode || config.Auth.SupabaseURL != "", "auth.supabase_url is required outside test mode")
+
+	check(config.Idempotency.KeyTTL > 0, "idempotency.key_ttl must be positive")
+	check(config.Idempotency.CleanupInterval > 0, "idempotency.cleanup_interval must be positive")
+	check(config.Idempotency.InFlightLease > 0, "idempotency.in_flight_lease must be positive")
+
+	alerts := config.Alerts
+	check(alerts.EvaluationInterval > 0, "alerts.evaluation_interval must be positive")
+	check(alerts.WindowDays > 0, "alerts.window_days must be positive")
+	check(alerts.MinSessions > 0, "alerts.min_sessions must be positive")
+	check(validPercentage(alerts.MinPercentage), "alerts.min_percentage must be between 0 and 100")
+	check(validPercentage(alerts.CriticalPercentage), "alerts.critical_percentage must be between 0 and 100")
+	check(alerts.CriticalPercentage <= alerts.MinPercentage, "alerts.critical_percentage cannot be above alerts.min_percentage")
+	check(alerts.ConsecutiveAbsences > 0, "alerts.consecutive_absences must be positive")
+	check(alerts.MonthlyLates > 0, "alerts.monthly_lates must be positive")
+
+	notifications := config.Notifications
+	check(notifications.Interval > 0, "notifications.interval must be positive")
+	check(notifications.DigestHour >= 0 && notifications.DigestHour <= 23, "notifications.digest_hour must be between 0 and 23")
+	check(notifications.MaxAgeDays >= 0, "notifications.max_age_days cannot be negative")
+	check(notifications.MaxAttempts > 0, "notifications.max_attempts must be positive")
+	if notifications.SMTP.Host != "" {
+		check(validPort(notifications.SMTP.Port), "notifications.smtp.port must be between 1 and 65535, got %d", notifications.SMTP.Port)
+	}
+
+	check(config.Webhooks.Interval > 0, "webhooks.interval must be positive")
+	check(config.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(config.Live.SnapshotInterval > 0, "live.snapshot_interval must be positive")
+	check(config.Sync.ChangeLag >= 0, "sync.change_lag cannot be negative")
+
+	if config.Environment == EnvironmentProduction {
+		check(!config.Auth.TestMode, "auth.test_mode cannot be enabled in production")
+		check(!config.Webhooks.AllowPrivateHosts, "webhooks.allow_private_hosts cannot be enabled in production")
+		check(databasePassword(database) != defaultDBPassword,
+			"the default database password cannot be used in production")
+	}
+
+	return errors.Join(problems...)
+}
+
+// databasePassword returns the password database connects with, read from
+// its URL when one is set.
+func databasePassword(database Database) string {
+	if database.URL == "" {
+		return database.Password
+	}
+	if parsed, err := url.Parse(database.URL); err == nil && (parsed.Scheme == "postgres" || parsed.Scheme == "postgresql") {
+		password, _ := parsed.User.Password()
+		return password
+	}
+	if database.Driver == DriverMySQL {
+		if dsn, err := mysql.ParseDSN(database.URL); err == nil {
+			return dsn.Passwd
+		}
+	}
+	for _, field := range strings.Fields(database.URL) {
+		if password, found := strings.CutPrefix(field, "password="); found {
+			return strings.Trim(password, "'")
+		}
+	}
+	return ""
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validRatio(ratio float64) bool {
+	return ratio >= 0 && ratio <= 1
+}
+
+func validPercentage(percentage float64) bool {
+	return percentage >= 0 && percentage <= 100
+}
+
+// Redacted returns a copy of the configuration with every secret that is set
+// replaced, safe to print or log.
+func (config Config) Redacted() Config {
+	redactSecrets(reflect.ValueOf(&config).Elem())
+	return config
+}
+
+func redactSecrets(section reflect.Value) {
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Field(i)
+		if field.Kind() == reflect.Struct {
+			redactSecrets(field)
+			continue
+		}
+		if section.Type().Field(i).Tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+}
+
+// YAML renders the configuration in the format of CONFIG_FILE.
+func (config Config) YAML() (string, error) {
+	var content strings.Builder
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return "", err
+	}
+	return content.String(), encoder.Close()
+}
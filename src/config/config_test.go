@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if config.Server.Port != 8080 || config.Database.Driver != DriverMySQL || config.Database.Port != 3306 {
+		t.Errorf("Unexpected defaults: %+v", config)
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected the defaults to be valid in development, got %v", err)
+	}
+}
+
+func TestLoad_EnvironmentOverridesFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `
+server:
+  port: 9000
+alerts:
+  window_days: 14
+  evaluation_interval: 5m
+notifications:
+  sms:
+    url: https://sms.example.com
+`))
+	t.Setenv("ALERT_WINDOW_DAYS", "7")
+	t.Setenv("SMS_GATEWAY_API_KEY", "sms-key")
+	t.Setenv("TEST_MODE", "true")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if config.Server.Port != 9000 || config.Alerts.EvaluationInterval != 5*time.Minute {
+		t.Errorf("Expected settings from the file, got %+v", config)
+	}
+	if config.Alerts.WindowDays != 7 || !config.Auth.TestMode {
+		t.Errorf("Expected the environment to override the file, got %+v", config)
+	}
+	if config.Notifications.SMS.URL != "https://sms.example.com" || config.Notifications.SMS.APIKey != "sms-key" {
+		t.Errorf("Unexpected SMS gateway: %+v", config.Notifications.SMS)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "server:\n  host: example.com\n"))
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an unknown setting")
+	}
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, ""))
+	t.Setenv("DB_PORT", "not-a-port")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "DB_PORT") {
+		t.Errorf("Expected an error naming DB_PORT, got %v", err)
+	}
+}
+
+func TestLoad_DriverFromDatabaseURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://skulla:secret@db:5432/skulla")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if config.Database.Driver != DriverPostgres || config.Database.Port != 5432 {
+		t.Errorf("Expected PostgreSQL on its default port, got %+v", config.Database)
+	}
+}
+
+func TestValidate_Production(t *testing.T) {
+	config := Default()
+	config.Environment = EnvironmentProduction
+	config.Auth.TestMode = true
+
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "test_mode") || !strings.Contains(err.Error(), "default database password") {
+		t.Fatalf("Expected test mode and the default password to be refused, got %v", err)
+	}
+
+	config.Auth.TestMode = false
+	config.Database.Username = "root"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "default database password") {
+		t.Errorf("Expected the default password to be refused for any username, got %v", err)
+	}
+
+	config.Database.Password = "a-real-password"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected a valid production configuration, got %v", err)
+	}
+}
+
+func TestValidate_ProductionDatabaseURL(t *testing.T) {
+	testCases := []struct {
+		name   string
+		driver string
+		url    string
+		valid  bool
+	}{
+		{"mysql default", DriverMySQL, "admin:admin@tcp(db:3306)/skulla", false},
+		{"mysql own", DriverMySQL, "admin:s3cret@tcp(db:3306)/skulla", true},
+		{"postgres url default", DriverPostgres, "postgres://skulla:admin@db:5432/skulla", false},
+		{"postgres url own", DriverPostgres, "postgres://skulla:s3cret@db:5432/skulla", true},
+		{"postgres keywords default", DriverPostgres, "host=db user=skulla password=admin dbname=skulla", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := Default()
+			config.Environment = EnvironmentProduction
+			config.Database.Driver = tc.driver
+			config.Database.URL = tc.url
+
+			err := config.Validate()
+			if tc.valid && err != nil {
+				t.Errorf("Expected %q to be accepted, got %v", tc.url, err)
+			}
+			if !tc.valid && (err == nil || !strings.Contains(err.Error(), "default database password")) {
+				t.Errorf("Expected the default password in %q to be refused, got %v", tc.url, err)
+			}
+		})
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	config := Default()
+	config.Server.Port = 0
+	config.Alerts.MinPercentage = 120
+	config.Notifications.DigestHour = 24
+	config.Metrics.Port = 0
+	config.Tracing.Endpoint = "otel-collector:4318"
+	config.Tracing.SampleRatio = 2
+
+	err := config.Validate()
+	for _, setting := range []string{"server.port", "metrics.port", "tracing.endpoint", "tracing.sample_ratio", "alerts.min_percentage", "notifications.digest_hour"} {
+		if err == nil || !strings.Contains(err.Error(), setting) {
+			t.Errorf("Expected a problem with %s, got %v", setting, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	config := Default()
+	config.Notifications.SMTP.Password = "smtp-password"
+
+	content, err := config.Redacted().YAML()
+	if err != nil {
+		t.Fatalf("YAML failed: %v", err)
+	}
+	for _, secret := range []string{"smtp-password", "password: admin"} {
+		if strings.Contains(content, secret) {
+			t.Errorf("Expected %q to be redacted:\n%s", secret, content)
+		}
+	}
+	if !strings.Contains(content, "username: admin") || !strings.Contains(content, `api_key: ""`) {
+		t.Errorf("Expected settings that are not secret or not set to be printed:\n%s", content)
+	}
+	if config.Notifications.SMTP.Password != "smtp-password" {
+		t.Error("Expected Redacted to leave the original configuration unchanged")
+	}
+}
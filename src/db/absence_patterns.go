@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type WeekdayAbsenceCount struct {
+	Weekday string `json:"weekday"`
+	Count   int    `json:"count"`
+}
+
+type AbsencePatterns struct {
+	LongestAbsenceStreak       int                   `json:"longestAbsenceStreak"`
+	CurrentAbsenceStreak       int                   `json:"currentAbsenceStreak"`
+	AbsencesByWeekday          []WeekdayAbsenceCount `json:"absencesByWeekday"`
+	AbsencesAdjacentToWeekends int                   `json:"absencesAdjacentToWeekends"`
+	AbsencesAdjacentToHolidays int                   `json:"absencesAdjacentToHolidays"`
+	FirstAbsenceDate           string                `json:"firstAbsenceDate,omitempty"`
+	LastAbsenceDate            string                `json:"lastAbsenceDate,omitempty"`
+}
+
+var weekdayOrder = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+// schoolDays returns the dates within [startDate, endDate] on which the classes
+// recorded attendance, leaving out draft registers unless includeDrafts is set.
+func schoolDays(ctx context.Context, studentClassIDs []uint, startDate string, endDate string, includeDrafts bool) map[string]bool {
+	days := make(map[string]bool)
+	if len(studentClassIDs) == 0 {
+		return days
+	}
+
+	var dates []string
+	submittedSummaries(conn(ctx), includeDrafts).
+		Where(`"DailyClassAttendanceSummary".student_class_id IN ?`, studentClassIDs).
+		Where(`"DailyClassAttendanceSummary".date >= ?`, startDate).
+		Where(`"DailyClassAttendanceSummary".date <= ?`, endDate).
+		Distinct().
+		Pluck(`"DailyClassAttendanceSummary".date`, &dates)
+
+	for _, date := range dates {
+		days[normalizeDate(date)] = true
+	}
+	return days
+}
+
+// getAbsencePatterns derives streaks and calendar patterns from records, which
+// must be in date order. A holiday is a weekday missing from days.
+func getAbsencePatterns(records []AttendanceRecord, days map[string]bool) AbsencePatterns {
+	patterns := AbsencePatterns{}
+	weekdayCounts := make(map[time.Weekday]int)
+
+	var absences []time.Time
+	streak := 0
+	for _, record := range records {
+		if record.Status != "ABSENT" {
+			streak = 0
+			continue
+		}
+
+		streak++
+		if streak > patterns.LongestAbsenceStreak {
+			patterns.LongestAbsenceStreak = streak
+		}
+
+		date, err := parseDate(record.Date)
+		if err != nil {
+			continue
+		}
+		absences = append(absences, date)
+		weekdayCounts[date.Weekday()]++
+	}
+	patterns.CurrentAbsenceStreak = streak
+
+	for _, weekday := range weekdayOrder {
+		patterns.AbsencesByWeekday = append(patterns.AbsencesByWeekday, WeekdayAbsenceCount{
+			Weekday: weekday.String(),
+			Count:   weekdayCounts[weekday],
+		})
+	}
+
+	if len(absences) == 0 {
+		return patterns
+	}
+
+	first, last := absences[0], absences[len(absences)-1]
+	patterns.FirstAbsenceDate = first.Format(dateLayout)
+	patterns.LastAbsenceDate = last.Format(dateLayout)
+
+	var firstSchoolDay, lastSchoolDay string
+	for day := range days {
+		if firstSchoolDay == "" || day < firstSchoolDay {
+			firstSchoolDay = day
+		}
+		if day > lastSchoolDay {
+			lastSchoolDay = day
+		}
+	}
+
+	isHoliday := func(date time.Time) bool {
+		day := date.Format(dateLayout)
+		return !isWeekend(date) && !days[day] && day > firstSchoolDay && day < lastSchoolDay
+	}
+
+	for _, absence := range absences {
+		before, after := absence.AddDate(0, 0, -1), absence.AddDate(0, 0, 1)
+		if isWeekend(before) || isWeekend(after) {
+			patterns.AbsencesAdjacentToWeekends++
+		}
+		if isHoliday(before) || isHoliday(after) {
+			patterns.AbsencesAdjacentToHolidays++
+		}
+	}
+
+	return patterns
+}
@@ -0,0 +1,38 @@
+package db
+
+import "testing"
+
+func TestGetAbsencePatterns_HolidaysFromCalendar(t *testing.T) {
+	records := []AttendanceRecord{
+		{Date: "2024-01-17", Status: "PRESENT"},
+		{Date: "2024-01-19", Status: "ABSENT"},
+		{Date: "2024-01-22", Status: "ABSENT"},
+	}
+
+	testCases := []struct {
+		name     string
+		days     []string
+		holidays int
+	}{
+		{"thursday off", []string{"2024-01-17", "2024-01-19", "2024-01-22", "2024-01-23"}, 1},
+		{"no day off", []string{"2024-01-17", "2024-01-18", "2024-01-19", "2024-01-22", "2024-01-23"}, 0},
+		{"nothing recorded after", []string{"2024-01-17", "2024-01-19", "2024-01-22"}, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			days := make(map[string]bool)
+			for _, day := range tc.days {
+				days[day] = true
+			}
+
+			patterns := getAbsencePatterns(records, days)
+			if patterns.AbsencesAdjacentToHolidays != tc.holidays {
+				t.Errorf("Expected %d absences adjacent to holidays, got %d", tc.holidays, patterns.AbsencesAdjacentToHolidays)
+			}
+			if patterns.AbsencesAdjacentToWeekends != 2 {
+				t.Errorf("Expected 2 absences adjacent to weekends, got %d", patterns.AbsencesAdjacentToWeekends)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const ActiveRegistrationStatus = "ACTIVE"
+
+const (
+	AlertRuleLowAttendance       = "LOW_ATTENDANCE"
+	AlertRuleConsecutiveAbsences = "CONSECUTIVE_ABSENCES"
+	AlertRuleMonthlyLates        = "MONTHLY_LATES"
+)
+
+const (
+	AlertSeverityLow    = "LOW"
+	AlertSeverityMedium = "MEDIUM"
+	AlertSeverityHigh   = "HIGH"
+)
+
+type Alert struct {
+	ID             uint         `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint         `gorm:"not null;index:idx_alert_school_id"`
+	RegistrationID uint         `gorm:"not null;index:idx_alert_registration_rule"`
+	Registration   Registration `gorm:"foreignKey:RegistrationID"`
+	StudentClassID uint         `gorm:"not null;index:idx_alert_student_class_id"`
+	Rule           string       `gorm:"size:50;not null;index:idx_alert_registration_rule"`
+	Severity       string       `gorm:"size:20;not null"`
+	Message        string       `gorm:"type:text"`
+	TriggeredOn    string       `gorm:"type:date;not null"`
+	AcknowledgedBy string       `gorm:"size:500"`
+	AcknowledgedAt *time.Time
+	ClearedAt      *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (Alert) TableName() string {
+	return "Alert"
+}
+
+// AlertRules holds the thresholds EvaluateAlerts checks every active
+// registration against.
+type AlertRules struct {
+	WindowDays          int
+	MinSessions         int
+	MinPercentage       float64
+	CriticalPercentage  float64
+	ConsecutiveAbsences int
+	MonthlyLates        int
+}
+
+type registrationStatusCount struct {
+	RegistrationID uint
+	Status         string
+	Count          int
+}
+
+func registrationReports(ctx context.Context, registrationIDs []uint, startDate string, endDate string) (map[uint]*AttendanceReport, error) {
+	var counts []registrationStatusCount
+	err := attendanceInRange(ctx, startDate, endDate, false).
+		Select(`"Attendance".registration_id AS registration_id, "Attendance".status AS status, COUNT(*) AS count`).
+		Where(`"Attendance".registration_id IN ?`, registrationIDs).
+		Group(`"Attendance".registration_id, "Attendance".status`).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make(map[uint]*AttendanceReport)
+	for _, count := range counts {
+		if reports[count.RegistrationID] == nil {
+			reports[count.RegistrationID] = &AttendanceReport{}
+		}
+		reports[count.RegistrationID].addStatus(count.Status, count.Count)
+	}
+	return reports, nil
+}
+
+// trailingAbsences returns, per registration, the number of ABSENT records
+// since the most recent non-absent record within [startDate, endDate].
+func trailingAbsences(ctx context.Context, registrationIDs []uint, startDate string, endDate string) (map[uint]int, error) {
+	var attendances []Attendance
+	err := attendanceInRange(ctx, startDate, endDate, false).
+		Select(`"Attendance".registration_id`, `"Attendance".date`, `"Attendance".status`).
+		Where(`"Attendance".registration_id IN ?`, registrationIDs).
+		Order(`"Attendance".registration_id ASC, "Attendance".date ASC`).
+		Find(&attendances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	streaks := make(map[uint]int)
+	for _, attendance := range attendances {
+		if attendance.Status == "ABSENT" {
+			streaks[attendance.RegistrationID]++
+		} else {
+			streaks[attendance.RegistrationID] = 0
+		}
+	}
+	return streaks, nil
+}
+
+// EvaluateAlerts checks every active registration against rules as of asOf.
+// A rule fires again only after its condition has cleared.
+func EvaluateAlerts(ctx context.Context, asOf time.Time, rules AlertRules) ([]Alert, error) {
+	var studentClassIDs []uint
+	err := conn(ctx).Model(&Registration{}).
+		Distinct("student_class_id").
+		Where("status = ?", ActiveRegistrationStatus).
+		Order("student_class_id ASC").
+		Pluck("student_class_id", &studentClassIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []Alert
+	for _, studentClassID := range studentClassIDs {
+		raised, err := evaluateClassAlerts(ctx, studentClassID, asOf, rules)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, raised...)
+	}
+	return alerts, nil
+}
+
+// evaluateClassAlerts evaluates one class under a lock on its row, so two
+// evaluations running at once cannot raise the same alert twice.
+func evaluateClassAlerts(ctx context.Context, studentClassID uint, asOf time.Time, rules AlertRules) ([]Alert, error) {
+	var registrations []Registration
+	err := conn(ctx).
+		Where("student_class_id = ?", studentClassID).
+		Where("status = ?", ActiveRegistrationStatus).
+		Find(&registrations).Error
+	if err != nil || len(registrations) == 0 {
+		return nil, err
+	}
+
+	var registrationIDs []uint
+	for _, registration := range registrations {
+		registrationIDs = append(registrationIDs, registration.ID)
+	}
+
+	endDate := asOf.Format(dateLayout)
+	windowStartDate := asOf.AddDate(0, 0, -(rules.WindowDays - 1)).Format(dateLayout)
+	monthStartDate := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, asOf.Location()).Format(dateLayout)
+
+	windowReports, err := registrationReports(ctx, registrationIDs, windowStartDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	monthReports, err := registrationReports(ctx, registrationIDs, monthStartDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	streaks, err := trailingAbsences(ctx, registrationIDs, windowStartDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Alert
+	raise := func(registration Registration, rule string, severity string, message string) {
+		candidates = append(candidates, Alert{
+			RegistrationID: registration.ID,
+			StudentClassID: registration.StudentClassID,
+			Rule:           rule,
+			Severity:       severity,
+			Message:        message,
+			TriggeredOn:    endDate,
+		})
+	}
+
+	for _, registration := range registrations {
+		if report, exists := windowReports[registration.ID]; exists && report.TotalDays >= rules.MinSessions && report.Percentage < rules.MinPercentage {
+			severity := AlertSeverityMedium
+			if report.Percentage < rules.CriticalPercentage {
+				severity = AlertSeverityHigh
+			}
+			raise(registration, AlertRuleLowAttendance, severity,
+				fmt.Sprintf("Attendance of %.1f%% over the last %d days is below %.1f%%", report.Percentage, rules.WindowDays, rules.MinPercentage))
+		}
+
+		if streak := streaks[registration.ID]; streak >= rules.ConsecutiveAbsences {
+			raise(registration, AlertRuleConsecutiveAbsences, AlertSeverityHigh,
+				fmt.Sprintf("%d consecutive absences", streak))
+		}
+
+		if report, exists := monthReports[registration.ID]; exists && report.LateCount >= rules.MonthlyLates {
+			raise(registration, AlertRuleMonthlyLates, AlertSeverityLow,
+				fmt.Sprintf("%d late arrivals this month", report.LateCount))
+		}
+	}
+
+	var alerts []Alert
+	err = conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var studentClass StudentClass
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&studentClass, studentClassID).Error; err != nil {
+			return err
+		}
+
+		var triggered []Alert
+		err := tx.Where("student_class_id = ?", studentClassID).
+			Where("cleared_at IS NULL").
+			Find(&triggered).Error
+		if err != nil {
+			return err
+		}
+
+		firing := make(map[string]bool)
+		for _, alert := range candidates {
+			firing[fmt.Sprintf("%d/%s", alert.RegistrationID, alert.Rule)] = true
+		}
+		active := make(map[string]bool)
+		var cleared []uint
+		for _, alert := range triggered {
+			key := fmt.Sprintf("%d/%s", alert.RegistrationID, alert.Rule)
+			if firing[key] {
+				active[key] = true
+			} else {
+				cleared = append(cleared, alert.ID)
+			}
+		}
+		if len(cleared) > 0 {
+			if err := tx.Model(&Alert{}).Where("id IN ?", cleared).Update("cleared_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, alert := range candidates {
+			if !active[fmt.Sprintf("%d/%s", alert.RegistrationID, alert.Rule)] {
+				alerts = append(alerts, alert)
+			}
+		}
+		if len(alerts) == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&alerts).Error; err != nil {
+			return err
+		}
+		for _, alert := range alerts {
+			err := writeOutboxEvent(tx, EventAlertRaised, AlertEventPayload{
+				AlertID:        alert.ID,
+				RegistrationID: alert.RegistrationID,
+				StudentClassID: alert.StudentClassID,
+				Rule:           alert.Rule,
+				Severity:       alert.Severity,
+				Message:        alert.Message,
+				TriggeredOn:    alert.TriggeredOn,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+func ListAlerts(ctx context.Context, studentClassIDs []uint, acknowledged *bool) []Alert {
+	var alerts []Alert
+	if len(studentClassIDs) == 0 {
+		return alerts
+	}
+
+	query := conn(ctx).Preload("Registration.Student").
+		Where("student_class_id IN ?", studentClassIDs)
+
+	if acknowledged != nil {
+		if *acknowledged {
+			query = query.Where("acknowledged_at IS NOT NULL")
+		} else {
+			query = query.Where("acknowledged_at IS NULL")
+		}
+	}
+
+	query.Order("created_at DESC, id DESC").Find(&alerts)
+	return alerts
+}
+
+func GetAlert(ctx context.Context, alertID uint) (Alert, error) {
+	var alert Alert
+	err := conn(ctx).First(&alert, alertID).Error
+	return alert, err
+}
+
+func AcknowledgeAlert(ctx context.Context, alertID uint, userEmail string) (Alert, error) {
+	var alert Alert
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&alert, alertID).Error; err != nil {
+			return err
+		}
+		if alert.AcknowledgedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		alert.AcknowledgedBy = userEmail
+		alert.AcknowledgedAt = &now
+		return tx.Model(&alert).Updates(map[string]interface{}{
+			"acknowledged_by": userEmail,
+			"acknowledged_at": now,
+		}).Error
+	})
+	return alert, err
+}
@@ -1,7 +1,11 @@
 package db
 
 import (
+	"context"
 	"fmt"
+	"skulla-api/live"
+	"skulla-api/metrics"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,6 +14,7 @@ import (
 
 type Attendance struct {
 	ID             uint         `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint         `gorm:"not null;index:idx_attendance_school_id"`
 	RegistrationID uint         `gorm:"not null;index:idx_registration_id;uniqueIndex:unique_registration_date"`
 	Registration   Registration `gorm:"foreignKey:RegistrationID"`
 	Date           string       `gorm:"type:date;not null;index:idx_date;uniqueIndex:unique_registration_date"`
@@ -17,6 +22,7 @@ type Attendance struct {
 	Remarks        string       `gorm:"type:text"`
 	CreatedBy      string       `gorm:"size:500"`
 	UpdatedBy      string       `gorm:"size:500"`
+	Version        int          `gorm:"not null;default:1"`
 	CreatedAt      time.Time    `gorm:"autoCreateTime"`
 	UpdatedAt      time.Time    `gorm:"autoUpdateTime"`
 }
@@ -25,22 +31,32 @@ func (Attendance) TableName() string {
 	return "Attendance"
 }
 
-func CreateOrUpdateAttendance(registrationID uint, date string, status string, remarks string, userEmail string) error {
-	attendance := Attendance{
-		RegistrationID: registrationID,
-		Date:           date,
-		Status:         status,
-		Remarks:        remarks,
-		CreatedBy:      userEmail,
-		UpdatedBy:      userEmail,
-	}
+// AttendanceChange is an append-only log of attendance writes, read by sync.
+type AttendanceChange struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint      `gorm:"not null;index:idx_change_school_id"`
+	RegistrationID uint      `gorm:"not null;index:idx_change_registration_id"`
+	Date           string    `gorm:"type:date;not null"`
+	ChangedBy      string    `gorm:"size:500"`
+	OnBehalfOf     string    `gorm:"size:500"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
 
-	result := db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "registration_id"}, {Name: "date"}},
-		DoUpdates: clause.AssignmentColumns([]string{"status", "remarks", "updated_by", "updated_at"}),
-	}).Create(&attendance)
+func (AttendanceChange) TableName() string {
+	return "AttendanceChange"
+}
 
-	return result.Error
+// CreateOrUpdateAttendance writes one record, failing with a
+// *VersionConflictError when expectedVersion does not match.
+func CreateOrUpdateAttendance(ctx context.Context, registrationID uint, date string, status string, remarks string, userEmail string, expectedVersion *int) error {
+	return CreateOrUpdateBulkAttendance(ctx, []BulkAttendanceRecord{{
+		RegistrationID:  registrationID,
+		Date:            date,
+		Status:          status,
+		Remarks:         remarks,
+		UserEmail:       userEmail,
+		ExpectedVersion: expectedVersion,
+	}})
 }
 
 type AttendanceReport struct {
@@ -52,36 +68,57 @@ type AttendanceReport struct {
 	Percentage   float64 `json:"percentage"`
 }
 
-func GetStudentAttendanceReport(studentID uint, startDate string, endDate string) AttendanceReport {
-	var attendances []Attendance
+// addStatus counts count attendances with the given status into the report.
+func (r *AttendanceReport) addStatus(status string, count int) {
+	r.TotalDays += count
+	switch status {
+	case "PRESENT":
+		r.PresentCount += count
+	case "ABSENT":
+		r.AbsentCount += count
+	case "LATE":
+		r.LateCount += count
+	case "EXCUSED":
+		r.ExcusedCount += count
+	}
+	r.Percentage = percentage(r.PresentCount, r.TotalDays)
+}
 
-	db.Joins("JOIN Registration ON Registration.id = Attendance.registration_id").
-		Where("Registration.student_id = ?", studentID).
-		Where("Attendance.date >= ?", startDate).
-		Where("Attendance.date <= ?", endDate).
-		Find(&attendances)
+// attendanceInRange queries the attendance within [startDate, endDate].
+func attendanceInRange(ctx context.Context, startDate string, endDate string, includeDrafts bool) *gorm.DB {
+	query := conn(ctx).Table("Attendance").
+		Joins(`JOIN "Registration" ON "Registration".id = "Attendance".registration_id`).
+		Where(`"Attendance".date >= ?`, startDate).
+		Where(`"Attendance".date <= ?`, endDate)
+	return submittedAttendance(query, includeDrafts)
+}
 
-	report := AttendanceReport{
-		TotalDays: len(attendances),
+// submittedAttendance restricts a query over Attendance joined to
+// Registration to submitted registers, unless includeDrafts is set.
+func submittedAttendance(query *gorm.DB, includeDrafts bool) *gorm.DB {
+	if includeDrafts {
+		return query
 	}
+	return excludeDraftRegisters(query, `"Registration".student_class_id`, `"Attendance".date`)
+}
 
-	for _, attendance := range attendances {
-		switch attendance.Status {
-		case "PRESENT":
-			report.PresentCount++
-		case "ABSENT":
-			report.AbsentCount++
-		case "LATE":
-			report.LateCount++
-		case "EXCUSED":
-			report.ExcusedCount++
-		}
-	}
+type statusCount struct {
+	Status string
+	Count  int
+}
 
-	if report.TotalDays > 0 {
-		report.Percentage = float64(report.PresentCount) / float64(report.TotalDays) * 100
+func GetStudentAttendanceReport(ctx context.Context, studentID uint, startDate string, endDate string, includeDrafts bool) AttendanceReport {
+	var counts []statusCount
+	attendanceInRange(ctx, startDate, endDate, includeDrafts).
+		Select(`"Attendance".status AS status, COUNT(*) AS count`).
+		Where(`"Registration".student_id = ?`, studentID).
+		Group(`"Attendance".status`).
+		Scan(&counts)
+
+	report := AttendanceReport{}
+	for _, count := range counts {
+		report.addStatus(count.Status, count.Count)
 	}
-
 	return report
 }
 
@@ -89,6 +126,7 @@ type AttendanceRecord struct {
 	Date    string `json:"date"`
 	Status  string `json:"status"`
 	Remarks string `json:"remarks"`
+	Version int    `json:"version"`
 }
 
 type WeeklyTrend struct {
@@ -110,6 +148,7 @@ type DetailedAttendanceReport struct {
 	Records       []AttendanceRecord `json:"records"`
 	WeeklyTrends  []WeeklyTrend      `json:"weeklyTrends"`
 	MonthlyTrends []MonthlyTrend     `json:"monthlyTrends"`
+	Patterns      AbsencePatterns    `json:"patterns"`
 }
 
 type StudentClassAttendanceReport struct {
@@ -126,247 +165,548 @@ type AggregatedStudentAttendanceReport struct {
 	ByClass        []StudentClassAttendanceReport `json:"byClass"`
 }
 
-func GetDetailedStudentAttendanceReport(studentID uint, startDate string, endDate string, studentClassID *uint) DetailedAttendanceReport {
-	var attendances []Attendance
+func GetDetailedStudentAttendanceReport(ctx context.Context, studentID uint, startDate string, endDate string, studentClassID *uint, includeDrafts bool) DetailedAttendanceReport {
+	studentAttendance := func() *gorm.DB {
+		query := attendanceInRange(ctx, startDate, endDate, includeDrafts).
+			Where(`"Registration".student_id = ?`, studentID)
+		if studentClassID != nil {
+			query = query.Where(`"Registration".student_class_id = ?`, *studentClassID)
+		}
+		return query
+	}
 
-	query := db.Joins("JOIN Registration ON Registration.id = Attendance.registration_id").
-		Where("Registration.student_id = ?", studentID).
-		Where("Attendance.date >= ?", startDate).
-		Where("Attendance.date <= ?", endDate)
+	var counts []classDailyStatusCount
+	studentAttendance().
+		Select(`"Attendance".date AS date, "Attendance".status AS status, COUNT(*) AS count`).
+		Group(`"Attendance".date, "Attendance".status`).
+		Scan(&counts)
 
-	if studentClassID != nil {
-		query = query.Where("Registration.student_class_id = ?", *studentClassID)
+	var records []AttendanceRecord
+	studentAttendance().
+		Select(`"Attendance".date AS date, "Attendance".status AS status, "Attendance".remarks AS remarks, "Attendance".version AS version`).
+		Order(`"Attendance".date ASC`).
+		Scan(&records)
+
+	summary := AttendanceReport{}
+	series := newTimeSeries()
+	for _, count := range counts {
+		summary.addStatus(count.Status, count.Count)
+		series.add(count.Date, count.Status, count.Count)
 	}
 
-	query.Order("Attendance.date ASC").Find(&attendances)
-
-	summary := AttendanceReport{
-		TotalDays: len(attendances),
+	// Holidays are looked up a week either side of the report.
+	var studentClassIDs []uint
+	if studentClassID != nil {
+		studentClassIDs = []uint{*studentClassID}
+	}
+	calendarStart, calendarEnd := startDate, endDate
+	if start, err := parseDate(startDate); err == nil {
+		calendarStart = start.AddDate(0, 0, -7).Format(dateLayout)
+	}
+	if end, err := parseDate(endDate); err == nil {
+		calendarEnd = end.AddDate(0, 0, 7).Format(dateLayout)
 	}
 
-	var records []AttendanceRecord
-	weeklyMap := make(map[string]*WeeklyTrend)
-	monthlyMap := make(map[string]*MonthlyTrend)
+	return DetailedAttendanceReport{
+		Summary:       summary,
+		Records:       records,
+		WeeklyTrends:  series.weeklyTrends(startDate, endDate),
+		MonthlyTrends: series.monthlyTrends(startDate, endDate),
+		Patterns:      getAbsencePatterns(records, schoolDays(ctx, studentClassIDs, calendarStart, calendarEnd, includeDrafts)),
+	}
+}
 
-	for _, attendance := range attendances {
-		switch attendance.Status {
-		case "PRESENT":
-			summary.PresentCount++
-		case "ABSENT":
-			summary.AbsentCount++
-		case "LATE":
-			summary.LateCount++
-		case "EXCUSED":
-			summary.ExcusedCount++
-		}
+type classDailyStatusCount struct {
+	StudentClassID uint
+	Date           string
+	Status         string
+	Count          int
+}
 
-		records = append(records, AttendanceRecord{
-			Date:    attendance.Date,
-			Status:  attendance.Status,
-			Remarks: attendance.Remarks,
-		})
+type classAttendanceRecord struct {
+	StudentClassID uint
+	Date           string
+	Status         string
+	Remarks        string
+	Version        int
+}
 
-		parsedDate, err := time.Parse("2006-01-02", attendance.Date)
-		if err == nil {
-			year, week := parsedDate.ISOWeek()
-			weekKey := fmt.Sprintf("%d-W%02d", year, week)
-			if weeklyMap[weekKey] == nil {
-				weeklyMap[weekKey] = &WeeklyTrend{Week: weekKey}
-			}
-			weeklyMap[weekKey].TotalDays++
-			if attendance.Status == "PRESENT" {
-				weeklyMap[weekKey].PresentCount++
-			}
+func GetAggregatedStudentAttendanceReport(ctx context.Context, studentID uint, startDate string, endDate string, includeDrafts bool) AggregatedStudentAttendanceReport {
+	var counts []classDailyStatusCount
+	attendanceInRange(ctx, startDate, endDate, includeDrafts).
+		Select(`"Registration".student_class_id AS student_class_id, "Attendance".date AS date, "Attendance".status AS status, COUNT(*) AS count`).
+		Where(`"Registration".student_id = ?`, studentID).
+		Group(`"Registration".student_class_id, "Attendance".date, "Attendance".status`).
+		Scan(&counts)
+
+	var records []classAttendanceRecord
+	attendanceInRange(ctx, startDate, endDate, includeDrafts).
+		Select(`"Registration".student_class_id AS student_class_id, "Attendance".date AS date, "Attendance".status AS status, "Attendance".remarks AS remarks, "Attendance".version AS version`).
+		Where(`"Registration".student_id = ?`, studentID).
+		Order(`"Attendance".date ASC`).
+		Scan(&records)
+
+	overallSummary := AttendanceReport{}
+	classMap := make(map[uint]*StudentClassAttendanceReport)
+	seriesMap := make(map[uint]*timeSeries)
+	var classIDs []uint
 
-			monthKey := parsedDate.Format("2006-01")
-			if monthlyMap[monthKey] == nil {
-				monthlyMap[monthKey] = &MonthlyTrend{Month: monthKey}
-			}
-			monthlyMap[monthKey].TotalDays++
-			if attendance.Status == "PRESENT" {
-				monthlyMap[monthKey].PresentCount++
+	for _, count := range counts {
+		classID := count.StudentClassID
+		if classMap[classID] == nil {
+			classIDs = append(classIDs, classID)
+			classMap[classID] = &StudentClassAttendanceReport{
+				StudentClassID: classID,
+				Summary:        AttendanceReport{},
+				Records:        []AttendanceRecord{},
 			}
+			seriesMap[classID] = newTimeSeries()
 		}
-	}
 
-	if summary.TotalDays > 0 {
-		summary.Percentage = float64(summary.PresentCount) / float64(summary.TotalDays) * 100
+		overallSummary.addStatus(count.Status, count.Count)
+		classMap[classID].Summary.addStatus(count.Status, count.Count)
+		seriesMap[classID].add(count.Date, count.Status, count.Count)
 	}
 
-	var weeklyTrends []WeeklyTrend
-	for _, trend := range weeklyMap {
-		if trend.TotalDays > 0 {
-			trend.Percentage = float64(trend.PresentCount) / float64(trend.TotalDays) * 100
+	for _, record := range records {
+		if classReport, exists := classMap[record.StudentClassID]; exists {
+			classReport.Records = append(classReport.Records, AttendanceRecord{
+				Date:    record.Date,
+				Status:  record.Status,
+				Remarks: record.Remarks,
+				Version: record.Version,
+			})
 		}
-		weeklyTrends = append(weeklyTrends, *trend)
 	}
 
-	var monthlyTrends []MonthlyTrend
-	for _, trend := range monthlyMap {
-		if trend.TotalDays > 0 {
-			trend.Percentage = float64(trend.PresentCount) / float64(trend.TotalDays) * 100
+	if len(classIDs) > 0 {
+		var studentClasses []StudentClass
+		conn(ctx).Where("id IN ?", classIDs).Find(&studentClasses)
+		for _, studentClass := range studentClasses {
+			classMap[studentClass.ID].StudentClassName = studentClass.Name
 		}
-		monthlyTrends = append(monthlyTrends, *trend)
 	}
 
-	return DetailedAttendanceReport{
-		Summary:       summary,
-		Records:       records,
-		WeeklyTrends:  weeklyTrends,
-		MonthlyTrends: monthlyTrends,
+	var byClass []StudentClassAttendanceReport
+	for classID, classReport := range classMap {
+		classReport.WeeklyTrends = seriesMap[classID].weeklyTrends(startDate, endDate)
+		classReport.MonthlyTrends = seriesMap[classID].monthlyTrends(startDate, endDate)
+		byClass = append(byClass, *classReport)
+	}
+
+	sort.Slice(byClass, func(i, j int) bool {
+		if byClass[i].StudentClassName != byClass[j].StudentClassName {
+			return byClass[i].StudentClassName < byClass[j].StudentClassName
+		}
+		return byClass[i].StudentClassID < byClass[j].StudentClassID
+	})
+
+	return AggregatedStudentAttendanceReport{
+		OverallSummary: overallSummary,
+		ByClass:        byClass,
 	}
 }
 
-func GetAggregatedStudentAttendanceReport(studentID uint, startDate string, endDate string) AggregatedStudentAttendanceReport {
-	var attendances []Attendance
+// BulkAttendanceRecord is one attendance write. An ExpectedVersion of 0 means
+// the record must not exist yet.
+type BulkAttendanceRecord struct {
+	RegistrationID  uint
+	Date            string
+	Status          string
+	Remarks         string
+	UserEmail       string
+	ExpectedVersion *int
+	// Draft opens a new register as a draft.
+	Draft bool
+}
 
-	db.Joins("JOIN Registration ON Registration.id = Attendance.registration_id").
-		Preload("Registration").
-		Where("Registration.student_id = ?", studentID).
-		Where("Attendance.date >= ?", startDate).
-		Where("Attendance.date <= ?", endDate).
-		Order("Attendance.date ASC").
-		Find(&attendances)
+// VersionConflictError reports that record Index of a write expected another
+// version than the stored one. Current is nil when the record does not exist.
+type VersionConflictError struct {
+	Index    int
+	Expected int
+	Current  *AttendanceState
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("record %d: expected version %d of attendance record", e.Index, e.Expected)
+}
 
-	overallSummary := AttendanceReport{
-		TotalDays: len(attendances),
+func CreateOrUpdateBulkAttendance(ctx context.Context, records []BulkAttendanceRecord) error {
+	var events []live.Event
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		events, err = recordAttendance(tx, records)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
-	classMap := make(map[uint]*StudentClassAttendanceReport)
+	publishAttendanceEvents(events)
+	return nil
+}
 
-	for _, attendance := range attendances {
-		classID := attendance.Registration.StudentClassID
+const (
+	BulkResultCreated   = "created"
+	BulkResultUpdated   = "updated"
+	BulkResultUnchanged = "unchanged"
+	BulkResultError     = "error"
+)
 
-		if classMap[classID] == nil {
-			var studentClass StudentClass
-			db.First(&studentClass, classID)
+// BulkAttendanceResult is the outcome of one record of a partial bulk write.
+type BulkAttendanceResult struct {
+	Index          int    `json:"index"`
+	RegistrationID uint   `json:"registration_id"`
+	Date           string `json:"date"`
+	Result         string `json:"result"`
+	Version        int    `json:"version,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
 
-			classMap[classID] = &StudentClassAttendanceReport{
-				StudentClassID:   classID,
-				StudentClassName: studentClass.Name,
-				Summary:          AttendanceReport{},
-				Records:          []AttendanceRecord{},
-				WeeklyTrends:     []WeeklyTrend{},
-				MonthlyTrends:    []MonthlyTrend{},
+// RecordPartialBulkAttendance writes the records it can and returns one result
+// per record in input order.
+func RecordPartialBulkAttendance(ctx context.Context, records []BulkAttendanceRecord) ([]BulkAttendanceResult, error) {
+	results := make([]BulkAttendanceResult, len(records))
+	var events []live.Event
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := existingAttendances(tx, records)
+		if err != nil {
+			return err
+		}
+
+		classByRegistration, err := registrationClassIDs(tx, records)
+		if err != nil {
+			return err
+		}
+		registers, err := loadRegisters(tx, records, classByRegistration)
+		if err != nil {
+			return err
+		}
+
+		var writes []BulkAttendanceRecord
+		for i, record := range records {
+			key := attendanceKey(record.RegistrationID, record.Date)
+			current, exists := existing[key]
+			results[i] = BulkAttendanceResult{
+				Index:          i,
+				RegistrationID: record.RegistrationID,
+				Date:           normalizeDate(record.Date),
+			}
+
+			if registers[registerKey(classByRegistration[record.RegistrationID], record.Date)].Status == RegisterLocked {
+				results[i].Result = BulkResultError
+				results[i].Version = current.Version
+				results[i].Error = ErrRegisterLocked.Error()
+				continue
 			}
+
+			if expected := record.ExpectedVersion; expected != nil && ((exists && current.Version != *expected) || (!exists && *expected != 0)) {
+				results[i].Result = BulkResultError
+				results[i].Version = current.Version
+				results[i].Error = fmt.Sprintf("expected version %d but the record is at version %d", *expected, current.Version)
+				continue
+			}
+
+			switch {
+			case !exists:
+				results[i].Result = BulkResultCreated
+				current = Attendance{RegistrationID: record.RegistrationID, Date: record.Date}
+			case current.Status == record.Status && current.Remarks == record.Remarks:
+				results[i].Result = BulkResultUnchanged
+				results[i].Version = current.Version
+				continue
+			default:
+				results[i].Result = BulkResultUpdated
+			}
+
+			current.Status = record.Status
+			current.Remarks = record.Remarks
+			current.Version++
+			existing[key] = current
+			results[i].Version = current.Version
+
+			// Versions were checked against the running state above.
+			record.ExpectedVersion = nil
+			writes = append(writes, record)
 		}
 
-		classReport := classMap[classID]
-		classReport.Summary.TotalDays++
+		if len(writes) == 0 {
+			return nil
+		}
+		events, err = recordAttendance(tx, writes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		switch attendance.Status {
-		case "PRESENT":
-			overallSummary.PresentCount++
-			classReport.Summary.PresentCount++
-		case "ABSENT":
-			overallSummary.AbsentCount++
-			classReport.Summary.AbsentCount++
-		case "LATE":
-			overallSummary.LateCount++
-			classReport.Summary.LateCount++
-		case "EXCUSED":
-			overallSummary.ExcusedCount++
-			classReport.Summary.ExcusedCount++
+	publishAttendanceEvents(events)
+	return results, nil
+}
+
+// recordAttendance upserts records inside the caller's transaction. Publish the
+// returned events only after it commits.
+func recordAttendance(tx *gorm.DB, records []BulkAttendanceRecord) ([]live.Event, error) {
+	existing, err := existingAttendances(tx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, record := range records {
+		if record.ExpectedVersion == nil {
+			continue
+		}
+		current, exists := existing[attendanceKey(record.RegistrationID, record.Date)]
+		if (exists && current.Version == *record.ExpectedVersion) || (!exists && *record.ExpectedVersion == 0) {
+			continue
+		}
+		state, err := currentAttendanceState(tx, record.RegistrationID, normalizeDate(record.Date))
+		if err != nil {
+			return nil, err
 		}
+		return nil, &VersionConflictError{Index: i, Expected: *record.ExpectedVersion, Current: state}
+	}
 
-		classReport.Records = append(classReport.Records, AttendanceRecord{
-			Date:    attendance.Date,
-			Status:  attendance.Status,
-			Remarks: attendance.Remarks,
-		})
+	classByRegistration, err := registrationClassIDs(tx, records)
+	if err != nil {
+		return nil, err
 	}
 
-	if overallSummary.TotalDays > 0 {
-		overallSummary.Percentage = float64(overallSummary.PresentCount) / float64(overallSummary.TotalDays) * 100
+	registers, err := openRegisters(tx, records, classByRegistration)
+	if err != nil {
+		return nil, err
 	}
 
-	var byClass []StudentClassAttendanceReport
-	for _, classReport := range classMap {
-		weeklyMap := make(map[string]*WeeklyTrend)
-		monthlyMap := make(map[string]*MonthlyTrend)
-
-		for _, record := range classReport.Records {
-			parsedDate, err := time.Parse("2006-01-02", record.Date)
-			if err == nil {
-				year, week := parsedDate.ISOWeek()
-				weekKey := fmt.Sprintf("%d-W%02d", year, week)
-				if weeklyMap[weekKey] == nil {
-					weeklyMap[weekKey] = &WeeklyTrend{Week: weekKey}
-				}
-				weeklyMap[weekKey].TotalDays++
-				if record.Status == "PRESENT" {
-					weeklyMap[weekKey].PresentCount++
-				}
-
-				monthKey := parsedDate.Format("2006-01")
-				if monthlyMap[monthKey] == nil {
-					monthlyMap[monthKey] = &MonthlyTrend{Month: monthKey}
-				}
-				monthlyMap[monthKey].TotalDays++
-				if record.Status == "PRESENT" {
-					monthlyMap[monthKey].PresentCount++
-				}
+	grantors, err := recordDelegationGrantors(tx, records, classByRegistration, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var events []live.Event
+	summaries := make(summaryDeltas)
+	for i, record := range records {
+		attendance := Attendance{
+			RegistrationID: record.RegistrationID,
+			Date:           record.Date,
+			Status:         record.Status,
+			Remarks:        record.Remarks,
+			CreatedBy:      record.UserEmail,
+			UpdatedBy:      record.UserEmail,
+			Version:        1,
+		}
+
+		key := attendanceKey(record.RegistrationID, record.Date)
+		previous, exists := existing[key]
+		conflict := clause.OnConflict{Columns: []clause.Column{{Name: "registration_id"}, {Name: "date"}}}
+		update := conflict
+		update.DoUpdates = clause.AssignmentColumns([]string{"status", "remarks", "updated_by", "updated_at"})
+		update.DoUpdates = append(update.DoUpdates, clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr(`"Attendance".version + 1`)})
+		createOnly := record.ExpectedVersion != nil && *record.ExpectedVersion == 0
+		if !exists || createOnly {
+			// A concurrent create is only caught by the unique key.
+			conflict.DoNothing = true
+		} else {
+			conflict = update
+		}
+		result := tx.Clauses(conflict).Create(&attendance)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if conflict.DoNothing && result.RowsAffected == 0 {
+			state, err := currentAttendanceState(tx.Clauses(clause.Locking{Strength: "UPDATE"}), record.RegistrationID, normalizeDate(record.Date))
+			if err != nil {
+				return nil, err
+			}
+			if createOnly {
+				return nil, &VersionConflictError{Index: i, Expected: 0, Current: state}
+			}
+			if state != nil {
+				previous, exists = Attendance{Status: state.Status, Remarks: state.Remarks, Version: state.Version}, true
+			}
+			if err := tx.Clauses(update).Create(&attendance).Error; err != nil {
+				return nil, err
 			}
 		}
+		current := previous
+		current.RegistrationID, current.Date, current.Status, current.Remarks = record.RegistrationID, record.Date, record.Status, record.Remarks
+		existing[key] = current
 
-		if classReport.Summary.TotalDays > 0 {
-			classReport.Summary.Percentage = float64(classReport.Summary.PresentCount) / float64(classReport.Summary.TotalDays) * 100
+		studentClassID := classByRegistration[record.RegistrationID]
+		if exists {
+			summaries.add(studentClassID, record.Date, previous.Status, -1)
+		}
+		summaries.add(studentClassID, record.Date, record.Status, 1)
+
+		onBehalfOf := grantors[delegatedWrite{email: record.UserEmail, studentClassID: classByRegistration[record.RegistrationID]}]
+		change := AttendanceChange{
+			RegistrationID: record.RegistrationID,
+			Date:           normalizeDate(record.Date),
+			ChangedBy:      record.UserEmail,
+			OnBehalfOf:     onBehalfOf,
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return nil, err
 		}
 
-		for _, trend := range weeklyMap {
-			if trend.TotalDays > 0 {
-				trend.Percentage = float64(trend.PresentCount) / float64(trend.TotalDays) * 100
+		eventType := EventAttendanceRecorded
+		if exists {
+			eventType = EventAttendanceUpdated
+		}
+		payload := AttendanceEventPayload{
+			RegistrationID: record.RegistrationID,
+			StudentClassID: classByRegistration[record.RegistrationID],
+			Date:           normalizeDate(record.Date),
+			Status:         record.Status,
+			PreviousStatus: previous.Status,
+			Remarks:        record.Remarks,
+			RecordedBy:     record.UserEmail,
+			OnBehalfOf:     onBehalfOf,
+		}
+		// Webhooks for draft registers are emitted when they are submitted.
+		if registers[registerKey(payload.StudentClassID, record.Date)].Status != RegisterDraft {
+			if err := writeOutboxEvent(tx, eventType, payload); err != nil {
+				return nil, err
 			}
-			classReport.WeeklyTrends = append(classReport.WeeklyTrends, *trend)
 		}
+		events = append(events, live.Event{
+			Type:           live.EventAttendance,
+			StudentClassID: payload.StudentClassID,
+			Data:           payload,
+		})
+	}
 
-		for _, trend := range monthlyMap {
-			if trend.TotalDays > 0 {
-				trend.Percentage = float64(trend.PresentCount) / float64(trend.TotalDays) * 100
-			}
-			classReport.MonthlyTrends = append(classReport.MonthlyTrends, *trend)
+	if err := summaries.apply(tx); err != nil {
+		return nil, err
+	}
+	// Notifications for draft registers are queued when they are submitted.
+	if err := enqueueGuardianNotifications(tx, submittedRecords(records, registers, classByRegistration)); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// publishAttendanceEvents hands committed attendance events to live board
+// clients and counts the records written.
+func publishAttendanceEvents(events []live.Event) {
+	for _, event := range events {
+		live.Publish(event)
+		if payload, ok := event.Data.(AttendanceEventPayload); ok {
+			metrics.AttendanceRecordsWritten.WithLabelValues(payload.Status).Inc()
 		}
+	}
+}
 
-		byClass = append(byClass, *classReport)
+// registrationClassIDs maps the registration of every record to its
+// StudentClass.
+func registrationClassIDs(tx *gorm.DB, records []BulkAttendanceRecord) (map[uint]uint, error) {
+	var registrationIDs []uint
+	for _, record := range records {
+		registrationIDs = append(registrationIDs, record.RegistrationID)
 	}
 
-	return AggregatedStudentAttendanceReport{
-		OverallSummary: overallSummary,
-		ByClass:        byClass,
+	var registrations []Registration
+	if err := tx.Select("id", "student_class_id").Where("id IN ?", registrationIDs).Find(&registrations).Error; err != nil {
+		return nil, err
 	}
+
+	classByRegistration := make(map[uint]uint)
+	for _, registration := range registrations {
+		classByRegistration[registration.ID] = registration.StudentClassID
+	}
+	return classByRegistration, nil
 }
 
-type BulkAttendanceRecord struct {
-	RegistrationID uint
-	Date           string
-	Status         string
-	Remarks        string
-	UserEmail      string
+func attendanceKey(registrationID uint, date string) string {
+	return fmt.Sprintf("%d/%s", registrationID, normalizeDate(date))
 }
 
-func CreateOrUpdateBulkAttendance(records []BulkAttendanceRecord) error {
-	return db.Transaction(func(tx *gorm.DB) error {
-		for _, record := range records {
-			attendance := Attendance{
-				RegistrationID: record.RegistrationID,
-				Date:           record.Date,
-				Status:         record.Status,
-				Remarks:        record.Remarks,
-				CreatedBy:      record.UserEmail,
-				UpdatedBy:      record.UserEmail,
-			}
+// existingAttendances returns every record that is already stored, keyed by
+// attendanceKey. The rows stay locked until the transaction ends.
+func existingAttendances(tx *gorm.DB, records []BulkAttendanceRecord) (map[string]Attendance, error) {
+	var registrationIDs []uint
+	var dates []string
+	for _, record := range records {
+		registrationIDs = append(registrationIDs, record.RegistrationID)
+		dates = append(dates, normalizeDate(record.Date))
+	}
 
-			result := tx.Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "registration_id"}, {Name: "date"}},
-				DoUpdates: clause.AssignmentColumns([]string{"status", "remarks", "updated_by", "updated_at"}),
-			}).Create(&attendance)
+	var attendances []Attendance
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("registration_id", "date", "status", "remarks", "version").
+		Where("registration_id IN ?", registrationIDs).
+		Where("date IN ?", dates).
+		Find(&attendances).Error
+	if err != nil {
+		return nil, err
+	}
 
-			if result.Error != nil {
-				return result.Error
-			}
-		}
-		return nil
-	})
+	existing := make(map[string]Attendance)
+	for _, attendance := range attendances {
+		existing[attendanceKey(attendance.RegistrationID, attendance.Date)] = attendance
+	}
+	return existing, nil
+}
+
+// AttendanceState is the stored state of one attendance record, including the
+// version clients must send back to update it.
+type AttendanceState struct {
+	RegistrationID uint      `json:"registration_id"`
+	StudentClassID uint      `json:"student_class_id"`
+	Date           string    `json:"date"`
+	Status         string    `json:"status"`
+	Remarks        string    `json:"remarks"`
+	Version        int       `json:"version"`
+	UpdatedBy      string    `json:"updated_by"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func attendanceStates(tx *gorm.DB) *gorm.DB {
+	return tx.Table("Attendance").
+		Joins(`JOIN "Registration" ON "Registration".id = "Attendance".registration_id`).
+		Select(`"Attendance".registration_id AS registration_id, "Registration".student_class_id AS student_class_id, ` +
+			`"Attendance".date AS date, "Attendance".status AS status, "Attendance".remarks AS remarks, ` +
+			`"Attendance".version AS version, "Attendance".updated_by AS updated_by, "Attendance".updated_at AS updated_at`)
+}
+
+// currentAttendanceState returns the stored record for registrationID on
+// date, or nil if there is none.
+func currentAttendanceState(tx *gorm.DB, registrationID uint, date string) (*AttendanceState, error) {
+	var states []AttendanceState
+	err := attendanceStates(tx).
+		Where(`"Attendance".registration_id = ?`, registrationID).
+		Where(`"Attendance".date = ?`, date).
+		Scan(&states).Error
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+	states[0].Date = normalizeDate(states[0].Date)
+	return &states[0], nil
+}
+
+// GetAttendanceState returns the stored record for registrationID on date, or
+// nil if there is none.
+func GetAttendanceState(ctx context.Context, registrationID uint, date string) (*AttendanceState, error) {
+	return currentAttendanceState(conn(ctx), registrationID, normalizeDate(date))
+}
+
+// GetClassAttendanceOnDate returns the records of a class on one date, keyed by
+// registration.
+func GetClassAttendanceOnDate(ctx context.Context, studentClassID uint, date string) (map[uint]Attendance, error) {
+	var attendances []Attendance
+	err := conn(ctx).Joins(`JOIN "Registration" ON "Registration".id = "Attendance".registration_id`).
+		Where(`"Registration".student_class_id = ?`, studentClassID).
+		Where(`"Attendance".date = ?`, normalizeDate(date)).
+		Find(&attendances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byRegistration := make(map[uint]Attendance, len(attendances))
+	for _, attendance := range attendances {
+		byRegistration[attendance.RegistrationID] = attendance
+	}
+	return byRegistration, nil
 }
 
 type StudentAttendanceSummary struct {
@@ -402,149 +742,123 @@ type ClassAttendanceReport struct {
 	MonthlyData      []MonthlyTrend             `json:"monthlyData,omitempty"`
 }
 
-func GetClassAttendanceReport(studentClassID uint, startDate string, endDate string, period string) ClassAttendanceReport {
-	var registrations []Registration
-	db.Where("student_class_id = ?", studentClassID).Preload("Student").Find(&registrations)
+type studentStatusCount struct {
+	StudentID uint
+	Status    string
+	Count     int
+}
 
-	var attendances []Attendance
-	db.Joins("JOIN Registration ON Registration.id = Attendance.registration_id").
-		Where("Registration.student_class_id = ?", studentClassID).
-		Where("Attendance.date >= ?", startDate).
-		Where("Attendance.date <= ?", endDate).
-		Preload("Registration.Student").
-		Order("Attendance.date ASC").
-		Find(&attendances)
+type dailyStatusCount struct {
+	Date   string
+	Status string
+	Count  int
+}
 
-	overallSummary := AttendanceReport{
-		TotalDays: len(attendances),
+func GetClassAttendanceReport(ctx context.Context, studentClassID uint, startDate string, endDate string, period string, includeDrafts bool) ClassAttendanceReport {
+	registrations := ListRegistrations(ctx, int(studentClassID))
+
+	var studentCounts []studentStatusCount
+	attendanceInRange(ctx, startDate, endDate, includeDrafts).
+		Select(`"Registration".student_id AS student_id, "Attendance".status AS status, COUNT(*) AS count`).
+		Where(`"Registration".student_class_id = ?`, studentClassID).
+		Group(`"Registration".student_id, "Attendance".status`).
+		Scan(&studentCounts)
+
+	// Weeks and months are rolled up from the pre-aggregated daily summaries in
+	// Go rather than grouped in SQL, since ISO week functions differ between
+	// database engines.
+	var dailyCounts []dailyStatusCount
+	for _, summary := range listDailyClassAttendanceSummaries(ctx, []uint{studentClassID}, startDate, endDate, includeDrafts) {
+		dailyCounts = append(dailyCounts, summary.statusCounts()...)
 	}
 
+	// Student order follows ListRegistrations, which sorts by first and last name.
+	var studentOrder []uint
 	studentMap := make(map[uint]*StudentAttendanceSummary)
 	for _, reg := range registrations {
+		if _, exists := studentMap[reg.StudentID]; exists {
+			continue
+		}
+		studentOrder = append(studentOrder, reg.StudentID)
 		studentMap[reg.StudentID] = &StudentAttendanceSummary{
 			StudentID:   reg.StudentID,
 			StudentName: fmt.Sprintf("%s %s", reg.Student.FirstName, reg.Student.LastName),
 		}
 	}
 
-	dailyMap := make(map[string]*DailyAttendance)
-	weeklyMap := make(map[string]*WeeklyTrend)
-	monthlyMap := make(map[string]*MonthlyTrend)
-
-	for _, attendance := range attendances {
-		switch attendance.Status {
+	for _, count := range studentCounts {
+		summary, exists := studentMap[count.StudentID]
+		if !exists {
+			continue
+		}
+		summary.TotalDays += count.Count
+		switch count.Status {
 		case "PRESENT":
-			overallSummary.PresentCount++
+			summary.PresentCount += count.Count
 		case "ABSENT":
-			overallSummary.AbsentCount++
+			summary.AbsentCount += count.Count
 		case "LATE":
-			overallSummary.LateCount++
+			summary.LateCount += count.Count
 		case "EXCUSED":
-			overallSummary.ExcusedCount++
-		}
-
-		studentID := attendance.Registration.StudentID
-		if summary, exists := studentMap[studentID]; exists {
-			summary.TotalDays++
-			switch attendance.Status {
-			case "PRESENT":
-				summary.PresentCount++
-			case "ABSENT":
-				summary.AbsentCount++
-			case "LATE":
-				summary.LateCount++
-			case "EXCUSED":
-				summary.ExcusedCount++
-			}
+			summary.ExcusedCount += count.Count
 		}
+	}
 
-		if period == "day" || period == "all" {
-			if dailyMap[attendance.Date] == nil {
-				dailyMap[attendance.Date] = &DailyAttendance{
-					Date:          attendance.Date,
-					TotalStudents: len(registrations),
-				}
-			}
-			dailyMap[attendance.Date].TotalStudents++
-			switch attendance.Status {
-			case "PRESENT":
-				dailyMap[attendance.Date].PresentCount++
-			case "ABSENT":
-				dailyMap[attendance.Date].AbsentCount++
-			case "LATE":
-				dailyMap[attendance.Date].LateCount++
-			case "EXCUSED":
-				dailyMap[attendance.Date].ExcusedCount++
-			}
-		}
+	overallSummary := AttendanceReport{}
+	var dailyOrder []string
+	dailyMap := make(map[string]*DailyAttendance)
+	series := newTimeSeries()
 
-		parsedDate, err := time.Parse("2006-01-02", attendance.Date)
-		if err == nil {
-			if period == "week" || period == "all" {
-				year, week := parsedDate.ISOWeek()
-				weekKey := fmt.Sprintf("%d-W%02d", year, week)
-				if weeklyMap[weekKey] == nil {
-					weeklyMap[weekKey] = &WeeklyTrend{Week: weekKey}
-				}
-				weeklyMap[weekKey].TotalDays++
-				if attendance.Status == "PRESENT" {
-					weeklyMap[weekKey].PresentCount++
-				}
-			}
+	for _, count := range dailyCounts {
+		overallSummary.addStatus(count.Status, count.Count)
+		series.add(count.Date, count.Status, count.Count)
 
-			if period == "month" || period == "all" {
-				monthKey := parsedDate.Format("2006-01")
-				if monthlyMap[monthKey] == nil {
-					monthlyMap[monthKey] = &MonthlyTrend{Month: monthKey}
-				}
-				monthlyMap[monthKey].TotalDays++
-				if attendance.Status == "PRESENT" {
-					monthlyMap[monthKey].PresentCount++
-				}
+		if dailyMap[count.Date] == nil {
+			dailyOrder = append(dailyOrder, count.Date)
+			dailyMap[count.Date] = &DailyAttendance{
+				Date:          count.Date,
+				TotalStudents: len(registrations),
 			}
 		}
-	}
-
-	if overallSummary.TotalDays > 0 {
-		overallSummary.Percentage = float64(overallSummary.PresentCount) / float64(overallSummary.TotalDays) * 100
+		daily := dailyMap[count.Date]
+		daily.TotalStudents += count.Count
+		switch count.Status {
+		case "PRESENT":
+			daily.PresentCount += count.Count
+		case "ABSENT":
+			daily.AbsentCount += count.Count
+		case "LATE":
+			daily.LateCount += count.Count
+		case "EXCUSED":
+			daily.ExcusedCount += count.Count
+		}
 	}
 
 	var studentSummaries []StudentAttendanceSummary
-	for _, summary := range studentMap {
-		if summary.TotalDays > 0 {
-			summary.Percentage = float64(summary.PresentCount) / float64(summary.TotalDays) * 100
-		}
+	for _, studentID := range studentOrder {
+		summary := studentMap[studentID]
+		summary.Percentage = percentage(summary.PresentCount, summary.TotalDays)
 		studentSummaries = append(studentSummaries, *summary)
 	}
 
+	// Daily summaries are loaded in date order, so dailyOrder is already chronological.
 	var dailyData []DailyAttendance
 	if period == "day" {
-		for _, daily := range dailyMap {
-			if daily.TotalStudents > 0 {
-				daily.Percentage = float64(daily.PresentCount) / float64(daily.TotalStudents) * 100
-			}
+		for _, date := range dailyOrder {
+			daily := dailyMap[date]
+			daily.Percentage = percentage(daily.PresentCount, daily.TotalStudents)
 			dailyData = append(dailyData, *daily)
 		}
 	}
 
 	var weeklyData []WeeklyTrend
 	if period == "week" {
-		for _, trend := range weeklyMap {
-			if trend.TotalDays > 0 {
-				trend.Percentage = float64(trend.PresentCount) / float64(trend.TotalDays) * 100
-			}
-			weeklyData = append(weeklyData, *trend)
-		}
+		weeklyData = series.weeklyTrends(startDate, endDate)
 	}
 
 	var monthlyData []MonthlyTrend
 	if period == "month" {
-		for _, trend := range monthlyMap {
-			if trend.TotalDays > 0 {
-				trend.Percentage = float64(trend.PresentCount) / float64(trend.TotalDays) * 100
-			}
-			monthlyData = append(monthlyData, *trend)
-		}
+		monthlyData = series.monthlyTrends(startDate, endDate)
 	}
 
 	return ClassAttendanceReport{
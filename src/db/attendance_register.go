@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"skulla-api/live"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RegisterDraft     = "DRAFT"
+	RegisterSubmitted = "SUBMITTED"
+	RegisterLocked    = "LOCKED"
+)
+
+var (
+	ErrRegisterLocked       = errors.New("attendance register is locked")
+	ErrRegisterNotDraft     = errors.New("attendance register was already submitted")
+	ErrRegisterNotSubmitted = errors.New("attendance register must be submitted before it is locked")
+)
+
+// AttendanceRegister is the roll call of one StudentClass on one date.
+type AttendanceRegister struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint   `gorm:"not null;index:idx_register_school_id"`
+	StudentClassID uint   `gorm:"not null;uniqueIndex:unique_register_class_date"`
+	Date           string `gorm:"type:date;not null;uniqueIndex:unique_register_class_date"`
+	Status         string `gorm:"size:20;not null"`
+	CreatedBy      string `gorm:"size:500"`
+	SubmittedBy    string `gorm:"size:500"`
+	SubmittedAt    *time.Time
+	LockedBy       string `gorm:"size:500"`
+	LockedAt       *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (AttendanceRegister) TableName() string {
+	return "AttendanceRegister"
+}
+
+// IncompleteRegisterError reports the active registrations that still have no
+// attendance in a register that is being submitted.
+type IncompleteRegisterError struct {
+	Missing []uint
+}
+
+func (e *IncompleteRegisterError) Error() string {
+	return fmt.Sprintf("%d active registrations have no attendance", len(e.Missing))
+}
+
+func registerKey(studentClassID uint, date string) string {
+	return fmt.Sprintf("%d/%s", studentClassID, normalizeDate(date))
+}
+
+// excludeDraftRegisters drops rows whose class and date, read from the given
+// columns, belong to a register that is still a draft.
+func excludeDraftRegisters(query *gorm.DB, classColumn string, dateColumn string) *gorm.DB {
+	return query.Where(fmt.Sprintf(
+		`NOT EXISTS (SELECT 1 FROM "AttendanceRegister" WHERE "AttendanceRegister".student_class_id = %s AND "AttendanceRegister".date = %s AND "AttendanceRegister".status = ?)`,
+		classColumn, dateColumn,
+	), RegisterDraft)
+}
+
+// loadRegisters returns the registers of the classes and dates of records,
+// keyed by registerKey. The rows stay locked until the transaction ends.
+func loadRegisters(tx *gorm.DB, records []BulkAttendanceRecord, classByRegistration map[uint]uint) (map[string]AttendanceRegister, error) {
+	var classIDs []uint
+	var dates []string
+	for _, record := range records {
+		classIDs = append(classIDs, classByRegistration[record.RegistrationID])
+		dates = append(dates, normalizeDate(record.Date))
+	}
+
+	var registers []AttendanceRegister
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("student_class_id IN ?", classIDs).
+		Where("date IN ?", dates).
+		Find(&registers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]AttendanceRegister)
+	for _, register := range registers {
+		byKey[registerKey(register.StudentClassID, register.Date)] = register
+	}
+	return byKey, nil
+}
+
+// openRegisters opens the missing registers of records and returns all of
+// them, keyed by registerKey.
+func openRegisters(tx *gorm.DB, records []BulkAttendanceRecord, classByRegistration map[uint]uint) (map[string]AttendanceRegister, error) {
+	registers, err := loadRegisters(tx, records, classByRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		studentClassID := classByRegistration[record.RegistrationID]
+		key := registerKey(studentClassID, record.Date)
+		register, exists := registers[key]
+		if exists && register.Status == RegisterLocked {
+			return nil, fmt.Errorf("%w: class %d on %s", ErrRegisterLocked, studentClassID, normalizeDate(record.Date))
+		}
+		if exists {
+			continue
+		}
+
+		now := time.Now()
+		register = AttendanceRegister{
+			StudentClassID: studentClassID,
+			Date:           normalizeDate(record.Date),
+			Status:         RegisterSubmitted,
+			CreatedBy:      record.UserEmail,
+			SubmittedBy:    record.UserEmail,
+			SubmittedAt:    &now,
+		}
+		if record.Draft {
+			// Attendance without a register already counts as submitted.
+			var recorded int64
+			err := tx.Table("Attendance").
+				Joins(`JOIN "Registration" ON "Registration".id = "Attendance".registration_id`).
+				Where(`"Registration".student_class_id = ?`, studentClassID).
+				Where(`"Attendance".date = ?`, normalizeDate(record.Date)).
+				Count(&recorded).Error
+			if err != nil {
+				return nil, err
+			}
+			if recorded == 0 {
+				register.Status = RegisterDraft
+				register.SubmittedBy = ""
+				register.SubmittedAt = nil
+			}
+		}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&register)
+		if created.Error != nil {
+			return nil, created.Error
+		}
+		registers[key] = register
+	}
+	return registers, nil
+}
+
+// submittedRecords returns the records whose register is no longer a draft.
+func submittedRecords(records []BulkAttendanceRecord, registers map[string]AttendanceRegister, classByRegistration map[uint]uint) []BulkAttendanceRecord {
+	var submitted []BulkAttendanceRecord
+	for _, record := range records {
+		if registers[registerKey(classByRegistration[record.RegistrationID], record.Date)].Status != RegisterDraft {
+			submitted = append(submitted, record)
+		}
+	}
+	return submitted
+}
+
+// ListAttendanceRegisters returns the registers of a class within
+// [startDate, endDate], ordered by date.
+func ListAttendanceRegisters(ctx context.Context, studentClassID uint, startDate string, endDate string) []AttendanceRegister {
+	var registers []AttendanceRegister
+	conn(ctx).Where("student_class_id = ?", studentClassID).
+		Where("date >= ?", startDate).
+		Where("date <= ?", endDate).
+		Order("date ASC").
+		Find(&registers)
+	for i := range registers {
+		registers[i].Date = normalizeDate(registers[i].Date)
+	}
+	return registers
+}
+
+func lockedRegister(tx *gorm.DB, studentClassID uint, date string) (AttendanceRegister, error) {
+	var register AttendanceRegister
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("student_class_id = ?", studentClassID).
+		Where("date = ?", normalizeDate(date)).
+		First(&register).Error
+	register.Date = normalizeDate(register.Date)
+	return register, err
+}
+
+// SubmitAttendanceRegister submits a complete draft register. missingStatus, if
+// set, is recorded for registrations without attendance.
+func SubmitAttendanceRegister(ctx context.Context, studentClassID uint, date string, userEmail string, missingStatus string) (AttendanceRegister, error) {
+	var register AttendanceRegister
+	var events []live.Event
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		register, err = lockedRegister(tx, studentClassID, date)
+		if err != nil {
+			return err
+		}
+		if register.Status != RegisterDraft {
+			return ErrRegisterNotDraft
+		}
+
+		var registrationIDs []uint
+		err = tx.Model(&Registration{}).
+			Where("student_class_id = ?", studentClassID).
+			Where("status = ?", ActiveRegistrationStatus).
+			Order("id ASC").
+			Pluck("id", &registrationIDs).Error
+		if err != nil {
+			return err
+		}
+
+		var marked []uint
+		err = tx.Model(&Attendance{}).
+			Where("registration_id IN ?", registrationIDs).
+			Where("date = ?", register.Date).
+			Pluck("registration_id", &marked).Error
+		if err != nil {
+			return err
+		}
+
+		missing := []uint{}
+		var missingRecords []BulkAttendanceRecord
+		for _, registrationID := range registrationIDs {
+			if !slices.Contains(marked, registrationID) {
+				missing = append(missing, registrationID)
+				missingRecords = append(missingRecords, BulkAttendanceRecord{
+					RegistrationID: registrationID,
+					Date:           register.Date,
+					Status:         missingStatus,
+					UserEmail:      userEmail,
+				})
+			}
+		}
+		if len(missing) > 0 && missingStatus == "" {
+			return &IncompleteRegisterError{Missing: missing}
+		}
+		if len(missingRecords) > 0 {
+			events, err = recordAttendance(tx, missingRecords)
+			if err != nil {
+				return err
+			}
+		}
+
+		var attendances []Attendance
+		err = tx.Joins(`JOIN "Registration" ON "Registration".id = "Attendance".registration_id`).
+			Where(`"Registration".student_class_id = ?`, studentClassID).
+			Where(`"Attendance".date = ?`, register.Date).
+			Order(`"Attendance".registration_id ASC`).
+			Find(&attendances).Error
+		if err != nil {
+			return err
+		}
+
+		var records []BulkAttendanceRecord
+		for _, attendance := range attendances {
+			records = append(records, BulkAttendanceRecord{
+				RegistrationID: attendance.RegistrationID,
+				Date:           register.Date,
+				Status:         attendance.Status,
+			})
+			err := writeOutboxEvent(tx, EventAttendanceRecorded, AttendanceEventPayload{
+				RegistrationID: attendance.RegistrationID,
+				StudentClassID: studentClassID,
+				Date:           register.Date,
+				Status:         attendance.Status,
+				Remarks:        attendance.Remarks,
+				RecordedBy:     attendance.UpdatedBy,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		register.Status = RegisterSubmitted
+		register.SubmittedBy = userEmail
+		register.SubmittedAt = &now
+		err = tx.Model(&register).Updates(map[string]interface{}{
+			"status":       register.Status,
+			"submitted_by": register.SubmittedBy,
+			"submitted_at": register.SubmittedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return enqueueGuardianNotifications(tx, records)
+	})
+	if err == nil {
+		publishAttendanceEvents(events)
+	}
+	return register, err
+}
+
+// LockAttendanceRegister locks a submitted register against further writes.
+func LockAttendanceRegister(ctx context.Context, studentClassID uint, date string, userEmail string) (AttendanceRegister, error) {
+	var register AttendanceRegister
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		register, err = lockedRegister(tx, studentClassID, date)
+		if err != nil {
+			return err
+		}
+		switch register.Status {
+		case RegisterDraft:
+			return ErrRegisterNotSubmitted
+		case RegisterLocked:
+			return ErrRegisterLocked
+		}
+
+		now := time.Now()
+		register.Status = RegisterLocked
+		register.LockedBy = userEmail
+		register.LockedAt = &now
+		return tx.Model(&register).Updates(map[string]interface{}{
+			"status":    register.Status,
+			"locked_by": register.LockedBy,
+			"locked_at": register.LockedAt,
+		}).Error
+	})
+	return register, err
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type ClassAttendanceComparison struct {
+	StudentClassID     uint             `json:"studentClassId"`
+	StudentClassName   string           `json:"studentClassName"`
+	CourseID           uint             `json:"courseId"`
+	CourseName         string           `json:"courseName"`
+	Rank               int              `json:"rank"`
+	Summary            AttendanceReport `json:"summary"`
+	PreviousPercentage float64          `json:"previousPercentage"`
+	PercentageDelta    float64          `json:"percentageDelta"`
+}
+
+type CourseAttendanceReport struct {
+	CourseID           uint                        `json:"courseId"`
+	CourseName         string                      `json:"courseName"`
+	StartDate          string                      `json:"startDate"`
+	EndDate            string                      `json:"endDate"`
+	PreviousStartDate  string                      `json:"previousStartDate"`
+	PreviousEndDate    string                      `json:"previousEndDate"`
+	OverallSummary     AttendanceReport            `json:"overallSummary"`
+	PreviousPercentage float64                     `json:"previousPercentage"`
+	PercentageDelta    float64                     `json:"percentageDelta"`
+	Classes            []ClassAttendanceComparison `json:"classes"`
+	WeeklyTrends       []WeeklyTrend               `json:"weeklyTrends"`
+	MonthlyTrends      []MonthlyTrend              `json:"monthlyTrends"`
+}
+
+type SchoolAttendanceReport struct {
+	StartDate          string                      `json:"startDate"`
+	EndDate            string                      `json:"endDate"`
+	PreviousStartDate  string                      `json:"previousStartDate"`
+	PreviousEndDate    string                      `json:"previousEndDate"`
+	TotalClasses       int                         `json:"totalClasses"`
+	OverallSummary     AttendanceReport            `json:"overallSummary"`
+	PreviousPercentage float64                     `json:"previousPercentage"`
+	PercentageDelta    float64                     `json:"percentageDelta"`
+	Classes            []ClassAttendanceComparison `json:"classes"`
+	WeeklyTrends       []WeeklyTrend               `json:"weeklyTrends"`
+	MonthlyTrends      []MonthlyTrend              `json:"monthlyTrends"`
+}
+
+// previousPeriod returns the window of equal length that ends the day before
+// startDate.
+func previousPeriod(startDate string, endDate string) (string, string) {
+	start, startErr := parseDate(startDate)
+	end, endErr := parseDate(endDate)
+	if startErr != nil || endErr != nil || end.Before(start) {
+		return startDate, endDate
+	}
+
+	days := int(end.Sub(start)/(24*time.Hour)) + 1
+	previousEnd := start.AddDate(0, 0, -1)
+	previousStart := previousEnd.AddDate(0, 0, -(days - 1))
+	return previousStart.Format(dateLayout), previousEnd.Format(dateLayout)
+}
+
+type classRollup struct {
+	previousStartDate string
+	previousEndDate   string
+	current           AttendanceReport
+	previous          AttendanceReport
+	classes           []ClassAttendanceComparison
+	series            *timeSeries
+}
+
+// rollupClasses ranks studentClasses over [startDate, endDate] against the
+// preceding window of equal length.
+func rollupClasses(ctx context.Context, studentClasses []StudentClass, startDate string, endDate string, includeDrafts bool) classRollup {
+	previousStartDate, previousEndDate := previousPeriod(startDate, endDate)
+	rollup := classRollup{
+		previousStartDate: previousStartDate,
+		previousEndDate:   previousEndDate,
+		series:            newTimeSeries(),
+	}
+
+	var classIDs []uint
+	comparisons := make(map[uint]*ClassAttendanceComparison)
+	previousSummaries := make(map[uint]*AttendanceReport)
+	for _, studentClass := range studentClasses {
+		classIDs = append(classIDs, studentClass.ID)
+		comparisons[studentClass.ID] = &ClassAttendanceComparison{
+			StudentClassID:   studentClass.ID,
+			StudentClassName: studentClass.Name,
+			CourseID:         studentClass.CourseID,
+			CourseName:       studentClass.Course.Name,
+		}
+		previousSummaries[studentClass.ID] = &AttendanceReport{}
+	}
+
+	for _, summary := range listDailyClassAttendanceSummaries(ctx, classIDs, startDate, endDate, includeDrafts) {
+		for _, count := range summary.statusCounts() {
+			rollup.current.addStatus(count.Status, count.Count)
+			comparisons[summary.StudentClassID].Summary.addStatus(count.Status, count.Count)
+			rollup.series.add(count.Date, count.Status, count.Count)
+		}
+	}
+
+	for _, summary := range listDailyClassAttendanceSummaries(ctx, classIDs, previousStartDate, previousEndDate, includeDrafts) {
+		for _, count := range summary.statusCounts() {
+			rollup.previous.addStatus(count.Status, count.Count)
+			previousSummaries[summary.StudentClassID].addStatus(count.Status, count.Count)
+		}
+	}
+
+	for _, studentClass := range studentClasses {
+		comparison := comparisons[studentClass.ID]
+		comparison.PreviousPercentage = previousSummaries[studentClass.ID].Percentage
+		comparison.PercentageDelta = comparison.Summary.Percentage - comparison.PreviousPercentage
+		rollup.classes = append(rollup.classes, *comparison)
+	}
+
+	sort.SliceStable(rollup.classes, func(i, j int) bool {
+		if rollup.classes[i].Summary.Percentage != rollup.classes[j].Summary.Percentage {
+			return rollup.classes[i].Summary.Percentage > rollup.classes[j].Summary.Percentage
+		}
+		if rollup.classes[i].StudentClassName != rollup.classes[j].StudentClassName {
+			return rollup.classes[i].StudentClassName < rollup.classes[j].StudentClassName
+		}
+		return rollup.classes[i].StudentClassID < rollup.classes[j].StudentClassID
+	})
+	for i := range rollup.classes {
+		rollup.classes[i].Rank = i + 1
+	}
+
+	return rollup
+}
+
+func GetCourseAttendanceReport(ctx context.Context, course Course, startDate string, endDate string, includeDrafts bool) CourseAttendanceReport {
+	var studentClasses []StudentClass
+	conn(ctx).Preload("Course").Where("course_id = ?", course.ID).Find(&studentClasses)
+
+	rollup := rollupClasses(ctx, studentClasses, startDate, endDate, includeDrafts)
+
+	return CourseAttendanceReport{
+		CourseID:           course.ID,
+		CourseName:         course.Name,
+		StartDate:          startDate,
+		EndDate:            endDate,
+		PreviousStartDate:  rollup.previousStartDate,
+		PreviousEndDate:    rollup.previousEndDate,
+		OverallSummary:     rollup.current,
+		PreviousPercentage: rollup.previous.Percentage,
+		PercentageDelta:    rollup.current.Percentage - rollup.previous.Percentage,
+		Classes:            rollup.classes,
+		WeeklyTrends:       rollup.series.weeklyTrends(startDate, endDate),
+		MonthlyTrends:      rollup.series.monthlyTrends(startDate, endDate),
+	}
+}
+
+// GetSchoolAttendanceReport compares every class with attendance in either
+// the requested window or the preceding one.
+func GetSchoolAttendanceReport(ctx context.Context, startDate string, endDate string, includeDrafts bool) SchoolAttendanceReport {
+	previousStartDate, _ := previousPeriod(startDate, endDate)
+
+	var classIDs []uint
+	submittedSummaries(conn(ctx), includeDrafts).
+		Where("date >= ?", previousStartDate).
+		Where("date <= ?", endDate).
+		Distinct().
+		Pluck("student_class_id", &classIDs)
+
+	var studentClasses []StudentClass
+	if len(classIDs) > 0 {
+		conn(ctx).Preload("Course").Where("id IN ?", classIDs).Find(&studentClasses)
+	}
+
+	rollup := rollupClasses(ctx, studentClasses, startDate, endDate, includeDrafts)
+
+	return SchoolAttendanceReport{
+		StartDate:          startDate,
+		EndDate:            endDate,
+		PreviousStartDate:  rollup.previousStartDate,
+		PreviousEndDate:    rollup.previousEndDate,
+		TotalClasses:       len(studentClasses),
+		OverallSummary:     rollup.current,
+		PreviousPercentage: rollup.previous.Percentage,
+		PercentageDelta:    rollup.current.Percentage - rollup.previous.Percentage,
+		Classes:            rollup.classes,
+		WeeklyTrends:       rollup.series.weeklyTrends(startDate, endDate),
+		MonthlyTrends:      rollup.series.monthlyTrends(startDate, endDate),
+	}
+}
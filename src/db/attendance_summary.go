@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DailyClassAttendanceSummary holds the status counts of one StudentClass on
+// one date.
+type DailyClassAttendanceSummary struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint      `gorm:"not null;index:idx_summary_school_id"`
+	StudentClassID uint      `gorm:"not null;index:idx_summary_student_class_id;uniqueIndex:unique_student_class_date"`
+	Date           string    `gorm:"type:date;not null;index:idx_summary_date;uniqueIndex:unique_student_class_date"`
+	PresentCount   int       `gorm:"not null;default:0"`
+	AbsentCount    int       `gorm:"not null;default:0"`
+	LateCount      int       `gorm:"not null;default:0"`
+	ExcusedCount   int       `gorm:"not null;default:0"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (DailyClassAttendanceSummary) TableName() string {
+	return "DailyClassAttendanceSummary"
+}
+
+func (s *DailyClassAttendanceSummary) addStatus(status string, count int) {
+	switch status {
+	case "PRESENT":
+		s.PresentCount += count
+	case "ABSENT":
+		s.AbsentCount += count
+	case "LATE":
+		s.LateCount += count
+	case "EXCUSED":
+		s.ExcusedCount += count
+	}
+}
+
+// statusCounts expands the summary into one dailyStatusCount per non-zero status.
+func (s DailyClassAttendanceSummary) statusCounts() []dailyStatusCount {
+	var counts []dailyStatusCount
+	for _, count := range []dailyStatusCount{
+		{Date: s.Date, Status: "PRESENT", Count: s.PresentCount},
+		{Date: s.Date, Status: "ABSENT", Count: s.AbsentCount},
+		{Date: s.Date, Status: "LATE", Count: s.LateCount},
+		{Date: s.Date, Status: "EXCUSED", Count: s.ExcusedCount},
+	} {
+		if count.Count > 0 {
+			counts = append(counts, count)
+		}
+	}
+	return counts
+}
+
+func upsertDailyClassAttendanceSummaries(tx *gorm.DB, summaries []DailyClassAttendanceSummary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "student_class_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"present_count", "absent_count", "late_count", "excused_count", "updated_at"}),
+	}).CreateInBatches(&summaries, 500).Error
+}
+
+// summaryDeltas collects the status count changes of a write, keyed by
+// registerKey, so they can be applied to the summary rows in one pass.
+type summaryDeltas map[string]*DailyClassAttendanceSummary
+
+func (d summaryDeltas) add(studentClassID uint, date string, status string, count int) {
+	key := registerKey(studentClassID, date)
+	if d[key] == nil {
+		d[key] = &DailyClassAttendanceSummary{StudentClassID: studentClassID, Date: normalizeDate(date)}
+	}
+	d[key].addStatus(status, count)
+}
+
+// apply adds the deltas to the summary rows inside the caller's transaction.
+func (d summaryDeltas) apply(tx *gorm.DB) error {
+	keys := make([]string, 0, len(d))
+	for key := range d {
+		keys = append(keys, key)
+	}
+	// A fixed order keeps concurrent writers from locking rows in turn.
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		delta := d[key]
+		row := DailyClassAttendanceSummary{StudentClassID: delta.StudentClassID, Date: delta.Date}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&DailyClassAttendanceSummary{}).
+			Where("student_class_id = ? AND date = ?", delta.StudentClassID, delta.Date).
+			Updates(map[string]interface{}{
+				"present_count": gorm.Expr("present_count + ?", delta.PresentCount),
+				"absent_count":  gorm.Expr("absent_count + ?", delta.AbsentCount),
+				"late_count":    gorm.Expr("late_count + ?", delta.LateCount),
+				"excused_count": gorm.Expr("excused_count + ?", delta.ExcusedCount),
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildDailyClassAttendanceSummaries discards every summary row and
+// regenerates them from the Attendance table.
+func RebuildDailyClassAttendanceSummaries(ctx context.Context) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&DailyClassAttendanceSummary{}).Error; err != nil {
+			return err
+		}
+
+		var counts []classDailyStatusCount
+		err := tx.Table("Attendance").
+			Joins(`JOIN "Registration" ON "Registration".id = "Attendance".registration_id`).
+			Select(`"Registration".student_class_id AS student_class_id, "Attendance".date AS date, "Attendance".status AS status, COUNT(*) AS count`).
+			Group(`"Registration".student_class_id, "Attendance".date, "Attendance".status`).
+			Order(`"Registration".student_class_id ASC, "Attendance".date ASC`).
+			Scan(&counts).Error
+		if err != nil {
+			return err
+		}
+
+		var summaries []DailyClassAttendanceSummary
+		index := make(map[string]int)
+		for _, count := range counts {
+			date := normalizeDate(count.Date)
+			key := fmt.Sprintf("%d/%s", count.StudentClassID, date)
+			i, exists := index[key]
+			if !exists {
+				i = len(summaries)
+				index[key] = i
+				summaries = append(summaries, DailyClassAttendanceSummary{StudentClassID: count.StudentClassID, Date: date})
+			}
+			summaries[i].addStatus(count.Status, count.Count)
+		}
+
+		return upsertDailyClassAttendanceSummaries(tx, summaries)
+	})
+}
+
+// listDailyClassAttendanceSummaries returns the summary rows of the classes
+// within [startDate, endDate], ordered by date.
+func listDailyClassAttendanceSummaries(ctx context.Context, studentClassIDs []uint, startDate string, endDate string, includeDrafts bool) []DailyClassAttendanceSummary {
+	var summaries []DailyClassAttendanceSummary
+	if len(studentClassIDs) == 0 {
+		return summaries
+	}
+	submittedSummaries(conn(ctx), includeDrafts).
+		Where("student_class_id IN ?", studentClassIDs).
+		Where("date >= ?", startDate).
+		Where("date <= ?", endDate).
+		Order("date ASC, student_class_id ASC").
+		Find(&summaries)
+	for i := range summaries {
+		summaries[i].Date = normalizeDate(summaries[i].Date)
+	}
+	return summaries
+}
+
+// submittedSummaries restricts a query over DailyClassAttendanceSummary to
+// submitted registers, unless includeDrafts is set.
+func submittedSummaries(query *gorm.DB, includeDrafts bool) *gorm.DB {
+	query = query.Model(&DailyClassAttendanceSummary{})
+	if includeDrafts {
+		return query
+	}
+	return excludeDraftRegisters(query, `"DailyClassAttendanceSummary".student_class_id`, `"DailyClassAttendanceSummary".date`)
+}
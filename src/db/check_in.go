@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"skulla-api/live"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyCheckedIn = errors.New("student already checked in")
+	ErrDeviceUsed       = errors.New("device already used to check in another student")
+	ErrAlreadyMarked    = errors.New("attendance already recorded for this date")
+)
+
+// CheckInWindow is a period during which students of a StudentClass can check
+// in by scanning a QR code.
+type CheckInWindow struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint      `gorm:"not null;index:idx_check_in_window_school_id"`
+	StudentClassID uint      `gorm:"not null;index:idx_check_in_window_student_class_id"`
+	Date           string    `gorm:"type:date;not null"`
+	Secret         string    `gorm:"size:64;not null" json:"-"`
+	OpensAt        time.Time `gorm:"not null"`
+	LateAfter      time.Time `gorm:"not null"`
+	ClosesAt       time.Time `gorm:"not null"`
+	CreatedBy      string    `gorm:"size:500"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (CheckInWindow) TableName() string {
+	return "CheckInWindow"
+}
+
+// IsOpen reports whether students can check in at now.
+func (w CheckInWindow) IsOpen(now time.Time) bool {
+	return !now.Before(w.OpensAt) && now.Before(w.ClosesAt)
+}
+
+// CheckIn records a student's self check-in.
+type CheckIn struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID        uint      `gorm:"not null;index:idx_check_in_school_id"`
+	CheckInWindowID uint      `gorm:"not null;uniqueIndex:unique_check_in_registration;uniqueIndex:unique_check_in_device"`
+	RegistrationID  uint      `gorm:"not null;uniqueIndex:unique_check_in_registration"`
+	DeviceID        string    `gorm:"size:255;not null;uniqueIndex:unique_check_in_device"`
+	Status          string    `gorm:"size:20;not null"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
+
+func (CheckIn) TableName() string {
+	return "CheckIn"
+}
+
+func CreateCheckInWindow(ctx context.Context, window *CheckInWindow) error {
+	return conn(ctx).Create(window).Error
+}
+
+func GetCheckInWindow(ctx context.Context, windowID uint) (CheckInWindow, error) {
+	var window CheckInWindow
+	err := conn(ctx).First(&window, windowID).Error
+	return window, err
+}
+
+// RecordCheckIn stores the check-in and its attendance in one transaction.
+func RecordCheckIn(ctx context.Context, window CheckInWindow, checkIn CheckIn, userEmail string) error {
+	var events []live.Event
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&CheckIn{}).
+			Where("check_in_window_id = ?", window.ID).
+			Where("registration_id = ?", checkIn.RegistrationID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyCheckedIn
+		}
+
+		err = tx.Model(&CheckIn{}).
+			Where("check_in_window_id = ?", window.ID).
+			Where("device_id = ?", checkIn.DeviceID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDeviceUsed
+		}
+
+		// A concurrent check-in is only caught by the unique keys.
+		checkIn.CheckInWindowID = window.ID
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&checkIn)
+		if created.Error != nil {
+			return created.Error
+		}
+		if created.RowsAffected == 0 {
+			err := tx.Model(&CheckIn{}).
+				Where("check_in_window_id = ?", window.ID).
+				Where("registration_id = ?", checkIn.RegistrationID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrAlreadyCheckedIn
+			}
+			return ErrDeviceUsed
+		}
+
+		createOnly := 0
+		events, err = recordAttendance(tx, []BulkAttendanceRecord{{
+			RegistrationID:  checkIn.RegistrationID,
+			Date:            window.Date,
+			Status:          checkIn.Status,
+			Remarks:         "Self check-in",
+			UserEmail:       userEmail,
+			ExpectedVersion: &createOnly,
+		}})
+		var conflict *VersionConflictError
+		if errors.As(err, &conflict) {
+			return ErrAlreadyMarked
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	publishAttendanceEvents(events)
+	return nil
+}
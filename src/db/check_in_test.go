@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestRecordCheckIn_ConcurrentDuplicate stores a competing check-in right
+// before the insert, after RecordCheckIn has looked for duplicates, as a
+// concurrent request would.
+func TestRecordCheckIn_ConcurrentDuplicate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		existing CheckIn
+		expected error
+	}{
+		{"same registration", CheckIn{RegistrationID: 1, DeviceID: "other-phone"}, ErrAlreadyCheckedIn},
+		{"same device", CheckIn{RegistrationID: 2, DeviceID: "phone"}, ErrDeviceUsed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			if err := database.AutoMigrate(&CheckIn{}); err != nil {
+				t.Fatalf("Failed to migrate: %v", err)
+			}
+			SetDB(database)
+
+			inserted := false
+			database.Callback().Create().Before("gorm:create").Register("test:concurrent_check_in", func(tx *gorm.DB) {
+				if _, ok := tx.Statement.Dest.(*CheckIn); !ok || inserted {
+					return
+				}
+				inserted = true
+				competing := tc.existing
+				competing.CheckInWindowID, competing.Status = 1, "PRESENT"
+				if err := tx.Session(&gorm.Session{NewDB: true}).Create(&competing).Error; err != nil {
+					t.Fatalf("Failed to store competing check-in: %v", err)
+				}
+			})
+
+			ctx := WithSchool(context.Background(), 1)
+			err = RecordCheckIn(ctx, CheckInWindow{ID: 1, Date: "2024-02-01"}, CheckIn{RegistrationID: 1, DeviceID: "phone", Status: "PRESENT"}, "student@test.com")
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import "context"
+
+// ClassCompletion tells how far roll call for one StudentClass has progressed
+// on a date.
+type ClassCompletion struct {
+	StudentClassID   uint    `json:"studentClassId"`
+	StudentClassName string  `json:"studentClassName"`
+	Date             string  `json:"date"`
+	Registrations    int     `json:"registrations"`
+	Recorded         int     `json:"recorded"`
+	Percentage       float64 `json:"percentage"`
+}
+
+type classRegistrationCount struct {
+	StudentClassID uint
+	Count          int
+}
+
+// GetClassCompletions returns the roll-call progress of every given class on
+// date, ordered by class name.
+func GetClassCompletions(ctx context.Context, studentClassIDs []uint, date string) []ClassCompletion {
+	completions := []ClassCompletion{}
+	if len(studentClassIDs) == 0 {
+		return completions
+	}
+
+	var classes []StudentClass
+	conn(ctx).Select("id", "name").
+		Where("id IN ?", studentClassIDs).
+		Order("name ASC, id ASC").
+		Find(&classes)
+
+	var counts []classRegistrationCount
+	conn(ctx).Model(&Registration{}).
+		Select("student_class_id, COUNT(*) AS count").
+		Where("student_class_id IN ?", studentClassIDs).
+		Where("status = ?", ActiveRegistrationStatus).
+		Group("student_class_id").
+		Scan(&counts)
+
+	registrations := make(map[uint]int)
+	for _, count := range counts {
+		registrations[count.StudentClassID] = count.Count
+	}
+
+	recorded := make(map[uint]int)
+	for _, summary := range listDailyClassAttendanceSummaries(ctx, studentClassIDs, date, date, true) {
+		recorded[summary.StudentClassID] = summary.PresentCount + summary.AbsentCount + summary.LateCount + summary.ExcusedCount
+	}
+
+	for _, class := range classes {
+		completions = append(completions, ClassCompletion{
+			StudentClassID:   class.ID,
+			StudentClassName: class.Name,
+			Date:             date,
+			Registrations:    registrations[class.ID],
+			Recorded:         recorded[class.ID],
+			Percentage:       percentage(recorded[class.ID], registrations[class.ID]),
+		})
+	}
+	return completions
+}
+
+// ListActiveStudentClassIDs returns every class that has at least one active
+// registration.
+func ListActiveStudentClassIDs(ctx context.Context) []uint {
+	var studentClassIDs []uint
+	conn(ctx).Model(&Registration{}).
+		Distinct("student_class_id").
+		Where("status = ?", ActiveRegistrationStatus).
+		Order("student_class_id ASC").
+		Pluck("student_class_id", &studentClassIDs)
+	return studentClassIDs
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ClassSchedule is one weekly session of a StudentClass. Weekday follows
+// ISO 8601 (1 = Monday, 7 = Sunday) and times are local "HH:MM" strings.
+type ClassSchedule struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint   `gorm:"not null;index:idx_schedule_school_id"`
+	StudentClassID uint   `gorm:"not null;index:idx_schedule_student_class_id"`
+	Weekday        int    `gorm:"not null"`
+	StartTime      string `gorm:"size:5;not null"`
+	EndTime        string `gorm:"size:5;not null"`
+}
+
+func (ClassSchedule) TableName() string {
+	return "ClassSchedule"
+}
+
+// isoWeekday converts date's weekday to ISO 8601 numbering.
+func isoWeekday(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(date.Weekday())
+}
+
+func ListClassSchedule(ctx context.Context, studentClassID uint) []ClassSchedule {
+	var schedule []ClassSchedule
+	conn(ctx).Where("student_class_id = ?", studentClassID).
+		Order("weekday ASC, start_time ASC").
+		Find(&schedule)
+	return schedule
+}
+
+// ReplaceClassSchedule swaps the whole weekly timetable of a StudentClass.
+func ReplaceClassSchedule(ctx context.Context, studentClassID uint, schedule []ClassSchedule) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("student_class_id = ?", studentClassID).Delete(&ClassSchedule{}).Error; err != nil {
+			return err
+		}
+		if len(schedule) == 0 {
+			return nil
+		}
+		for i := range schedule {
+			schedule[i].ID = 0
+			schedule[i].StudentClassID = studentClassID
+		}
+		return tx.Create(&schedule).Error
+	})
+}
@@ -1,75 +1,164 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
+	"skulla-api/config"
+	"skulla-api/metrics"
+	"strings"
+	"time"
 
+	driver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverMySQL    = config.DriverMySQL
+	DriverPostgres = config.DriverPostgres
+)
+
+// mysqlANSIQuotes adds ANSI_QUOTES to a sql_mode expression.
+const mysqlANSIQuotes = "CONCAT(%s, ',ANSI_QUOTES')"
+
 var db *gorm.DB
 
-func Connect() {
-	log.Printf("Connecting to %s", dbHost())
+// maxConnectBackoff caps the wait between two connection attempts.
+const maxConnectBackoff = 30 * time.Second
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%v)/%s?charset=utf8mb4&parseTime=True&loc=Local", dbUsername(), dbPassword(), dbHost(), dbPort(), dbName())
+func Connect(settings config.Database) {
+	if settings.URL != "" {
+		log.Printf("Connecting to %s database from its URL", settings.Driver)
+	} else {
+		log.Printf("Connecting to %s database at %s", settings.Driver, settings.Host)
+	}
 
-	db0, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	db = db0
+	dialector, err := Dialector(settings)
+	if err != nil {
+		log.Fatal("Invalid database configuration:", err)
+	}
+
+	db0, err := openWithRetry(dialector, settings.ConnectAttempts, settings.ConnectBackoff)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	db = db0
+
+	if err := db.Use(tenantScope{}); err != nil {
+		log.Fatal("Failed to register tenant scope:", err)
+	}
+	if err := db.Use(queryMetrics{}); err != nil {
+		log.Fatal("Failed to register query metrics:", err)
+	}
+	if err := db.Use(queryTracing{}); err != nil {
+		log.Fatal("Failed to register query tracing:", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB); err != nil {
+			log.Fatal("Failed to register connection pool metrics:", err)
+		}
+	}
 
 	log.Println("Successfully connected to database")
 }
 
-func dbHost() string {
-	val, ok := os.LookupEnv("DB_HOST")
-	if !ok {
-		return "localhost"
+// openWithRetry opens dialector, retrying up to attempts times in all with a
+// backoff that doubles after every failure, and returns the last error.
+func openWithRetry(dialector gorm.Dialector, attempts int, backoff time.Duration) (*gorm.DB, error) {
+	for attempt := 1; ; attempt++ {
+		database, err := gorm.Open(dialector, &gorm.Config{})
+		if err == nil {
+			return database, nil
+		}
+		if attempt >= attempts {
+			return nil, err
+		}
+
+		log.Printf("Failed to connect to database (attempt %d of %d), retrying in %s: %v", attempt, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
 	}
-	return val
 }
 
-func dbPort() int {
-	val, ok := os.LookupEnv("DB_PORT")
-	if !ok {
-		return 3306
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
 	}
-	port, err := strconv.Atoi(val)
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the connection pool, waiting for queries in progress.
+func Close() error {
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("Failed to parse DB_PORT:", err)
+		return err
 	}
-	return port
+	return sqlDB.Close()
 }
 
-func dbName() string {
-	val, ok := os.LookupEnv("DB_NAME")
-	if !ok {
-		return "omniscience"
+// Dialector opens the configured driver with the database URL, or with a DSN
+// built from the other settings when no URL is set.
+func Dialector(settings config.Database) (gorm.Dialector, error) {
+	switch settings.Driver {
+	case DriverMySQL:
+		dsn, err := mysqlDSN(settings)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(postgresDSN(settings)), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", settings.Driver)
 	}
-	return val
 }
 
-func dbUsername() string {
-	val, ok := os.LookupEnv("DB_USERNAME")
-	if !ok {
-		return "admin"
+func mysqlDSN(settings config.Database) (string, error) {
+	dsn := settings.URL
+	if dsn == "" {
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%v)/%s?charset=utf8mb4&parseTime=True&loc=Local", settings.Username, settings.Password, settings.Host, settings.Port, settings.Name)
+	}
+
+	mysqlConfig, err := driver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
 	}
-	return val
+	if mysqlConfig.Params == nil {
+		mysqlConfig.Params = make(map[string]string)
+	}
+	// Raw queries need ANSI_QUOTES.
+	sqlMode, exists := mysqlConfig.Params["sql_mode"]
+	if !exists {
+		sqlMode = "@@sql_mode"
+	}
+	if !strings.Contains(strings.ToUpper(sqlMode), "ANSI_QUOTES") {
+		mysqlConfig.Params["sql_mode"] = fmt.Sprintf(mysqlANSIQuotes, sqlMode)
+	}
+	return mysqlConfig.FormatDSN(), nil
 }
 
-func dbPassword() string {
-	val, ok := os.LookupEnv("DB_PASSWORD")
-	if !ok {
-		return "admin"
+func postgresDSN(settings config.Database) string {
+	if settings.URL != "" {
+		return settings.URL
 	}
-	return val
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", settings.Host, settings.Port, settings.Username, settings.Password, settings.Name)
 }
 
+// SetDB replaces the connection, typically with a test database, and scopes
+// it to tenants, times and traces its queries like Connect does.
 func SetDB(database *gorm.DB) {
+	if err := database.Use(tenantScope{}); err != nil {
+		log.Fatal("Failed to register tenant scope:", err)
+	}
+	if err := database.Use(queryMetrics{}); err != nil {
+		log.Fatal("Failed to register query metrics:", err)
+	}
+	if err := database.Use(queryTracing{}); err != nil {
+		log.Fatal("Failed to register query tracing:", err)
+	}
 	db = database
 }
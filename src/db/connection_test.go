@@ -0,0 +1,58 @@
+package db
+
+import (
+	"path/filepath"
+	"skulla-api/config"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/sqlite"
+)
+
+func TestOpenWithRetry(t *testing.T) {
+	database, err := openWithRetry(sqlite.Open(":memory:"), 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected to connect, got %v", err)
+	}
+	if sqlDB, err := database.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	unreachable := filepath.Join(t.TempDir(), "missing", "test.db")
+	started := time.Now()
+	if _, err := openWithRetry(sqlite.Open(unreachable), 3, 20*time.Millisecond); err == nil {
+		t.Fatal("Expected an error for a database that cannot be opened")
+	}
+	if elapsed := time.Since(started); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected two backoffs of 20ms and 40ms between three attempts, returned after %s", elapsed)
+	}
+}
+
+func TestMySQLDSN_AddsANSIQuotes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"server default", "user:pass@tcp(db:3306)/skulla", "CONCAT(@@sql_mode, ',ANSI_QUOTES')"},
+		{"own sql_mode", "user:pass@tcp(db:3306)/skulla?sql_mode='TRADITIONAL'", "CONCAT('TRADITIONAL', ',ANSI_QUOTES')"},
+		{"already ansi quotes", "user:pass@tcp(db:3306)/skulla?sql_mode='TRADITIONAL,ANSI_QUOTES'", "'TRADITIONAL,ANSI_QUOTES'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dsn, err := mysqlDSN(config.Database{Driver: DriverMySQL, URL: tc.url})
+			if err != nil {
+				t.Fatalf("Failed to build DSN: %v", err)
+			}
+			parsed, err := driver.ParseDSN(dsn)
+			if err != nil {
+				t.Fatalf("Failed to parse DSN %q: %v", dsn, err)
+			}
+			if parsed.Params["sql_mode"] != tc.expected {
+				t.Errorf("Expected sql_mode %q, got %q", tc.expected, parsed.Params["sql_mode"])
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package db
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type Course struct {
 	ID           uint   `gorm:"primaryKey"`
+	SchoolID     uint   `gorm:"not null;index:idx_course_school_id"`
 	Name         string `gorm:"size:255;not null"`
 	TeacherEmail string `gorm:"column:teacher_email;size:255"`
 }
@@ -12,19 +16,25 @@ func (Course) TableName() string {
 	return "Course"
 }
 
-func ListCoursesByTeacherEmail(email string) []Course {
+func ListCoursesByTeacherEmail(ctx context.Context, email string) []Course {
 	var courses []Course
-	db.
-		Where("teacher_email LIKE ?", fmt.Sprintf("%%%s%%", email)).
+	conn(ctx).
+		Where("LOWER(teacher_email) LIKE LOWER(?)", fmt.Sprintf("%%%s%%", email)).
 		Find(&courses)
 	return courses
 }
 
-func IsTeacherEmailBelongToCourse(email string, courseId int) bool {
+func IsTeacherEmailBelongToCourse(ctx context.Context, email string, courseId int) bool {
 	var courses []Course
-	db.
-		Where("teacher_email LIKE ?", fmt.Sprintf("%%%s%%", email)).
+	conn(ctx).
+		Where("LOWER(teacher_email) LIKE LOWER(?)", fmt.Sprintf("%%%s%%", email)).
 		Where("id = ?", courseId).
 		Find(&courses)
 	return len(courses) > 0
 }
+
+func GetCourse(ctx context.Context, courseID uint) (Course, error) {
+	var course Course
+	err := conn(ctx).First(&course, courseID).Error
+	return course, err
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Delegation lends a teacher's access to some of their classes to a
+// substitute between ValidFrom and ValidTo.
+type Delegation struct {
+	ID             uint                     `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint                     `gorm:"not null;index:idx_delegation_school_id"`
+	GrantorEmail   string                   `gorm:"size:500;not null;index:idx_delegation_grantor_email"`
+	GranteeEmail   string                   `gorm:"size:500;not null;index:idx_delegation_grantee_email"`
+	ValidFrom      time.Time                `gorm:"not null"`
+	ValidTo        time.Time                `gorm:"not null"`
+	StudentClasses []DelegationStudentClass `gorm:"foreignKey:DelegationID"`
+	CreatedAt      time.Time                `gorm:"autoCreateTime"`
+}
+
+func (Delegation) TableName() string {
+	return "Delegation"
+}
+
+// IsActive reports whether the delegation grants access at now.
+func (d Delegation) IsActive(now time.Time) bool {
+	return !now.Before(d.ValidFrom) && now.Before(d.ValidTo)
+}
+
+type DelegationStudentClass struct {
+	DelegationID   uint `gorm:"primaryKey"`
+	SchoolID       uint `gorm:"not null;index:idx_delegation_class_school_id"`
+	StudentClassID uint `gorm:"primaryKey;index:idx_delegation_student_class_id"`
+}
+
+func (DelegationStudentClass) TableName() string {
+	return "DelegationStudentClass"
+}
+
+func CreateDelegation(ctx context.Context, delegation *Delegation) error {
+	return conn(ctx).Create(delegation).Error
+}
+
+func GetDelegation(ctx context.Context, delegationID uint) (Delegation, error) {
+	var delegation Delegation
+	err := conn(ctx).Preload("StudentClasses").First(&delegation, delegationID).Error
+	return delegation, err
+}
+
+// ListDelegations returns the delegations granted by or to email, newest
+// first.
+func ListDelegations(ctx context.Context, email string) []Delegation {
+	var delegations []Delegation
+	conn(ctx).Preload("StudentClasses").
+		Where("grantor_email = ? OR grantee_email = ?", email, email).
+		Order("valid_from DESC, id DESC").
+		Find(&delegations)
+	return delegations
+}
+
+// RevokeDelegation ends the delegation's window at now, unless it already
+// ended.
+func RevokeDelegation(ctx context.Context, delegationID uint, now time.Time) (Delegation, error) {
+	err := conn(ctx).Model(&Delegation{}).
+		Where("id = ?", delegationID).
+		Where("valid_to > ?", now).
+		Update("valid_to", now).Error
+	if err != nil {
+		return Delegation{}, err
+	}
+	return GetDelegation(ctx, delegationID)
+}
+
+// activeDelegations starts a query over DelegationStudentClass joined to the
+// delegations granted to email that are active at now.
+func activeDelegations(tx *gorm.DB, email string, now time.Time) *gorm.DB {
+	return tx.Table("DelegationStudentClass").
+		Joins(`JOIN "Delegation" ON "Delegation".id = "DelegationStudentClass".delegation_id`).
+		Where(`"Delegation".grantee_email = ?`, email).
+		Where(`"Delegation".valid_from <= ?`, now).
+		Where(`"Delegation".valid_to > ?`, now)
+}
+
+// DelegatedStudentClassIDs returns the classes email can access at now through
+// an active delegation.
+func DelegatedStudentClassIDs(ctx context.Context, email string, now time.Time) []uint {
+	var studentClassIDs []uint
+	activeDelegations(conn(ctx), email, now).
+		Distinct(`"DelegationStudentClass".student_class_id`).
+		Pluck(`"DelegationStudentClass".student_class_id`, &studentClassIDs)
+	return studentClassIDs
+}
+
+// delegationGrantors maps each class email can access only through a
+// delegation to the teacher who granted it.
+func delegationGrantors(tx *gorm.DB, email string, studentClassIDs []uint, now time.Time) (map[uint]string, error) {
+	var rows []struct {
+		StudentClassID uint
+		GrantorEmail   string
+	}
+	err := activeDelegations(tx, email, now).
+		Select(`"DelegationStudentClass".student_class_id AS student_class_id, "Delegation".grantor_email AS grantor_email`).
+		Joins(`JOIN "StudentClass" ON "StudentClass".id = "DelegationStudentClass".student_class_id`).
+		Joins(`JOIN "Course" ON "Course".id = "StudentClass".course_id`).
+		Where(`"DelegationStudentClass".student_class_id IN ?`, studentClassIDs).
+		Where(`LOWER(COALESCE("Course".teacher_email, '')) NOT LIKE LOWER(?)`, fmt.Sprintf("%%%s%%", email)).
+		Order(`"Delegation".id ASC`).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	grantors := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		if _, exists := grantors[row.StudentClassID]; !exists {
+			grantors[row.StudentClassID] = row.GrantorEmail
+		}
+	}
+	return grantors, nil
+}
+
+type delegatedWrite struct {
+	email          string
+	studentClassID uint
+}
+
+// recordDelegationGrantors finds, for every author and class among records,
+// the teacher whose active delegation lets the author write to the class.
+func recordDelegationGrantors(tx *gorm.DB, records []BulkAttendanceRecord, classByRegistration map[uint]uint, now time.Time) (map[delegatedWrite]string, error) {
+	classesByEmail := make(map[string][]uint)
+	for _, record := range records {
+		if record.UserEmail != "" {
+			classesByEmail[record.UserEmail] = append(classesByEmail[record.UserEmail], classByRegistration[record.RegistrationID])
+		}
+	}
+
+	result := make(map[delegatedWrite]string)
+	for email, studentClassIDs := range classesByEmail {
+		grantors, err := delegationGrantors(tx, email, studentClassIDs, now)
+		if err != nil {
+			return nil, err
+		}
+		for studentClassID, grantor := range grantors {
+			result[delegatedWrite{email: email, studentClassID: studentClassID}] = grantor
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	NotificationChannelEmail    = "EMAIL"
+	NotificationChannelSMS      = "SMS"
+	NotificationChannelWhatsApp = "WHATSAPP"
+)
+
+const (
+	DeliveryStatusPending   = "PENDING"
+	DeliveryStatusSent      = "SENT"
+	DeliveryStatusFailed    = "FAILED"
+	DeliveryStatusCancelled = "CANCELLED"
+)
+
+type Guardian struct {
+	ID           uint    `gorm:"primaryKey;autoIncrement"`
+	SchoolID     uint    `gorm:"not null;index:idx_guardian_school_id"`
+	StudentID    uint    `gorm:"not null;index:idx_guardian_student_id"`
+	Student      Student `gorm:"foreignKey:StudentID"`
+	Name         string  `gorm:"size:255;not null"`
+	Email        string  `gorm:"size:255"`
+	Phone        string  `gorm:"size:50"`
+	Channels     string  `gorm:"size:100"`
+	NotifyAbsent bool    `gorm:"not null"`
+	NotifyLate   bool    `gorm:"not null"`
+	DailyDigest  bool    `gorm:"not null"`
+}
+
+func (Guardian) TableName() string {
+	return "Guardian"
+}
+
+// ChannelList returns the guardian's preferred notification channels.
+func (g Guardian) ChannelList() []string {
+	var channels []string
+	for _, channel := range strings.Split(g.Channels, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+func (g Guardian) wantsStatus(status string) bool {
+	return (status == "ABSENT" && g.NotifyAbsent) || (status == "LATE" && g.NotifyLate)
+}
+
+// GuardianNotification is both the queue and the delivery log of attendance
+// notifications sent to guardians.
+type GuardianNotification struct {
+	ID               uint         `gorm:"primaryKey;autoIncrement"`
+	SchoolID         uint         `gorm:"not null;index:idx_notification_school_id"`
+	GuardianID       uint         `gorm:"not null;uniqueIndex:unique_guardian_notification"`
+	Guardian         Guardian     `gorm:"foreignKey:GuardianID"`
+	Channel          string       `gorm:"size:20;not null;uniqueIndex:unique_guardian_notification"`
+	RegistrationID   uint         `gorm:"not null;uniqueIndex:unique_guardian_notification"`
+	Registration     Registration `gorm:"foreignKey:RegistrationID"`
+	Date             string       `gorm:"type:date;not null;uniqueIndex:unique_guardian_notification"`
+	AttendanceStatus string       `gorm:"size:20;not null;uniqueIndex:unique_guardian_notification"`
+	DeliveryStatus   string       `gorm:"size:20;not null;index:idx_notification_delivery"`
+	Attempts         int          `gorm:"not null;default:0"`
+	LastError        string       `gorm:"type:text"`
+	NextAttemptAt    time.Time    `gorm:"index:idx_notification_delivery"`
+	SentAt           *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+}
+
+func (GuardianNotification) TableName() string {
+	return "GuardianNotification"
+}
+
+func ListGuardians(ctx context.Context, studentID uint) []Guardian {
+	var guardians []Guardian
+	conn(ctx).Where("student_id = ?", studentID).Order("name ASC").Find(&guardians)
+	return guardians
+}
+
+func GetGuardian(ctx context.Context, guardianID uint) (Guardian, error) {
+	var guardian Guardian
+	err := conn(ctx).First(&guardian, guardianID).Error
+	return guardian, err
+}
+
+func CreateGuardian(ctx context.Context, guardian *Guardian) error {
+	return conn(ctx).Create(guardian).Error
+}
+
+func UpdateGuardian(ctx context.Context, guardian *Guardian) error {
+	return conn(ctx).Save(guardian).Error
+}
+
+func StudentExists(ctx context.Context, studentID uint) bool {
+	var count int64
+	conn(ctx).Model(&Student{}).Where("id = ?", studentID).Count(&count)
+	return count > 0
+}
+
+// enqueueGuardianNotifications queues the notifications for records inside the
+// caller's transaction and cancels the ones they supersede.
+func enqueueGuardianNotifications(tx *gorm.DB, records []BulkAttendanceRecord) error {
+	if err := cancelSupersededNotifications(tx, records); err != nil {
+		return err
+	}
+
+	var registrationIDs []uint
+	for _, record := range records {
+		if record.Status == "ABSENT" || record.Status == "LATE" {
+			registrationIDs = append(registrationIDs, record.RegistrationID)
+		}
+	}
+	if len(registrationIDs) == 0 {
+		return nil
+	}
+
+	var registrations []Registration
+	if err := tx.Select("id", "student_id").Where("id IN ?", registrationIDs).Find(&registrations).Error; err != nil {
+		return err
+	}
+
+	var studentIDs []uint
+	studentByRegistration := make(map[uint]uint)
+	for _, registration := range registrations {
+		studentIDs = append(studentIDs, registration.StudentID)
+		studentByRegistration[registration.ID] = registration.StudentID
+	}
+
+	var guardians []Guardian
+	if err := tx.Where("student_id IN ?", studentIDs).Find(&guardians).Error; err != nil {
+		return err
+	}
+	if len(guardians) == 0 {
+		return nil
+	}
+
+	guardiansByStudent := make(map[uint][]Guardian)
+	for _, guardian := range guardians {
+		guardiansByStudent[guardian.StudentID] = append(guardiansByStudent[guardian.StudentID], guardian)
+	}
+
+	now := time.Now()
+	var notifications []GuardianNotification
+	for _, record := range records {
+		for _, guardian := range guardiansByStudent[studentByRegistration[record.RegistrationID]] {
+			if !guardian.wantsStatus(record.Status) {
+				continue
+			}
+			for _, channel := range guardian.ChannelList() {
+				notifications = append(notifications, GuardianNotification{
+					GuardianID:       guardian.ID,
+					Channel:          channel,
+					RegistrationID:   record.RegistrationID,
+					Date:             normalizeDate(record.Date),
+					AttendanceStatus: record.Status,
+					DeliveryStatus:   DeliveryStatusPending,
+					NextAttemptAt:    now,
+				})
+			}
+		}
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guardian_id"}, {Name: "channel"}, {Name: "registration_id"}, {Name: "date"}, {Name: "attendance_status"}},
+		DoNothing: true,
+	}).Create(&notifications).Error
+	if err != nil {
+		return err
+	}
+
+	// A status that was cancelled and is recorded again is queued again.
+	queued := make(map[string]bool)
+	var queuedRegistrationIDs []uint
+	var queuedDates []string
+	for _, notification := range notifications {
+		queued[notificationKey(notification)] = true
+		queuedRegistrationIDs = append(queuedRegistrationIDs, notification.RegistrationID)
+		queuedDates = append(queuedDates, notification.Date)
+	}
+	var cancelled []GuardianNotification
+	err = tx.Where("registration_id IN ?", queuedRegistrationIDs).
+		Where("date IN ?", queuedDates).
+		Where("delivery_status = ?", DeliveryStatusCancelled).
+		Find(&cancelled).Error
+	if err != nil {
+		return err
+	}
+	var requeued []uint
+	for _, notification := range cancelled {
+		if queued[notificationKey(notification)] {
+			requeued = append(requeued, notification.ID)
+		}
+	}
+	if len(requeued) == 0 {
+		return nil
+	}
+	return tx.Model(&GuardianNotification{}).
+		Where("id IN ?", requeued).
+		Updates(map[string]interface{}{
+			"delivery_status": DeliveryStatusPending,
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": now,
+		}).Error
+}
+
+// cancelSupersededNotifications cancels the pending notifications for the
+// registration and date of each record that report another status.
+func cancelSupersededNotifications(tx *gorm.DB, records []BulkAttendanceRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var registrationIDs []uint
+	var dates []string
+	statuses := make(map[string]string)
+	for _, record := range records {
+		registrationIDs = append(registrationIDs, record.RegistrationID)
+		dates = append(dates, normalizeDate(record.Date))
+		statuses[attendanceKey(record.RegistrationID, record.Date)] = record.Status
+	}
+
+	var pending []GuardianNotification
+	err := tx.Select("id", "registration_id", "date", "attendance_status").
+		Where("registration_id IN ?", registrationIDs).
+		Where("date IN ?", dates).
+		Where("delivery_status = ?", DeliveryStatusPending).
+		Find(&pending).Error
+	if err != nil {
+		return err
+	}
+
+	var cancelled []uint
+	for _, notification := range pending {
+		status, exists := statuses[attendanceKey(notification.RegistrationID, notification.Date)]
+		if exists && status != notification.AttendanceStatus {
+			cancelled = append(cancelled, notification.ID)
+		}
+	}
+	return cancelGuardianNotifications(tx, cancelled)
+}
+
+func notificationKey(notification GuardianNotification) string {
+	return fmt.Sprintf("%d/%s/%s/%s", notification.GuardianID, notification.Channel, attendanceKey(notification.RegistrationID, notification.Date), notification.AttendanceStatus)
+}
+
+// CancelGuardianNotifications takes pending notifications out of the queue
+// without sending them.
+func CancelGuardianNotifications(ctx context.Context, notificationIDs []uint) error {
+	return cancelGuardianNotifications(conn(ctx), notificationIDs)
+}
+
+func cancelGuardianNotifications(tx *gorm.DB, notificationIDs []uint) error {
+	if len(notificationIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&GuardianNotification{}).
+		Where("id IN ?", notificationIDs).
+		Where("delivery_status = ?", DeliveryStatusPending).
+		Update("delivery_status", DeliveryStatusCancelled).Error
+}
+
+// ListDueGuardianNotifications returns the pending notifications whose next
+// attempt is due at now, ordered by guardian, channel and date.
+func ListDueGuardianNotifications(ctx context.Context, now time.Time) []GuardianNotification {
+	var notifications []GuardianNotification
+	conn(ctx).Preload("Guardian").
+		Preload("Registration.Student").
+		Where("delivery_status = ?", DeliveryStatusPending).
+		Where("next_attempt_at <= ?", now).
+		Order("guardian_id ASC, channel ASC, date ASC, id ASC").
+		Find(&notifications)
+	for i := range notifications {
+		notifications[i].Date = normalizeDate(notifications[i].Date)
+	}
+	return notifications
+}
+
+func MarkGuardianNotificationsSent(ctx context.Context, notificationIDs []uint, sentAt time.Time) error {
+	return conn(ctx).Model(&GuardianNotification{}).
+		Where("id IN ?", notificationIDs).
+		Updates(map[string]interface{}{
+			"delivery_status": DeliveryStatusSent,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      "",
+			"sent_at":         sentAt,
+		}).Error
+}
+
+// MarkGuardianNotificationsFailed records a failed delivery attempt.
+func MarkGuardianNotificationsFailed(ctx context.Context, notificationIDs []uint, cause error, nextAttemptAt time.Time, maxAttempts int) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&GuardianNotification{}).
+			Where("id IN ?", notificationIDs).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"last_error":      cause.Error(),
+				"next_attempt_at": nextAttemptAt,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&GuardianNotification{}).
+			Where("id IN ?", notificationIDs).
+			Where("attempts >= ?", maxAttempts).
+			Update("delivery_status", DeliveryStatusFailed).Error
+	})
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKey remembers the response to a write sent with an
+// Idempotency-Key header. StatusCode stays 0 while it is in flight.
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID     uint      `gorm:"not null;uniqueIndex:unique_idempotency_key"`
+	Key          string    `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:unique_idempotency_key"`
+	UserEmail    string    `gorm:"size:255;not null;uniqueIndex:unique_idempotency_key"`
+	Method       string    `gorm:"size:10;not null"`
+	Path         string    `gorm:"size:1000;not null"`
+	Fingerprint  string    `gorm:"size:64;not null"`
+	StatusCode   int       `gorm:"not null"`
+	ContentType  string    `gorm:"size:255"`
+	ResponseBody string    `gorm:"size:16777215"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	ExpiresAt    time.Time `gorm:"not null;index:idx_idempotency_expires_at"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "IdempotencyKey"
+}
+
+// ReserveIdempotencyKey stores entry and returns nil, or returns the stored
+// entry when the key is taken.
+func ReserveIdempotencyKey(ctx context.Context, entry *IdempotencyKey, inFlightLease time.Duration) (*IdempotencyKey, error) {
+	var existing *IdempotencyKey
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Where("idempotency_key = ? AND user_email = ?", entry.Key, entry.UserEmail).
+			Where("expires_at <= ? OR (status_code = 0 AND created_at <= ?)", now, now.Add(-inFlightLease)).
+			Delete(&IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+		if created.Error != nil {
+			return created.Error
+		}
+		if created.RowsAffected > 0 {
+			return nil
+		}
+
+		var stored IdempotencyKey
+		err = tx.Where("idempotency_key = ? AND user_email = ?", entry.Key, entry.UserEmail).First(&stored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("idempotency key was released concurrently")
+		}
+		if err != nil {
+			return err
+		}
+		existing = &stored
+		return nil
+	})
+	return existing, err
+}
+
+// CompleteIdempotencyKey saves the response of the request that reserved the
+// key.
+func CompleteIdempotencyKey(ctx context.Context, id uint, statusCode int, contentType string, body string) error {
+	return conn(ctx).Model(&IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+	}).Error
+}
+
+// ReleaseIdempotencyKey forgets a reserved key, so that a retry runs the
+// request again.
+func ReleaseIdempotencyKey(ctx context.Context, id uint) error {
+	return conn(ctx).Delete(&IdempotencyKey{}, id).Error
+}
+
+func DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result := conn(ctx).Where("expires_at <= ?", now).Delete(&IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
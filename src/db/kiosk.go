@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"skulla-api/live"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// KioskDevice is a card reader or gate kiosk. It authenticates with a key
+// that is only shown once, when the device is registered.
+type KioskDevice struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID   uint      `gorm:"not null;index:idx_kiosk_device_school_id"`
+	Name       string    `gorm:"size:255;not null"`
+	KeyHash    string    `gorm:"size:64;not null;uniqueIndex:unique_kiosk_key_hash" json:"-"`
+	CreatedBy  string    `gorm:"size:500"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	LastSeenAt *time.Time
+}
+
+func (KioskDevice) TableName() string {
+	return "KioskDevice"
+}
+
+// KioskSwipe is one card presented at a kiosk. Swipes are unique per device,
+// card and time, so re-uploading an offline buffer is harmless.
+type KioskSwipe struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID      uint      `gorm:"not null;index:idx_swipe_school_id"`
+	KioskDeviceID uint      `gorm:"not null;uniqueIndex:unique_kiosk_swipe"`
+	CardID        string    `gorm:"size:100;not null;uniqueIndex:unique_kiosk_swipe"`
+	SwipedAt      time.Time `gorm:"not null;uniqueIndex:unique_kiosk_swipe;index:idx_swipe_swiped_at"`
+	StudentID     *uint
+	ReceivedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+func (KioskSwipe) TableName() string {
+	return "KioskSwipe"
+}
+
+// KioskAttendance is an attendance created from a swipe.
+type KioskAttendance struct {
+	RegistrationID uint   `json:"registration_id"`
+	StudentClassID uint   `json:"student_class_id"`
+	Status         string `json:"status"`
+}
+
+type SwipeResult struct {
+	CardID      string            `json:"card_id"`
+	SwipedAt    time.Time         `json:"swiped_at"`
+	StudentID   *uint             `json:"student_id"`
+	UnknownCard bool              `json:"unknown_card"`
+	Duplicate   bool              `json:"duplicate"`
+	Attendances []KioskAttendance `json:"attendances"`
+}
+
+func hashKioskKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKioskDevice registers device with a freshly generated key and returns
+// the key. Only its hash is stored.
+func CreateKioskDevice(ctx context.Context, device *KioskDevice) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(raw)
+
+	device.KeyHash = hashKioskKey(key)
+	if err := conn(ctx).Create(device).Error; err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func ListKioskDevices(ctx context.Context) []KioskDevice {
+	var devices []KioskDevice
+	conn(ctx).Order("name ASC, id ASC").Find(&devices)
+	return devices
+}
+
+func GetKioskDeviceByKey(ctx context.Context, key string) (KioskDevice, error) {
+	var device KioskDevice
+	err := conn(ctx).Where("key_hash = ?", hashKioskKey(key)).First(&device).Error
+	return device, err
+}
+
+// scheduledRegistration is an active registration whose class meets on the
+// swipe's weekday, with the span of that day's sessions.
+type scheduledRegistration struct {
+	RegistrationID uint
+	StudentClassID uint
+	FirstStart     string
+	LastEnd        string
+}
+
+func scheduledRegistrations(tx *gorm.DB, studentID uint, arrival time.Time) ([]scheduledRegistration, error) {
+	var registrations []scheduledRegistration
+	err := tx.Table("Registration").
+		Joins(`JOIN "StudentClass" ON "StudentClass".id = "Registration".student_class_id`).
+		Joins(`JOIN "Period" ON "Period".id = "StudentClass".period_id`).
+		Joins(`JOIN "ClassSchedule" ON "ClassSchedule".student_class_id = "Registration".student_class_id`).
+		Select(`"Registration".id AS registration_id, "Registration".student_class_id AS student_class_id, MIN("ClassSchedule".start_time) AS first_start, MAX("ClassSchedule".end_time) AS last_end`).
+		Where(`"Registration".student_id = ?`, studentID).
+		Where(`"Registration".status = ?`, ActiveRegistrationStatus).
+		Where(`"Period"."start" <= ?`, arrival).
+		Where(`"Period"."end" >= ?`, arrival).
+		Where(`"ClassSchedule".weekday = ?`, isoWeekday(arrival)).
+		Group(`"Registration".id, "Registration".student_class_id`).
+		Order(`"Registration".id ASC`).
+		Scan(&registrations).Error
+	return registrations, err
+}
+
+// arrivalStatus is PRESENT before the first session and LATE before the last
+// session ends.
+func arrivalStatus(arrival time.Time, firstStart string, lastEnd string) string {
+	clock := arrival.Format("15:04")
+	switch {
+	case clock <= firstStart:
+		return "PRESENT"
+	case clock < lastEnd:
+		return "LATE"
+	default:
+		return ""
+	}
+}
+
+// RecordKioskSwipes stores swipes from device and records each student's first
+// arrival of the day as attendance.
+func RecordKioskSwipes(ctx context.Context, device KioskDevice, swipes []KioskSwipe) ([]SwipeResult, error) {
+	sort.SliceStable(swipes, func(i, j int) bool {
+		return swipes[i].SwipedAt.Before(swipes[j].SwipedAt)
+	})
+
+	var results []SwipeResult
+	var events []live.Event
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		results = make([]SwipeResult, 0, len(swipes))
+		students := make(map[string]*Student)
+		var records []BulkAttendanceRecord
+		queued := make(map[string]bool)
+
+		for _, swipe := range swipes {
+			arrival := swipe.SwipedAt.In(time.Local)
+			result := SwipeResult{CardID: swipe.CardID, SwipedAt: swipe.SwipedAt, Attendances: []KioskAttendance{}}
+
+			student, cached := students[swipe.CardID]
+			if !cached {
+				var found Student
+				err := tx.Where("card_id = ?", swipe.CardID).First(&found).Error
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				if err == nil {
+					student = &found
+				}
+				students[swipe.CardID] = student
+			}
+
+			swipe.KioskDeviceID = device.ID
+			if student != nil {
+				swipe.StudentID = &student.ID
+				result.StudentID = &student.ID
+			}
+			created := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "kiosk_device_id"}, {Name: "card_id"}, {Name: "swiped_at"}},
+				DoNothing: true,
+			}).Create(&swipe)
+			if created.Error != nil {
+				return created.Error
+			}
+
+			switch {
+			case created.RowsAffected == 0:
+				result.Duplicate = true
+			case student == nil:
+				result.UnknownCard = true
+			default:
+				registrations, err := scheduledRegistrations(tx, student.ID, arrival)
+				if err != nil {
+					return err
+				}
+
+				date := arrival.Format(dateLayout)
+				var candidates []BulkAttendanceRecord
+				for _, registration := range registrations {
+					status := arrivalStatus(arrival, registration.FirstStart, registration.LastEnd)
+					key := attendanceKey(registration.RegistrationID, date)
+					if status == "" || queued[key] {
+						continue
+					}
+					queued[key] = true
+					candidates = append(candidates, BulkAttendanceRecord{
+						RegistrationID: registration.RegistrationID,
+						Date:           date,
+						Status:         status,
+						Remarks:        fmt.Sprintf("Kiosk arrival at %s", arrival.Format("15:04")),
+						UserEmail:      fmt.Sprintf("kiosk:%s", device.Name),
+					})
+					result.Attendances = append(result.Attendances, KioskAttendance{
+						RegistrationID: registration.RegistrationID,
+						StudentClassID: registration.StudentClassID,
+						Status:         status,
+					})
+				}
+
+				if len(candidates) > 0 {
+					existing, err := existingAttendances(tx, candidates)
+					if err != nil {
+						return err
+					}
+					classByRegistration, err := registrationClassIDs(tx, candidates)
+					if err != nil {
+						return err
+					}
+					registers, err := loadRegisters(tx, candidates, classByRegistration)
+					if err != nil {
+						return err
+					}
+					kept := []KioskAttendance{}
+					for i, candidate := range candidates {
+						if _, exists := existing[attendanceKey(candidate.RegistrationID, candidate.Date)]; exists {
+							continue
+						}
+						if registers[registerKey(classByRegistration[candidate.RegistrationID], candidate.Date)].Status == RegisterLocked {
+							continue
+						}
+						records = append(records, candidate)
+						kept = append(kept, result.Attendances[i])
+					}
+					result.Attendances = kept
+				}
+			}
+
+			results = append(results, result)
+		}
+
+		now := time.Now()
+		if err := tx.Model(&KioskDevice{}).Where("id = ?", device.ID).Update("last_seen_at", now).Error; err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return nil
+		}
+		var err error
+		events, err = recordAttendance(tx, records)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishAttendanceEvents(events)
+	return results, nil
+}
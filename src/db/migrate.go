@@ -0,0 +1,222 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Models lists every model of the schema, in the order their tables can be
+// created.
+func Models() []interface{} {
+	return []interface{}{
+		&School{}, &Course{}, &Period{}, &StudentClass{}, &Student{}, &Registration{},
+		&Attendance{}, &DailyClassAttendanceSummary{}, &Alert{}, &Guardian{}, &GuardianNotification{},
+		&WebhookSubscription{}, &OutboxEvent{}, &WebhookDelivery{},
+		&CheckInWindow{}, &CheckIn{}, &ClassSchedule{}, &KioskDevice{}, &KioskSwipe{},
+		&AttendanceChange{}, &SyncMutation{}, &IdempotencyKey{}, &AttendanceRegister{},
+		&Delegation{}, &DelegationStudentClass{},
+	}
+}
+
+// SchemaMigration records a migration applied to the database.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "SchemaMigration"
+}
+
+// Migration is one versioned schema change, read from
+// migrations/<dialect>/<version>_<name>.up.sql and its .down.sql.
+type Migration struct {
+	Version   int
+	Name      string
+	Up        string
+	Down      string
+	AppliedAt *time.Time
+}
+
+// loadMigrations reads the migrations of dialect, ordered by version.
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %s", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		base, direction, found := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		version, name, _ := strings.Cut(base, "_")
+		number, err := strconv.Atoi(version)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[number]
+		if !exists {
+			migration = &Migration{Version: number, Name: name}
+			byVersion[number] = migration
+		}
+		switch direction {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		default:
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into statements ending with ";" at
+// the end of a line, since not every driver executes several at once.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		statements = append(statements, strings.TrimSpace(current.String()))
+	}
+	return statements
+}
+
+// migrationStatus returns every migration of the database's dialect with
+// the time it was applied, if it was.
+func migrationStatus(database *gorm.DB) ([]Migration, error) {
+	if err := database.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(database.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []SchemaMigration
+	if err := database.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int]time.Time)
+	for _, migration := range applied {
+		appliedAt[migration.Version] = migration.AppliedAt
+	}
+
+	for i := range migrations {
+		if at, exists := appliedAt[migrations[i].Version]; exists {
+			migrations[i].AppliedAt = &at
+		}
+	}
+	return migrations, nil
+}
+
+// migrateUp applies every pending migration in order and returns the ones it
+// applied.
+func migrateUp(database *gorm.DB) ([]Migration, error) {
+	migrations, err := migrationStatus(database)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		if migration.AppliedAt != nil {
+			continue
+		}
+		err := database.Transaction(func(tx *gorm.DB) error {
+			for _, statement := range splitStatements(migration.Up) {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// migrateDown reverts the last steps applied migrations, newest first, and
+// returns the ones it reverted.
+func migrateDown(database *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := migrationStatus(database)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := migrations[i]
+		if migration.AppliedAt == nil {
+			continue
+		}
+		err := database.Transaction(func(tx *gorm.DB) error {
+			for _, statement := range splitStatements(migration.Down) {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+func MigrationStatus() ([]Migration, error) {
+	return migrationStatus(db)
+}
+
+func MigrateUp() ([]Migration, error) {
+	return migrateUp(db)
+}
+
+func MigrateDown(steps int) ([]Migration, error) {
+	return migrateDown(db, steps)
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// describeSchema renders the tables, columns and indexes of database in a
+// form that can be compared across databases of the same dialect.
+func describeSchema(t *testing.T, database *gorm.DB) []string {
+	migrator := database.Migrator()
+	tables, err := migrator.GetTables()
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+
+	var schema []string
+	for _, table := range tables {
+		if table == "SchemaMigration" || table == "sqlite_sequence" {
+			continue
+		}
+
+		columns, err := migrator.ColumnTypes(table)
+		if err != nil {
+			t.Fatalf("Failed to read columns of %s: %v", table, err)
+		}
+		for _, column := range columns {
+			nullable, _ := column.Nullable()
+			length, _ := column.Length()
+			schema = append(schema, fmt.Sprintf("%s.%s %s(%d) nullable=%t", table, column.Name(), strings.ToLower(column.DatabaseTypeName()), length, nullable))
+		}
+
+		indexes, err := migrator.GetIndexes(table)
+		if err != nil {
+			t.Fatalf("Failed to read indexes of %s: %v", table, err)
+		}
+		for _, index := range indexes {
+			unique, _ := index.Unique()
+			schema = append(schema, fmt.Sprintf("%s index %s (%s) unique=%t", table, index.Name(), strings.Join(index.Columns(), ", "), unique))
+		}
+	}
+	sort.Strings(schema)
+	return schema
+}
+
+// assertSameSchema fails when migrating one database gives a different schema
+// than auto-migrating the models into another.
+func assertSameSchema(t *testing.T, migrated *gorm.DB, expected *gorm.DB) {
+	if _, err := migrateUp(migrated); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+	if err := expected.AutoMigrate(Models()...); err != nil {
+		t.Fatalf("Failed to auto-migrate models: %v", err)
+	}
+
+	got := strings.Join(describeSchema(t, migrated), "\n")
+	want := strings.Join(describeSchema(t, expected), "\n")
+	if got != want {
+		t.Errorf("Migrated schema differs from the models.\nMigrations:\n%s\n\nModels:\n%s", got, want)
+	}
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	return database
+}
+
+func TestMigrations_SQLiteMatchModels(t *testing.T) {
+	assertSameSchema(t, openSQLite(t), openSQLite(t))
+}
+
+// TestMigrations_MySQLMatchModels needs a server whose user can create
+// databases, e.g. TEST_MYSQL_DSN="root:root@tcp(localhost:3306)/".
+func TestMigrations_MySQLMatchModels(t *testing.T) {
+	dsn, ok := os.LookupEnv("TEST_MYSQL_DSN")
+	if !ok {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	open := func(name string) *gorm.DB {
+		server, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatalf("Failed to connect to MySQL: %v", err)
+		}
+		server.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name))
+		if err := server.Exec(fmt.Sprintf("CREATE DATABASE `%s`", name)).Error; err != nil {
+			t.Fatalf("Failed to create database %s: %v", name, err)
+		}
+		database, err := gorm.Open(mysql.Open(dsn+name+"?parseTime=True"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatalf("Failed to connect to %s: %v", name, err)
+		}
+		return database
+	}
+
+	assertSameSchema(t, open("migrations_check_migrated"), open("migrations_check_models"))
+}
+
+// TestMigrations_PostgresMatchModels compares two schemas of the database of
+// TEST_POSTGRES_DSN. Each connection pool is limited to one connection so
+// its search_path applies to every statement.
+func TestMigrations_PostgresMatchModels(t *testing.T) {
+	dsn, ok := os.LookupEnv("TEST_POSTGRES_DSN")
+	if !ok {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	open := func(schema string) *gorm.DB {
+		database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		}
+		sqlDB, err := database.DB()
+		if err != nil {
+			t.Fatalf("Failed to get connection pool: %v", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+		t.Cleanup(func() { sqlDB.Close() })
+
+		for _, statement := range []string{
+			fmt.Sprintf(`DROP SCHEMA IF EXISTS "%s" CASCADE`, schema),
+			fmt.Sprintf(`CREATE SCHEMA "%s"`, schema),
+			fmt.Sprintf(`SET search_path TO "%s"`, schema),
+		} {
+			if err := database.Exec(statement).Error; err != nil {
+				t.Fatalf("Failed to prepare schema %s: %v", schema, err)
+			}
+		}
+		return database
+	}
+
+	assertSameSchema(t, open("migrations_check_migrated"), open("migrations_check_models"))
+}
+
+func TestMigrations_UpDownStatus(t *testing.T) {
+	database := openSQLite(t)
+
+	applied, err := migrateUp(database)
+	if err != nil || len(applied) == 0 {
+		t.Fatalf("Expected migrations to be applied, got %d (%v)", len(applied), err)
+	}
+	if applied, err := migrateUp(database); err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing left to apply, got %d (%v)", len(applied), err)
+	}
+
+	var school School
+	if err := database.First(&school, 1).Error; err != nil || school.Subdomain != "default" {
+		t.Errorf("Expected the default school, got %+v (%v)", school, err)
+	}
+
+	migrations, _ := migrationStatus(database)
+	for _, migration := range migrations {
+		if migration.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be applied", migration.Version)
+		}
+	}
+
+	reverted, err := migrateDown(database, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != migrations[len(migrations)-1].Version {
+		t.Fatalf("Expected the last migration to be reverted, got %+v (%v)", reverted, err)
+	}
+
+	migrations, _ = migrationStatus(database)
+	if migrations[len(migrations)-1].AppliedAt != nil {
+		t.Errorf("Expected the reverted migration to be pending")
+	}
+}
+
+func TestMigrations_UpgradeAndRevertExistingDatabase(t *testing.T) {
+	database := openSQLite(t)
+	migrations, err := loadMigrations("sqlite")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	// Production has the baseline tables, with data, from before migrations.
+	for _, statement := range splitStatements(migrations[0].Up) {
+		if err := database.Exec(statement).Error; err != nil {
+			t.Fatalf("Failed to create baseline tables: %v", err)
+		}
+	}
+	for _, statement := range []string{
+		"INSERT INTO `Course` (`id`, `name`) VALUES (1, 'Course')",
+		"INSERT INTO `Student` (`id`, `firstName`) VALUES (1, 'Ana')",
+		"INSERT INTO `Registration` (`id`, `status`, `student_id`, `student_class_id`) VALUES (1, 'ACTIVE', 1, 1)",
+		"INSERT INTO `Attendance` (`registration_id`, `date`, `status`) VALUES (1, '2024-01-15', 'PRESENT')",
+	} {
+		if err := database.Exec(statement).Error; err != nil {
+			t.Fatalf("Failed to seed baseline data: %v", err)
+		}
+	}
+
+	if applied, err := migrateUp(database); err != nil || len(applied) != len(migrations) {
+		t.Fatalf("Expected every migration to be applied, got %d (%v)", len(applied), err)
+	}
+	var attendance Attendance
+	if err := database.First(&attendance).Error; err != nil || attendance.SchoolID != 1 || attendance.Version != 1 {
+		t.Errorf("Expected existing attendance in the default school at version 1, got %+v (%v)", attendance, err)
+	}
+	var changes int64
+	database.Model(&AttendanceChange{}).Count(&changes)
+	if changes != 1 {
+		t.Errorf("Expected existing attendance in the change log, got %d changes", changes)
+	}
+
+	if reverted, err := migrateDown(database, len(migrations)); err != nil || len(reverted) != len(migrations) {
+		t.Fatalf("Expected every migration to be reverted, got %d (%v)", len(reverted), err)
+	}
+	var remaining int64
+	if err := database.Table("Attendance").Count(&remaining).Error; err != nil || remaining != 1 {
+		t.Errorf("Expected the baseline tables to keep their data, got %d rows (%v)", remaining, err)
+	}
+	if database.Migrator().HasColumn(&Attendance{}, "school_id") || database.Migrator().HasTable(&School{}) {
+		t.Error("Expected the columns and tables of later migrations to be removed")
+	}
+
+	if applied, err := migrateUp(database); err != nil || len(applied) != len(migrations) {
+		t.Errorf("Expected the migrations to apply again, got %d (%v)", len(applied), err)
+	}
+}
@@ -0,0 +1,3 @@
+-- The baseline tables predate versioned migrations and hold data this
+-- migration never created, so reverting it drops nothing.
+
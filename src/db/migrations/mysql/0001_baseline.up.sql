@@ -0,0 +1,60 @@
+-- Schema of the tables that predate versioned migrations. Databases that
+-- already have them, like production, keep them as they are.
+
+CREATE TABLE IF NOT EXISTS `Course` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `name` varchar(255) NOT NULL,
+                              `teacher_email` varchar(255),
+                              PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `Period` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `start` datetime,
+                              `end` datetime,
+                              PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `StudentClass` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `name` varchar(255) NOT NULL,
+                              `course_id` bigint,
+                              `period_id` bigint,
+                              PRIMARY KEY (`id`),
+                              CONSTRAINT `fk_StudentClass_course` FOREIGN KEY (`course_id`) REFERENCES `Course` (`id`),
+                              CONSTRAINT `fk_StudentClass_period` FOREIGN KEY (`period_id`) REFERENCES `Period` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `Student` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `firstName` varchar(255),
+                              `lastName` varchar(255),
+                              PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `Registration` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `status` varchar(255) NOT NULL,
+                              `student_id` bigint,
+                              `student_class_id` bigint,
+                              PRIMARY KEY (`id`),
+                              CONSTRAINT `fk_Registration_student` FOREIGN KEY (`student_id`) REFERENCES `Student` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `Attendance` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `registration_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `status` varchar(20) NOT NULL,
+                              `remarks` text,
+                              `created_by` varchar(500),
+                              `updated_by` varchar(500),
+                              `created_at` datetime(3) NULL,
+                              `updated_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_registration_id` (`registration_id`),
+                              UNIQUE KEY `unique_registration_date` (`registration_id`, `date`),
+                              KEY `idx_date` (`date`),
+                              KEY `idx_status` (`status`),
+                              CONSTRAINT `fk_Attendance_registration` FOREIGN KEY (`registration_id`) REFERENCES `Registration` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
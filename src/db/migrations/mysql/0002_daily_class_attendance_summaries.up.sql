@@ -0,0 +1,16 @@
+-- Pre-aggregated daily attendance counts per student class.
+
+CREATE TABLE `DailyClassAttendanceSummary` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `student_class_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `present_count` bigint NOT NULL DEFAULT 0,
+                              `absent_count` bigint NOT NULL DEFAULT 0,
+                              `late_count` bigint NOT NULL DEFAULT 0,
+                              `excused_count` bigint NOT NULL DEFAULT 0,
+                              `updated_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_summary_student_class_id` (`student_class_id`),
+                              UNIQUE KEY `unique_student_class_date` (`student_class_id`, `date`),
+                              KEY `idx_summary_date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
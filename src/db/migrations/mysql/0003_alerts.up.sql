@@ -0,0 +1,18 @@
+-- At-risk attendance alerts raised by the alert evaluation job.
+
+CREATE TABLE `Alert` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `registration_id` bigint NOT NULL,
+                              `student_class_id` bigint NOT NULL,
+                              `rule` varchar(50) NOT NULL,
+                              `severity` varchar(20) NOT NULL,
+                              `message` text,
+                              `triggered_on` date NOT NULL,
+                              `acknowledged_by` varchar(500),
+                              `acknowledged_at` datetime(3) NULL,
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_alert_registration_rule` (`registration_id`, `rule`),
+                              KEY `idx_alert_student_class_id` (`student_class_id`),
+                              CONSTRAINT `fk_Alert_registration` FOREIGN KEY (`registration_id`) REFERENCES `Registration` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,37 @@
+-- Student guardians and the queue of notifications sent to them.
+
+CREATE TABLE `Guardian` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `student_id` bigint NOT NULL,
+                              `name` varchar(255) NOT NULL,
+                              `email` varchar(255),
+                              `phone` varchar(50),
+                              `channels` varchar(100),
+                              `notify_absent` boolean NOT NULL,
+                              `notify_late` boolean NOT NULL,
+                              `daily_digest` boolean NOT NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_guardian_student_id` (`student_id`),
+                              CONSTRAINT `fk_Guardian_student` FOREIGN KEY (`student_id`) REFERENCES `Student` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `GuardianNotification` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `guardian_id` bigint NOT NULL,
+                              `channel` varchar(20) NOT NULL,
+                              `registration_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `attendance_status` varchar(20) NOT NULL,
+                              `delivery_status` varchar(20) NOT NULL,
+                              `attempts` bigint NOT NULL DEFAULT 0,
+                              `last_error` text,
+                              `next_attempt_at` datetime(3) NULL,
+                              `sent_at` datetime(3) NULL,
+                              `created_at` datetime(3) NULL,
+                              `updated_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_guardian_notification` (`guardian_id`, `channel`, `registration_id`, `date`, `attendance_status`),
+                              KEY `idx_notification_delivery` (`delivery_status`, `next_attempt_at`),
+                              CONSTRAINT `fk_GuardianNotification_guardian` FOREIGN KEY (`guardian_id`) REFERENCES `Guardian` (`id`),
+                              CONSTRAINT `fk_GuardianNotification_registration` FOREIGN KEY (`registration_id`) REFERENCES `Registration` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
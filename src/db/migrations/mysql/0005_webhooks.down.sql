@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `WebhookDelivery`;
+DROP TABLE IF EXISTS `OutboxEvent`;
+DROP TABLE IF EXISTS `WebhookSubscription`;
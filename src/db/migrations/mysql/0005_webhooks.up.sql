@@ -0,0 +1,39 @@
+-- Outgoing webhook subscriptions, transactional outbox and delivery log.
+
+CREATE TABLE `WebhookSubscription` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `url` varchar(1000) NOT NULL,
+                              `secret` varchar(255) NOT NULL,
+                              `events` varchar(500) NOT NULL,
+                              `created_by` varchar(500),
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `OutboxEvent` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `event_type` varchar(100) NOT NULL,
+                              `payload` text NOT NULL,
+                              `fanned_out_at` datetime(3) NULL,
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_outbox_fanned_out_at` (`fanned_out_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `WebhookDelivery` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `subscription_id` bigint NOT NULL,
+                              `outbox_event_id` bigint NOT NULL,
+                              `status` varchar(20) NOT NULL,
+                              `attempts` bigint NOT NULL,
+                              `last_error` text,
+                              `next_attempt_at` datetime(3) NULL,
+                              `delivered_at` datetime(3) NULL,
+                              `created_at` datetime(3) NULL,
+                              `updated_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_delivery_subscription_id` (`subscription_id`),
+                              KEY `idx_delivery_status` (`status`, `next_attempt_at`),
+                              CONSTRAINT `fk_WebhookDelivery_subscription` FOREIGN KEY (`subscription_id`) REFERENCES `WebhookSubscription` (`id`),
+                              CONSTRAINT `fk_WebhookDelivery_outbox_event` FOREIGN KEY (`outbox_event_id`) REFERENCES `OutboxEvent` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,29 @@
+-- Student accounts and QR code self check-in windows.
+
+ALTER TABLE `Student` ADD COLUMN `email` varchar(255), ADD KEY `idx_student_email` (`email`);
+
+CREATE TABLE `CheckInWindow` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `student_class_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `secret` varchar(64) NOT NULL,
+                              `opens_at` datetime(3) NOT NULL,
+                              `late_after` datetime(3) NOT NULL,
+                              `closes_at` datetime(3) NOT NULL,
+                              `created_by` varchar(500),
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_check_in_window_student_class_id` (`student_class_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `CheckIn` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `check_in_window_id` bigint NOT NULL,
+                              `registration_id` bigint NOT NULL,
+                              `device_id` varchar(255) NOT NULL,
+                              `status` varchar(20) NOT NULL,
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_check_in_registration` (`check_in_window_id`, `registration_id`),
+                              UNIQUE KEY `unique_check_in_device` (`check_in_window_id`, `device_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `KioskSwipe`;
+DROP TABLE IF EXISTS `KioskDevice`;
+DROP TABLE IF EXISTS `ClassSchedule`;
+ALTER TABLE `Student` DROP COLUMN `card_id`;
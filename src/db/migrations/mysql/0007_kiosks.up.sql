@@ -0,0 +1,36 @@
+-- Student cards, class timetables, kiosk devices and their swipes.
+
+ALTER TABLE `Student` ADD COLUMN `card_id` varchar(100), ADD KEY `idx_student_card_id` (`card_id`);
+
+CREATE TABLE `ClassSchedule` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `student_class_id` bigint NOT NULL,
+                              `weekday` bigint NOT NULL,
+                              `start_time` varchar(5) NOT NULL,
+                              `end_time` varchar(5) NOT NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_schedule_student_class_id` (`student_class_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `KioskDevice` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `name` varchar(255) NOT NULL,
+                              `key_hash` varchar(64) NOT NULL,
+                              `created_by` varchar(500),
+                              `created_at` datetime(3) NULL,
+                              `last_seen_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_kiosk_key_hash` (`key_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `KioskSwipe` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `kiosk_device_id` bigint NOT NULL,
+                              `card_id` varchar(100) NOT NULL,
+                              `swiped_at` datetime(3) NOT NULL,
+                              `student_id` bigint,
+                              `received_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_kiosk_swipe` (`kiosk_device_id`, `card_id`, `swiped_at`),
+                              KEY `idx_swipe_swiped_at` (`swiped_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
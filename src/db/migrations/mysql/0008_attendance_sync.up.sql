@@ -0,0 +1,28 @@
+-- Attendance versions and the change log read by offline sync clients.
+
+ALTER TABLE `Attendance` ADD COLUMN `version` bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE `AttendanceChange` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `registration_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_change_registration_id` (`registration_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT INTO `AttendanceChange` (`registration_id`, `date`, `created_at`)
+SELECT `registration_id`, `date`, `updated_at` FROM `Attendance` ORDER BY `updated_at`, `id`;
+
+CREATE TABLE `SyncMutation` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `client_id` varchar(100) NOT NULL,
+                              `registration_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `outcome` varchar(20) NOT NULL,
+                              `version` bigint NOT NULL,
+                              `created_by` varchar(500),
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_sync_mutation_client_id` (`client_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,18 @@
+-- Idempotency keys for retried attendance writes.
+
+CREATE TABLE `IdempotencyKey` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `idempotency_key` varchar(255) NOT NULL,
+                              `user_email` varchar(255) NOT NULL,
+                              `method` varchar(10) NOT NULL,
+                              `path` varchar(1000) NOT NULL,
+                              `fingerprint` varchar(64) NOT NULL,
+                              `status_code` bigint NOT NULL,
+                              `content_type` varchar(255),
+                              `response_body` mediumtext,
+                              `created_at` datetime(3) NULL,
+                              `expires_at` datetime(3) NOT NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_idempotency_expires_at` (`expires_at`),
+                              UNIQUE KEY `unique_idempotency_key` (`idempotency_key`, `user_email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
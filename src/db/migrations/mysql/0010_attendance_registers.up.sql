@@ -0,0 +1,18 @@
+-- Draft, submitted and locked attendance registers. Attendance without a
+-- register counts as submitted, so existing days need none.
+
+CREATE TABLE `AttendanceRegister` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `student_class_id` bigint NOT NULL,
+                              `date` date NOT NULL,
+                              `status` varchar(20) NOT NULL,
+                              `created_by` varchar(500),
+                              `submitted_by` varchar(500),
+                              `submitted_at` datetime(3) NULL,
+                              `locked_by` varchar(500),
+                              `locked_at` datetime(3) NULL,
+                              `created_at` datetime(3) NULL,
+                              `updated_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_register_class_date` (`student_class_id`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
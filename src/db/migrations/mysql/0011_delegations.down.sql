@@ -0,0 +1,3 @@
+ALTER TABLE `AttendanceChange` DROP COLUMN `changed_by`, DROP COLUMN `on_behalf_of`;
+DROP TABLE IF EXISTS `DelegationStudentClass`;
+DROP TABLE IF EXISTS `Delegation`;
@@ -0,0 +1,23 @@
+-- Substitute teacher delegations, and who recorded each attendance change.
+
+CREATE TABLE `Delegation` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `grantor_email` varchar(500) NOT NULL,
+                              `grantee_email` varchar(500) NOT NULL,
+                              `valid_from` datetime(3) NOT NULL,
+                              `valid_to` datetime(3) NOT NULL,
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              KEY `idx_delegation_grantor_email` (`grantor_email`),
+                              KEY `idx_delegation_grantee_email` (`grantee_email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `DelegationStudentClass` (
+                              `delegation_id` bigint,
+                              `student_class_id` bigint,
+                              PRIMARY KEY (`delegation_id`, `student_class_id`),
+                              KEY `idx_delegation_student_class_id` (`student_class_id`),
+                              CONSTRAINT `fk_Delegation_student_classes` FOREIGN KEY (`delegation_id`) REFERENCES `Delegation` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+ALTER TABLE `AttendanceChange` ADD COLUMN `changed_by` varchar(500), ADD COLUMN `on_behalf_of` varchar(500);
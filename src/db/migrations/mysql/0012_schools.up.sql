@@ -0,0 +1,85 @@
+-- Schools. Every existing row belongs to the default school, and new rows
+-- must name theirs.
+
+CREATE TABLE `School` (
+                              `id` bigint NOT NULL AUTO_INCREMENT,
+                              `name` varchar(255) NOT NULL,
+                              `subdomain` varchar(100) NOT NULL,
+                              `created_at` datetime(3) NULL,
+                              PRIMARY KEY (`id`),
+                              UNIQUE KEY `unique_school_subdomain` (`subdomain`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT INTO `School` (`id`, `name`, `subdomain`) VALUES (1, 'Default school', 'default');
+
+ALTER TABLE `Course` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_course_school_id` (`school_id`);
+ALTER TABLE `Course` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Period` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_period_school_id` (`school_id`);
+ALTER TABLE `Period` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `StudentClass` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_student_class_school_id` (`school_id`);
+ALTER TABLE `StudentClass` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Student` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_student_school_id` (`school_id`);
+ALTER TABLE `Student` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Registration` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_registration_school_id` (`school_id`);
+ALTER TABLE `Registration` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Attendance` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_attendance_school_id` (`school_id`);
+ALTER TABLE `Attendance` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `DailyClassAttendanceSummary` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_summary_school_id` (`school_id`);
+ALTER TABLE `DailyClassAttendanceSummary` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Alert` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_alert_school_id` (`school_id`);
+ALTER TABLE `Alert` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Guardian` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_guardian_school_id` (`school_id`);
+ALTER TABLE `Guardian` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `GuardianNotification` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_notification_school_id` (`school_id`);
+ALTER TABLE `GuardianNotification` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `WebhookSubscription` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_subscription_school_id` (`school_id`);
+ALTER TABLE `WebhookSubscription` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `OutboxEvent` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_outbox_school_id` (`school_id`);
+ALTER TABLE `OutboxEvent` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `WebhookDelivery` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_delivery_school_id` (`school_id`);
+ALTER TABLE `WebhookDelivery` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `CheckInWindow` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_check_in_window_school_id` (`school_id`);
+ALTER TABLE `CheckInWindow` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `CheckIn` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_check_in_school_id` (`school_id`);
+ALTER TABLE `CheckIn` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `ClassSchedule` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_schedule_school_id` (`school_id`);
+ALTER TABLE `ClassSchedule` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `KioskDevice` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_kiosk_device_school_id` (`school_id`);
+ALTER TABLE `KioskDevice` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `KioskSwipe` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_swipe_school_id` (`school_id`);
+ALTER TABLE `KioskSwipe` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `AttendanceChange` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_change_school_id` (`school_id`);
+ALTER TABLE `AttendanceChange` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `SyncMutation` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, DROP INDEX `unique_sync_mutation_client_id`, ADD UNIQUE KEY `unique_sync_mutation_client_id` (`school_id`, `client_id`);
+ALTER TABLE `SyncMutation` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `IdempotencyKey` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, DROP INDEX `unique_idempotency_key`, ADD UNIQUE KEY `unique_idempotency_key` (`school_id`, `idempotency_key`, `user_email`);
+ALTER TABLE `IdempotencyKey` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `AttendanceRegister` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_register_school_id` (`school_id`);
+ALTER TABLE `AttendanceRegister` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `Delegation` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_delegation_school_id` (`school_id`);
+ALTER TABLE `Delegation` ALTER COLUMN `school_id` DROP DEFAULT;
+
+ALTER TABLE `DelegationStudentClass` ADD COLUMN `school_id` bigint NOT NULL DEFAULT 1, ADD KEY `idx_delegation_class_school_id` (`school_id`);
+ALTER TABLE `DelegationStudentClass` ALTER COLUMN `school_id` DROP DEFAULT;
@@ -0,0 +1 @@
+ALTER TABLE `SyncMutation` DROP INDEX `unique_sync_mutation_client_id`, ADD UNIQUE KEY `unique_sync_mutation_client_id` (`school_id`, `client_id`);
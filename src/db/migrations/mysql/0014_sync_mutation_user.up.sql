@@ -0,0 +1,3 @@
+-- Sync client IDs are unique per user rather than per school.
+
+ALTER TABLE `SyncMutation` DROP INDEX `unique_sync_mutation_client_id`, ADD UNIQUE KEY `unique_sync_mutation_client_id` (`school_id`, `client_id`, `created_by`);
@@ -0,0 +1,60 @@
+-- Schema of the tables that predate versioned migrations. Databases that
+-- already have them, like production, keep them as they are.
+
+CREATE TABLE IF NOT EXISTS "Course" (
+                              "id" bigserial,
+                              "name" varchar(255) NOT NULL,
+                              "teacher_email" varchar(255),
+                              PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "Period" (
+                              "id" bigserial,
+                              "start" timestamptz,
+                              "end" timestamptz,
+                              PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "StudentClass" (
+                              "id" bigserial,
+                              "name" varchar(255) NOT NULL,
+                              "course_id" bigint,
+                              "period_id" bigint,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_StudentClass_course" FOREIGN KEY ("course_id") REFERENCES "Course" ("id"),
+                              CONSTRAINT "fk_StudentClass_period" FOREIGN KEY ("period_id") REFERENCES "Period" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "Student" (
+                              "id" bigserial,
+                              "firstName" varchar(255),
+                              "lastName" varchar(255),
+                              PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "Registration" (
+                              "id" bigserial,
+                              "status" varchar(255) NOT NULL,
+                              "student_id" bigint,
+                              "student_class_id" bigint,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_Registration_student" FOREIGN KEY ("student_id") REFERENCES "Student" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "Attendance" (
+                              "id" bigserial,
+                              "registration_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "status" varchar(20) NOT NULL,
+                              "remarks" text,
+                              "created_by" varchar(500),
+                              "updated_by" varchar(500),
+                              "created_at" timestamptz,
+                              "updated_at" timestamptz,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_Attendance_registration" FOREIGN KEY ("registration_id") REFERENCES "Registration" ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_status" ON "Attendance" ("status");
+CREATE INDEX IF NOT EXISTS "idx_date" ON "Attendance" ("date");
+CREATE UNIQUE INDEX IF NOT EXISTS "unique_registration_date" ON "Attendance" ("registration_id", "date");
+CREATE INDEX IF NOT EXISTS "idx_registration_id" ON "Attendance" ("registration_id");
@@ -0,0 +1,16 @@
+-- Pre-aggregated daily attendance counts per student class.
+
+CREATE TABLE "DailyClassAttendanceSummary" (
+                              "id" bigserial,
+                              "student_class_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "present_count" bigint NOT NULL DEFAULT 0,
+                              "absent_count" bigint NOT NULL DEFAULT 0,
+                              "late_count" bigint NOT NULL DEFAULT 0,
+                              "excused_count" bigint NOT NULL DEFAULT 0,
+                              "updated_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_summary_date" ON "DailyClassAttendanceSummary" ("date");
+CREATE UNIQUE INDEX "unique_student_class_date" ON "DailyClassAttendanceSummary" ("student_class_id", "date");
+CREATE INDEX "idx_summary_student_class_id" ON "DailyClassAttendanceSummary" ("student_class_id");
@@ -0,0 +1,18 @@
+-- At-risk attendance alerts raised by the alert evaluation job.
+
+CREATE TABLE "Alert" (
+                              "id" bigserial,
+                              "registration_id" bigint NOT NULL,
+                              "student_class_id" bigint NOT NULL,
+                              "rule" varchar(50) NOT NULL,
+                              "severity" varchar(20) NOT NULL,
+                              "message" text,
+                              "triggered_on" date NOT NULL,
+                              "acknowledged_by" varchar(500),
+                              "acknowledged_at" timestamptz,
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_Alert_registration" FOREIGN KEY ("registration_id") REFERENCES "Registration" ("id")
+);
+CREATE INDEX "idx_alert_student_class_id" ON "Alert" ("student_class_id");
+CREATE INDEX "idx_alert_registration_rule" ON "Alert" ("registration_id", "rule");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "GuardianNotification";
+DROP TABLE IF EXISTS "Guardian";
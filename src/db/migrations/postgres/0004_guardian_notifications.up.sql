@@ -0,0 +1,37 @@
+-- Student guardians and the queue of notifications sent to them.
+
+CREATE TABLE "Guardian" (
+                              "id" bigserial,
+                              "student_id" bigint NOT NULL,
+                              "name" varchar(255) NOT NULL,
+                              "email" varchar(255),
+                              "phone" varchar(50),
+                              "channels" varchar(100),
+                              "notify_absent" boolean NOT NULL,
+                              "notify_late" boolean NOT NULL,
+                              "daily_digest" boolean NOT NULL,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_Guardian_student" FOREIGN KEY ("student_id") REFERENCES "Student" ("id")
+);
+CREATE INDEX "idx_guardian_student_id" ON "Guardian" ("student_id");
+
+CREATE TABLE "GuardianNotification" (
+                              "id" bigserial,
+                              "guardian_id" bigint NOT NULL,
+                              "channel" varchar(20) NOT NULL,
+                              "registration_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "attendance_status" varchar(20) NOT NULL,
+                              "delivery_status" varchar(20) NOT NULL,
+                              "attempts" bigint NOT NULL DEFAULT 0,
+                              "last_error" text,
+                              "next_attempt_at" timestamptz,
+                              "sent_at" timestamptz,
+                              "created_at" timestamptz,
+                              "updated_at" timestamptz,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_GuardianNotification_guardian" FOREIGN KEY ("guardian_id") REFERENCES "Guardian" ("id"),
+                              CONSTRAINT "fk_GuardianNotification_registration" FOREIGN KEY ("registration_id") REFERENCES "Registration" ("id")
+);
+CREATE INDEX "idx_notification_delivery" ON "GuardianNotification" ("delivery_status", "next_attempt_at");
+CREATE UNIQUE INDEX "unique_guardian_notification" ON "GuardianNotification" ("guardian_id", "channel", "registration_id", "date", "attendance_status");
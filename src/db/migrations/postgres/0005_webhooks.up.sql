@@ -0,0 +1,39 @@
+-- Outgoing webhook subscriptions, transactional outbox and delivery log.
+
+CREATE TABLE "WebhookSubscription" (
+                              "id" bigserial,
+                              "url" varchar(1000) NOT NULL,
+                              "secret" varchar(255) NOT NULL,
+                              "events" varchar(500) NOT NULL,
+                              "created_by" varchar(500),
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+
+CREATE TABLE "OutboxEvent" (
+                              "id" bigserial,
+                              "event_type" varchar(100) NOT NULL,
+                              "payload" text NOT NULL,
+                              "fanned_out_at" timestamptz,
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_outbox_fanned_out_at" ON "OutboxEvent" ("fanned_out_at");
+
+CREATE TABLE "WebhookDelivery" (
+                              "id" bigserial,
+                              "subscription_id" bigint NOT NULL,
+                              "outbox_event_id" bigint NOT NULL,
+                              "status" varchar(20) NOT NULL,
+                              "attempts" bigint NOT NULL,
+                              "last_error" text,
+                              "next_attempt_at" timestamptz,
+                              "delivered_at" timestamptz,
+                              "created_at" timestamptz,
+                              "updated_at" timestamptz,
+                              PRIMARY KEY ("id"),
+                              CONSTRAINT "fk_WebhookDelivery_subscription" FOREIGN KEY ("subscription_id") REFERENCES "WebhookSubscription" ("id"),
+                              CONSTRAINT "fk_WebhookDelivery_outbox_event" FOREIGN KEY ("outbox_event_id") REFERENCES "OutboxEvent" ("id")
+);
+CREATE INDEX "idx_delivery_status" ON "WebhookDelivery" ("status", "next_attempt_at");
+CREATE INDEX "idx_delivery_subscription_id" ON "WebhookDelivery" ("subscription_id");
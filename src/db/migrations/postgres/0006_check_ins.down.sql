@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "CheckIn";
+DROP TABLE IF EXISTS "CheckInWindow";
+ALTER TABLE "Student" DROP COLUMN "email";
@@ -0,0 +1,30 @@
+-- Student accounts and QR code self check-in windows.
+
+ALTER TABLE "Student" ADD COLUMN "email" varchar(255);
+CREATE INDEX "idx_student_email" ON "Student" ("email");
+
+CREATE TABLE "CheckInWindow" (
+                              "id" bigserial,
+                              "student_class_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "secret" varchar(64) NOT NULL,
+                              "opens_at" timestamptz NOT NULL,
+                              "late_after" timestamptz NOT NULL,
+                              "closes_at" timestamptz NOT NULL,
+                              "created_by" varchar(500),
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_check_in_window_student_class_id" ON "CheckInWindow" ("student_class_id");
+
+CREATE TABLE "CheckIn" (
+                              "id" bigserial,
+                              "check_in_window_id" bigint NOT NULL,
+                              "registration_id" bigint NOT NULL,
+                              "device_id" varchar(255) NOT NULL,
+                              "status" varchar(20) NOT NULL,
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_check_in_device" ON "CheckIn" ("check_in_window_id", "device_id");
+CREATE UNIQUE INDEX "unique_check_in_registration" ON "CheckIn" ("check_in_window_id", "registration_id");
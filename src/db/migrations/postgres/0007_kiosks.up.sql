@@ -0,0 +1,37 @@
+-- Student cards, class timetables, kiosk devices and their swipes.
+
+ALTER TABLE "Student" ADD COLUMN "card_id" varchar(100);
+CREATE INDEX "idx_student_card_id" ON "Student" ("card_id");
+
+CREATE TABLE "ClassSchedule" (
+                              "id" bigserial,
+                              "student_class_id" bigint NOT NULL,
+                              "weekday" bigint NOT NULL,
+                              "start_time" varchar(5) NOT NULL,
+                              "end_time" varchar(5) NOT NULL,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_schedule_student_class_id" ON "ClassSchedule" ("student_class_id");
+
+CREATE TABLE "KioskDevice" (
+                              "id" bigserial,
+                              "name" varchar(255) NOT NULL,
+                              "key_hash" varchar(64) NOT NULL,
+                              "created_by" varchar(500),
+                              "created_at" timestamptz,
+                              "last_seen_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_kiosk_key_hash" ON "KioskDevice" ("key_hash");
+
+CREATE TABLE "KioskSwipe" (
+                              "id" bigserial,
+                              "kiosk_device_id" bigint NOT NULL,
+                              "card_id" varchar(100) NOT NULL,
+                              "swiped_at" timestamptz NOT NULL,
+                              "student_id" bigint,
+                              "received_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_swipe_swiped_at" ON "KioskSwipe" ("swiped_at");
+CREATE UNIQUE INDEX "unique_kiosk_swipe" ON "KioskSwipe" ("kiosk_device_id", "card_id", "swiped_at");
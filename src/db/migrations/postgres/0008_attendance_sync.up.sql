@@ -0,0 +1,28 @@
+-- Attendance versions and the change log read by offline sync clients.
+
+ALTER TABLE "Attendance" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE "AttendanceChange" (
+                              "id" bigserial,
+                              "registration_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_change_registration_id" ON "AttendanceChange" ("registration_id");
+
+INSERT INTO "AttendanceChange" ("registration_id", "date", "created_at")
+SELECT "registration_id", "date", "updated_at" FROM "Attendance" ORDER BY "updated_at", "id";
+
+CREATE TABLE "SyncMutation" (
+                              "id" bigserial,
+                              "client_id" varchar(100) NOT NULL,
+                              "registration_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "outcome" varchar(20) NOT NULL,
+                              "version" bigint NOT NULL,
+                              "created_by" varchar(500),
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_sync_mutation_client_id" ON "SyncMutation" ("client_id");
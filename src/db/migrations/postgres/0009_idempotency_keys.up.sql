@@ -0,0 +1,18 @@
+-- Idempotency keys for retried attendance writes.
+
+CREATE TABLE "IdempotencyKey" (
+                              "id" bigserial,
+                              "idempotency_key" varchar(255) NOT NULL,
+                              "user_email" varchar(255) NOT NULL,
+                              "method" varchar(10) NOT NULL,
+                              "path" varchar(1000) NOT NULL,
+                              "fingerprint" varchar(64) NOT NULL,
+                              "status_code" bigint NOT NULL,
+                              "content_type" varchar(255),
+                              "response_body" text,
+                              "created_at" timestamptz,
+                              "expires_at" timestamptz NOT NULL,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_idempotency_expires_at" ON "IdempotencyKey" ("expires_at");
+CREATE UNIQUE INDEX "unique_idempotency_key" ON "IdempotencyKey" ("idempotency_key", "user_email");
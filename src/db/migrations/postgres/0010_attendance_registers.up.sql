@@ -0,0 +1,18 @@
+-- Draft, submitted and locked attendance registers. Attendance without a
+-- register counts as submitted, so existing days need none.
+
+CREATE TABLE "AttendanceRegister" (
+                              "id" bigserial,
+                              "student_class_id" bigint NOT NULL,
+                              "date" date NOT NULL,
+                              "status" varchar(20) NOT NULL,
+                              "created_by" varchar(500),
+                              "submitted_by" varchar(500),
+                              "submitted_at" timestamptz,
+                              "locked_by" varchar(500),
+                              "locked_at" timestamptz,
+                              "created_at" timestamptz,
+                              "updated_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_register_class_date" ON "AttendanceRegister" ("student_class_id", "date");
@@ -0,0 +1,4 @@
+ALTER TABLE "AttendanceChange" DROP COLUMN "changed_by";
+ALTER TABLE "AttendanceChange" DROP COLUMN "on_behalf_of";
+DROP TABLE IF EXISTS "DelegationStudentClass";
+DROP TABLE IF EXISTS "Delegation";
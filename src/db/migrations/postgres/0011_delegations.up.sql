@@ -0,0 +1,24 @@
+-- Substitute teacher delegations, and who recorded each attendance change.
+
+CREATE TABLE "Delegation" (
+                              "id" bigserial,
+                              "grantor_email" varchar(500) NOT NULL,
+                              "grantee_email" varchar(500) NOT NULL,
+                              "valid_from" timestamptz NOT NULL,
+                              "valid_to" timestamptz NOT NULL,
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_delegation_grantee_email" ON "Delegation" ("grantee_email");
+CREATE INDEX "idx_delegation_grantor_email" ON "Delegation" ("grantor_email");
+
+CREATE TABLE "DelegationStudentClass" (
+                              "delegation_id" bigint,
+                              "student_class_id" bigint,
+                              PRIMARY KEY ("delegation_id", "student_class_id"),
+                              CONSTRAINT "fk_Delegation_student_classes" FOREIGN KEY ("delegation_id") REFERENCES "Delegation" ("id")
+);
+CREATE INDEX "idx_delegation_student_class_id" ON "DelegationStudentClass" ("student_class_id");
+
+ALTER TABLE "AttendanceChange" ADD COLUMN "changed_by" varchar(500);
+ALTER TABLE "AttendanceChange" ADD COLUMN "on_behalf_of" varchar(500);
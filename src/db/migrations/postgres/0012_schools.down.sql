@@ -0,0 +1,29 @@
+ALTER TABLE "Course" DROP COLUMN "school_id";
+ALTER TABLE "Period" DROP COLUMN "school_id";
+ALTER TABLE "StudentClass" DROP COLUMN "school_id";
+ALTER TABLE "Student" DROP COLUMN "school_id";
+ALTER TABLE "Registration" DROP COLUMN "school_id";
+ALTER TABLE "Attendance" DROP COLUMN "school_id";
+ALTER TABLE "DailyClassAttendanceSummary" DROP COLUMN "school_id";
+ALTER TABLE "Alert" DROP COLUMN "school_id";
+ALTER TABLE "Guardian" DROP COLUMN "school_id";
+ALTER TABLE "GuardianNotification" DROP COLUMN "school_id";
+ALTER TABLE "WebhookSubscription" DROP COLUMN "school_id";
+ALTER TABLE "OutboxEvent" DROP COLUMN "school_id";
+ALTER TABLE "WebhookDelivery" DROP COLUMN "school_id";
+ALTER TABLE "CheckInWindow" DROP COLUMN "school_id";
+ALTER TABLE "CheckIn" DROP COLUMN "school_id";
+ALTER TABLE "ClassSchedule" DROP COLUMN "school_id";
+ALTER TABLE "KioskDevice" DROP COLUMN "school_id";
+ALTER TABLE "KioskSwipe" DROP COLUMN "school_id";
+ALTER TABLE "AttendanceChange" DROP COLUMN "school_id";
+DROP INDEX "unique_sync_mutation_client_id";
+CREATE UNIQUE INDEX "unique_sync_mutation_client_id" ON "SyncMutation" ("client_id");
+ALTER TABLE "SyncMutation" DROP COLUMN "school_id";
+DROP INDEX "unique_idempotency_key";
+CREATE UNIQUE INDEX "unique_idempotency_key" ON "IdempotencyKey" ("idempotency_key", "user_email");
+ALTER TABLE "IdempotencyKey" DROP COLUMN "school_id";
+ALTER TABLE "AttendanceRegister" DROP COLUMN "school_id";
+ALTER TABLE "Delegation" DROP COLUMN "school_id";
+ALTER TABLE "DelegationStudentClass" DROP COLUMN "school_id";
+DROP TABLE IF EXISTS "School";
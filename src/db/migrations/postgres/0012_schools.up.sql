@@ -0,0 +1,112 @@
+-- Schools. Every existing row belongs to the default school, and new rows
+-- must name theirs.
+
+CREATE TABLE "School" (
+                              "id" bigserial,
+                              "name" varchar(255) NOT NULL,
+                              "subdomain" varchar(100) NOT NULL,
+                              "created_at" timestamptz,
+                              PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_school_subdomain" ON "School" ("subdomain");
+
+INSERT INTO "School" ("id", "name", "subdomain") VALUES (1, 'Default school', 'default');
+SELECT setval(pg_get_serial_sequence('"School"', 'id'), 1);
+
+ALTER TABLE "Course" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_course_school_id" ON "Course" ("school_id");
+ALTER TABLE "Course" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Period" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_period_school_id" ON "Period" ("school_id");
+ALTER TABLE "Period" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "StudentClass" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_student_class_school_id" ON "StudentClass" ("school_id");
+ALTER TABLE "StudentClass" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Student" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_student_school_id" ON "Student" ("school_id");
+ALTER TABLE "Student" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Registration" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_registration_school_id" ON "Registration" ("school_id");
+ALTER TABLE "Registration" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Attendance" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_attendance_school_id" ON "Attendance" ("school_id");
+ALTER TABLE "Attendance" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "DailyClassAttendanceSummary" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_summary_school_id" ON "DailyClassAttendanceSummary" ("school_id");
+ALTER TABLE "DailyClassAttendanceSummary" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Alert" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_alert_school_id" ON "Alert" ("school_id");
+ALTER TABLE "Alert" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Guardian" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_guardian_school_id" ON "Guardian" ("school_id");
+ALTER TABLE "Guardian" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "GuardianNotification" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_notification_school_id" ON "GuardianNotification" ("school_id");
+ALTER TABLE "GuardianNotification" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "WebhookSubscription" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_subscription_school_id" ON "WebhookSubscription" ("school_id");
+ALTER TABLE "WebhookSubscription" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "OutboxEvent" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_outbox_school_id" ON "OutboxEvent" ("school_id");
+ALTER TABLE "OutboxEvent" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "WebhookDelivery" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_delivery_school_id" ON "WebhookDelivery" ("school_id");
+ALTER TABLE "WebhookDelivery" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "CheckInWindow" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_check_in_window_school_id" ON "CheckInWindow" ("school_id");
+ALTER TABLE "CheckInWindow" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "CheckIn" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_check_in_school_id" ON "CheckIn" ("school_id");
+ALTER TABLE "CheckIn" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "ClassSchedule" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_schedule_school_id" ON "ClassSchedule" ("school_id");
+ALTER TABLE "ClassSchedule" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "KioskDevice" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_kiosk_device_school_id" ON "KioskDevice" ("school_id");
+ALTER TABLE "KioskDevice" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "KioskSwipe" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_swipe_school_id" ON "KioskSwipe" ("school_id");
+ALTER TABLE "KioskSwipe" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "AttendanceChange" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_change_school_id" ON "AttendanceChange" ("school_id");
+ALTER TABLE "AttendanceChange" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "SyncMutation" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+DROP INDEX "unique_sync_mutation_client_id";
+CREATE UNIQUE INDEX "unique_sync_mutation_client_id" ON "SyncMutation" ("school_id", "client_id");
+ALTER TABLE "SyncMutation" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "IdempotencyKey" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+DROP INDEX "unique_idempotency_key";
+CREATE UNIQUE INDEX "unique_idempotency_key" ON "IdempotencyKey" ("school_id", "idempotency_key", "user_email");
+ALTER TABLE "IdempotencyKey" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "AttendanceRegister" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_register_school_id" ON "AttendanceRegister" ("school_id");
+ALTER TABLE "AttendanceRegister" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "Delegation" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_delegation_school_id" ON "Delegation" ("school_id");
+ALTER TABLE "Delegation" ALTER COLUMN "school_id" DROP DEFAULT;
+
+ALTER TABLE "DelegationStudentClass" ADD COLUMN "school_id" bigint NOT NULL DEFAULT 1;
+CREATE INDEX "idx_delegation_class_school_id" ON "DelegationStudentClass" ("school_id");
+ALTER TABLE "DelegationStudentClass" ALTER COLUMN "school_id" DROP DEFAULT;
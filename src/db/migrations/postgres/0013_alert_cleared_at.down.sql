@@ -0,0 +1 @@
+ALTER TABLE "Alert" DROP COLUMN "cleared_at";
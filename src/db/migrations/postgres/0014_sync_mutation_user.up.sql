@@ -0,0 +1,4 @@
+-- Sync client IDs are unique per user rather than per school.
+
+DROP INDEX "unique_sync_mutation_client_id";
+CREATE UNIQUE INDEX "unique_sync_mutation_client_id" ON "SyncMutation" ("school_id", "client_id", "created_by");
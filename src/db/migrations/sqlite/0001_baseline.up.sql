@@ -0,0 +1,54 @@
+-- Schema of the tables that predate versioned migrations. Databases that
+-- already have them, like production, keep them as they are.
+
+CREATE TABLE IF NOT EXISTS `Course` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `name` text NOT NULL,
+                              `teacher_email` text
+);
+
+CREATE TABLE IF NOT EXISTS `Period` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `start` datetime,
+                              `end` datetime
+);
+
+CREATE TABLE IF NOT EXISTS `StudentClass` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `name` text NOT NULL,
+                              `course_id` integer,
+                              `period_id` integer,
+                              CONSTRAINT `fk_StudentClass_course` FOREIGN KEY (`course_id`) REFERENCES `Course` (`id`),
+                              CONSTRAINT `fk_StudentClass_period` FOREIGN KEY (`period_id`) REFERENCES `Period` (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `Student` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `firstName` text,
+                              `lastName` text
+);
+
+CREATE TABLE IF NOT EXISTS `Registration` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `status` text NOT NULL,
+                              `student_id` integer,
+                              `student_class_id` integer,
+                              CONSTRAINT `fk_Registration_student` FOREIGN KEY (`student_id`) REFERENCES `Student` (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `Attendance` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `registration_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `status` text NOT NULL,
+                              `remarks` text,
+                              `created_by` text,
+                              `updated_by` text,
+                              `created_at` datetime,
+                              `updated_at` datetime,
+                              CONSTRAINT `fk_Attendance_registration` FOREIGN KEY (`registration_id`) REFERENCES `Registration` (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_status` ON `Attendance` (`status`);
+CREATE INDEX IF NOT EXISTS `idx_date` ON `Attendance` (`date`);
+CREATE UNIQUE INDEX IF NOT EXISTS `unique_registration_date` ON `Attendance` (`registration_id`, `date`);
+CREATE INDEX IF NOT EXISTS `idx_registration_id` ON `Attendance` (`registration_id`);
@@ -0,0 +1,15 @@
+-- Pre-aggregated daily attendance counts per student class.
+
+CREATE TABLE `DailyClassAttendanceSummary` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `student_class_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `present_count` integer NOT NULL DEFAULT 0,
+                              `absent_count` integer NOT NULL DEFAULT 0,
+                              `late_count` integer NOT NULL DEFAULT 0,
+                              `excused_count` integer NOT NULL DEFAULT 0,
+                              `updated_at` datetime
+);
+CREATE INDEX `idx_summary_date` ON `DailyClassAttendanceSummary` (`date`);
+CREATE UNIQUE INDEX `unique_student_class_date` ON `DailyClassAttendanceSummary` (`student_class_id`, `date`);
+CREATE INDEX `idx_summary_student_class_id` ON `DailyClassAttendanceSummary` (`student_class_id`);
@@ -0,0 +1,17 @@
+-- At-risk attendance alerts raised by the alert evaluation job.
+
+CREATE TABLE `Alert` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `registration_id` integer NOT NULL,
+                              `student_class_id` integer NOT NULL,
+                              `rule` text NOT NULL,
+                              `severity` text NOT NULL,
+                              `message` text,
+                              `triggered_on` date NOT NULL,
+                              `acknowledged_by` text,
+                              `acknowledged_at` datetime,
+                              `created_at` datetime,
+                              CONSTRAINT `fk_Alert_registration` FOREIGN KEY (`registration_id`) REFERENCES `Registration` (`id`)
+);
+CREATE INDEX `idx_alert_student_class_id` ON `Alert` (`student_class_id`);
+CREATE INDEX `idx_alert_registration_rule` ON `Alert` (`registration_id`, `rule`);
@@ -0,0 +1,35 @@
+-- Student guardians and the queue of notifications sent to them.
+
+CREATE TABLE `Guardian` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `student_id` integer NOT NULL,
+                              `name` text NOT NULL,
+                              `email` text,
+                              `phone` text,
+                              `channels` text,
+                              `notify_absent` numeric NOT NULL,
+                              `notify_late` numeric NOT NULL,
+                              `daily_digest` numeric NOT NULL,
+                              CONSTRAINT `fk_Guardian_student` FOREIGN KEY (`student_id`) REFERENCES `Student` (`id`)
+);
+CREATE INDEX `idx_guardian_student_id` ON `Guardian` (`student_id`);
+
+CREATE TABLE `GuardianNotification` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `guardian_id` integer NOT NULL,
+                              `channel` text NOT NULL,
+                              `registration_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `attendance_status` text NOT NULL,
+                              `delivery_status` text NOT NULL,
+                              `attempts` integer NOT NULL DEFAULT 0,
+                              `last_error` text,
+                              `next_attempt_at` datetime,
+                              `sent_at` datetime,
+                              `created_at` datetime,
+                              `updated_at` datetime,
+                              CONSTRAINT `fk_GuardianNotification_guardian` FOREIGN KEY (`guardian_id`) REFERENCES `Guardian` (`id`),
+                              CONSTRAINT `fk_GuardianNotification_registration` FOREIGN KEY (`registration_id`) REFERENCES `Registration` (`id`)
+);
+CREATE INDEX `idx_notification_delivery` ON `GuardianNotification` (`delivery_status`, `next_attempt_at`);
+CREATE UNIQUE INDEX `unique_guardian_notification` ON `GuardianNotification` (`guardian_id`, `channel`, `registration_id`, `date`, `attendance_status`);
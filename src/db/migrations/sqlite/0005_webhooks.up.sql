@@ -0,0 +1,36 @@
+-- Outgoing webhook subscriptions, transactional outbox and delivery log.
+
+CREATE TABLE `WebhookSubscription` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `url` text NOT NULL,
+                              `secret` text NOT NULL,
+                              `events` text NOT NULL,
+                              `created_by` text,
+                              `created_at` datetime
+);
+
+CREATE TABLE `OutboxEvent` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `event_type` text NOT NULL,
+                              `payload` text NOT NULL,
+                              `fanned_out_at` datetime,
+                              `created_at` datetime
+);
+CREATE INDEX `idx_outbox_fanned_out_at` ON `OutboxEvent` (`fanned_out_at`);
+
+CREATE TABLE `WebhookDelivery` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `subscription_id` integer NOT NULL,
+                              `outbox_event_id` integer NOT NULL,
+                              `status` text NOT NULL,
+                              `attempts` integer NOT NULL,
+                              `last_error` text,
+                              `next_attempt_at` datetime,
+                              `delivered_at` datetime,
+                              `created_at` datetime,
+                              `updated_at` datetime,
+                              CONSTRAINT `fk_WebhookDelivery_subscription` FOREIGN KEY (`subscription_id`) REFERENCES `WebhookSubscription` (`id`),
+                              CONSTRAINT `fk_WebhookDelivery_outbox_event` FOREIGN KEY (`outbox_event_id`) REFERENCES `OutboxEvent` (`id`)
+);
+CREATE INDEX `idx_delivery_status` ON `WebhookDelivery` (`status`, `next_attempt_at`);
+CREATE INDEX `idx_delivery_subscription_id` ON `WebhookDelivery` (`subscription_id`);
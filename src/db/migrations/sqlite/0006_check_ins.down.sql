@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `CheckIn`;
+DROP TABLE IF EXISTS `CheckInWindow`;
+DROP INDEX `idx_student_email`;
+ALTER TABLE `Student` DROP COLUMN `email`;
@@ -0,0 +1,28 @@
+-- Student accounts and QR code self check-in windows.
+
+ALTER TABLE `Student` ADD COLUMN `email` text;
+CREATE INDEX `idx_student_email` ON `Student` (`email`);
+
+CREATE TABLE `CheckInWindow` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `student_class_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `secret` text NOT NULL,
+                              `opens_at` datetime NOT NULL,
+                              `late_after` datetime NOT NULL,
+                              `closes_at` datetime NOT NULL,
+                              `created_by` text,
+                              `created_at` datetime
+);
+CREATE INDEX `idx_check_in_window_student_class_id` ON `CheckInWindow` (`student_class_id`);
+
+CREATE TABLE `CheckIn` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `check_in_window_id` integer NOT NULL,
+                              `registration_id` integer NOT NULL,
+                              `device_id` text NOT NULL,
+                              `status` text NOT NULL,
+                              `created_at` datetime
+);
+CREATE UNIQUE INDEX `unique_check_in_device` ON `CheckIn` (`check_in_window_id`, `device_id`);
+CREATE UNIQUE INDEX `unique_check_in_registration` ON `CheckIn` (`check_in_window_id`, `registration_id`);
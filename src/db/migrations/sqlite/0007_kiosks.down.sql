@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `KioskSwipe`;
+DROP TABLE IF EXISTS `KioskDevice`;
+DROP TABLE IF EXISTS `ClassSchedule`;
+DROP INDEX `idx_student_card_id`;
+ALTER TABLE `Student` DROP COLUMN `card_id`;
@@ -0,0 +1,34 @@
+-- Student cards, class timetables, kiosk devices and their swipes.
+
+ALTER TABLE `Student` ADD COLUMN `card_id` text;
+CREATE INDEX `idx_student_card_id` ON `Student` (`card_id`);
+
+CREATE TABLE `ClassSchedule` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `student_class_id` integer NOT NULL,
+                              `weekday` integer NOT NULL,
+                              `start_time` text NOT NULL,
+                              `end_time` text NOT NULL
+);
+CREATE INDEX `idx_schedule_student_class_id` ON `ClassSchedule` (`student_class_id`);
+
+CREATE TABLE `KioskDevice` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `name` text NOT NULL,
+                              `key_hash` text NOT NULL,
+                              `created_by` text,
+                              `created_at` datetime,
+                              `last_seen_at` datetime
+);
+CREATE UNIQUE INDEX `unique_kiosk_key_hash` ON `KioskDevice` (`key_hash`);
+
+CREATE TABLE `KioskSwipe` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `kiosk_device_id` integer NOT NULL,
+                              `card_id` text NOT NULL,
+                              `swiped_at` datetime NOT NULL,
+                              `student_id` integer,
+                              `received_at` datetime
+);
+CREATE INDEX `idx_swipe_swiped_at` ON `KioskSwipe` (`swiped_at`);
+CREATE UNIQUE INDEX `unique_kiosk_swipe` ON `KioskSwipe` (`kiosk_device_id`, `card_id`, `swiped_at`);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `SyncMutation`;
+DROP TABLE IF EXISTS `AttendanceChange`;
+ALTER TABLE `Attendance` DROP COLUMN `version`;
@@ -0,0 +1,26 @@
+-- Attendance versions and the change log read by offline sync clients.
+
+ALTER TABLE `Attendance` ADD COLUMN `version` integer NOT NULL DEFAULT 1;
+
+CREATE TABLE `AttendanceChange` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `registration_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `created_at` datetime
+);
+CREATE INDEX `idx_change_registration_id` ON `AttendanceChange` (`registration_id`);
+
+INSERT INTO `AttendanceChange` (`registration_id`, `date`, `created_at`)
+SELECT `registration_id`, `date`, `updated_at` FROM `Attendance` ORDER BY `updated_at`, `id`;
+
+CREATE TABLE `SyncMutation` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `client_id` text NOT NULL,
+                              `registration_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `outcome` text NOT NULL,
+                              `version` integer NOT NULL,
+                              `created_by` text,
+                              `created_at` datetime
+);
+CREATE UNIQUE INDEX `unique_sync_mutation_client_id` ON `SyncMutation` (`client_id`);
@@ -0,0 +1,17 @@
+-- Idempotency keys for retried attendance writes.
+
+CREATE TABLE `IdempotencyKey` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `idempotency_key` text NOT NULL,
+                              `user_email` text NOT NULL,
+                              `method` text NOT NULL,
+                              `path` text NOT NULL,
+                              `fingerprint` text NOT NULL,
+                              `status_code` integer NOT NULL,
+                              `content_type` text,
+                              `response_body` text,
+                              `created_at` datetime,
+                              `expires_at` datetime NOT NULL
+);
+CREATE INDEX `idx_idempotency_expires_at` ON `IdempotencyKey` (`expires_at`);
+CREATE UNIQUE INDEX `unique_idempotency_key` ON `IdempotencyKey` (`idempotency_key`, `user_email`);
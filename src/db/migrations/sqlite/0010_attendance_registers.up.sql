@@ -0,0 +1,17 @@
+-- Draft, submitted and locked attendance registers. Attendance without a
+-- register counts as submitted, so existing days need none.
+
+CREATE TABLE `AttendanceRegister` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `student_class_id` integer NOT NULL,
+                              `date` date NOT NULL,
+                              `status` text NOT NULL,
+                              `created_by` text,
+                              `submitted_by` text,
+                              `submitted_at` datetime,
+                              `locked_by` text,
+                              `locked_at` datetime,
+                              `created_at` datetime,
+                              `updated_at` datetime
+);
+CREATE UNIQUE INDEX `unique_register_class_date` ON `AttendanceRegister` (`student_class_id`, `date`);
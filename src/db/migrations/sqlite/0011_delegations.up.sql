@@ -0,0 +1,23 @@
+-- Substitute teacher delegations, and who recorded each attendance change.
+
+CREATE TABLE `Delegation` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `grantor_email` text NOT NULL,
+                              `grantee_email` text NOT NULL,
+                              `valid_from` datetime NOT NULL,
+                              `valid_to` datetime NOT NULL,
+                              `created_at` datetime
+);
+CREATE INDEX `idx_delegation_grantee_email` ON `Delegation` (`grantee_email`);
+CREATE INDEX `idx_delegation_grantor_email` ON `Delegation` (`grantor_email`);
+
+CREATE TABLE `DelegationStudentClass` (
+                              `delegation_id` integer,
+                              `student_class_id` integer,
+                              PRIMARY KEY (`delegation_id`, `student_class_id`),
+                              CONSTRAINT `fk_Delegation_student_classes` FOREIGN KEY (`delegation_id`) REFERENCES `Delegation` (`id`)
+);
+CREATE INDEX `idx_delegation_student_class_id` ON `DelegationStudentClass` (`student_class_id`);
+
+ALTER TABLE `AttendanceChange` ADD COLUMN `changed_by` text;
+ALTER TABLE `AttendanceChange` ADD COLUMN `on_behalf_of` text;
@@ -0,0 +1,51 @@
+DROP INDEX `idx_course_school_id`;
+ALTER TABLE `Course` DROP COLUMN `school_id`;
+DROP INDEX `idx_period_school_id`;
+ALTER TABLE `Period` DROP COLUMN `school_id`;
+DROP INDEX `idx_student_class_school_id`;
+ALTER TABLE `StudentClass` DROP COLUMN `school_id`;
+DROP INDEX `idx_student_school_id`;
+ALTER TABLE `Student` DROP COLUMN `school_id`;
+DROP INDEX `idx_registration_school_id`;
+ALTER TABLE `Registration` DROP COLUMN `school_id`;
+DROP INDEX `idx_attendance_school_id`;
+ALTER TABLE `Attendance` DROP COLUMN `school_id`;
+DROP INDEX `idx_summary_school_id`;
+ALTER TABLE `DailyClassAttendanceSummary` DROP COLUMN `school_id`;
+DROP INDEX `idx_alert_school_id`;
+ALTER TABLE `Alert` DROP COLUMN `school_id`;
+DROP INDEX `idx_guardian_school_id`;
+ALTER TABLE `Guardian` DROP COLUMN `school_id`;
+DROP INDEX `idx_notification_school_id`;
+ALTER TABLE `GuardianNotification` DROP COLUMN `school_id`;
+DROP INDEX `idx_subscription_school_id`;
+ALTER TABLE `WebhookSubscription` DROP COLUMN `school_id`;
+DROP INDEX `idx_outbox_school_id`;
+ALTER TABLE `OutboxEvent` DROP COLUMN `school_id`;
+DROP INDEX `idx_delivery_school_id`;
+ALTER TABLE `WebhookDelivery` DROP COLUMN `school_id`;
+DROP INDEX `idx_check_in_window_school_id`;
+ALTER TABLE `CheckInWindow` DROP COLUMN `school_id`;
+DROP INDEX `idx_check_in_school_id`;
+ALTER TABLE `CheckIn` DROP COLUMN `school_id`;
+DROP INDEX `idx_schedule_school_id`;
+ALTER TABLE `ClassSchedule` DROP COLUMN `school_id`;
+DROP INDEX `idx_kiosk_device_school_id`;
+ALTER TABLE `KioskDevice` DROP COLUMN `school_id`;
+DROP INDEX `idx_swipe_school_id`;
+ALTER TABLE `KioskSwipe` DROP COLUMN `school_id`;
+DROP INDEX `idx_change_school_id`;
+ALTER TABLE `AttendanceChange` DROP COLUMN `school_id`;
+DROP INDEX `unique_sync_mutation_client_id`;
+CREATE UNIQUE INDEX `unique_sync_mutation_client_id` ON `SyncMutation` (`client_id`);
+ALTER TABLE `SyncMutation` DROP COLUMN `school_id`;
+DROP INDEX `unique_idempotency_key`;
+CREATE UNIQUE INDEX `unique_idempotency_key` ON `IdempotencyKey` (`idempotency_key`, `user_email`);
+ALTER TABLE `IdempotencyKey` DROP COLUMN `school_id`;
+DROP INDEX `idx_register_school_id`;
+ALTER TABLE `AttendanceRegister` DROP COLUMN `school_id`;
+DROP INDEX `idx_delegation_school_id`;
+ALTER TABLE `Delegation` DROP COLUMN `school_id`;
+DROP INDEX `idx_delegation_class_school_id`;
+ALTER TABLE `DelegationStudentClass` DROP COLUMN `school_id`;
+DROP TABLE IF EXISTS `School`;
@@ -0,0 +1,86 @@
+-- Schools. Every existing row belongs to the default school, and new rows
+-- must name theirs.
+
+CREATE TABLE `School` (
+                              `id` integer PRIMARY KEY AUTOINCREMENT,
+                              `name` text NOT NULL,
+                              `subdomain` text NOT NULL,
+                              `created_at` datetime
+);
+CREATE UNIQUE INDEX `unique_school_subdomain` ON `School` (`subdomain`);
+
+INSERT INTO `School` (`id`, `name`, `subdomain`) VALUES (1, 'Default school', 'default');
+
+ALTER TABLE `Course` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_course_school_id` ON `Course` (`school_id`);
+
+ALTER TABLE `Period` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_period_school_id` ON `Period` (`school_id`);
+
+ALTER TABLE `StudentClass` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_student_class_school_id` ON `StudentClass` (`school_id`);
+
+ALTER TABLE `Student` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_student_school_id` ON `Student` (`school_id`);
+
+ALTER TABLE `Registration` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_registration_school_id` ON `Registration` (`school_id`);
+
+ALTER TABLE `Attendance` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_attendance_school_id` ON `Attendance` (`school_id`);
+
+ALTER TABLE `DailyClassAttendanceSummary` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_summary_school_id` ON `DailyClassAttendanceSummary` (`school_id`);
+
+ALTER TABLE `Alert` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_alert_school_id` ON `Alert` (`school_id`);
+
+ALTER TABLE `Guardian` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_guardian_school_id` ON `Guardian` (`school_id`);
+
+ALTER TABLE `GuardianNotification` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_notification_school_id` ON `GuardianNotification` (`school_id`);
+
+ALTER TABLE `WebhookSubscription` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_subscription_school_id` ON `WebhookSubscription` (`school_id`);
+
+ALTER TABLE `OutboxEvent` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_outbox_school_id` ON `OutboxEvent` (`school_id`);
+
+ALTER TABLE `WebhookDelivery` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_delivery_school_id` ON `WebhookDelivery` (`school_id`);
+
+ALTER TABLE `CheckInWindow` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_check_in_window_school_id` ON `CheckInWindow` (`school_id`);
+
+ALTER TABLE `CheckIn` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_check_in_school_id` ON `CheckIn` (`school_id`);
+
+ALTER TABLE `ClassSchedule` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_schedule_school_id` ON `ClassSchedule` (`school_id`);
+
+ALTER TABLE `KioskDevice` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_kiosk_device_school_id` ON `KioskDevice` (`school_id`);
+
+ALTER TABLE `KioskSwipe` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_swipe_school_id` ON `KioskSwipe` (`school_id`);
+
+ALTER TABLE `AttendanceChange` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_change_school_id` ON `AttendanceChange` (`school_id`);
+
+ALTER TABLE `SyncMutation` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+DROP INDEX `unique_sync_mutation_client_id`;
+CREATE UNIQUE INDEX `unique_sync_mutation_client_id` ON `SyncMutation` (`school_id`, `client_id`);
+
+ALTER TABLE `IdempotencyKey` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+DROP INDEX `unique_idempotency_key`;
+CREATE UNIQUE INDEX `unique_idempotency_key` ON `IdempotencyKey` (`school_id`, `idempotency_key`, `user_email`);
+
+ALTER TABLE `AttendanceRegister` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_register_school_id` ON `AttendanceRegister` (`school_id`);
+
+ALTER TABLE `Delegation` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_delegation_school_id` ON `Delegation` (`school_id`);
+
+ALTER TABLE `DelegationStudentClass` ADD COLUMN `school_id` integer NOT NULL DEFAULT 1;
+CREATE INDEX `idx_delegation_class_school_id` ON `DelegationStudentClass` (`school_id`);
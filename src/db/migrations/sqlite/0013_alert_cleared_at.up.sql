@@ -0,0 +1,4 @@
+-- Alerts remember when their condition cleared, so a rule only fires again
+-- after that.
+
+ALTER TABLE `Alert` ADD COLUMN `cleared_at` datetime;
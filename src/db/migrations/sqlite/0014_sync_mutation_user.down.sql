@@ -0,0 +1,2 @@
+DROP INDEX `unique_sync_mutation_client_id`;
+CREATE UNIQUE INDEX `unique_sync_mutation_client_id` ON `SyncMutation` (`school_id`, `client_id`);
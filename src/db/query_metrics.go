@@ -0,0 +1,55 @@
+package db
+
+import (
+	"errors"
+	"skulla-api/metrics"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStartedAtKey = "metrics:started_at"
+
+// queryMetrics is a GORM plugin that records the duration of every statement
+// by operation and table.
+type queryMetrics struct{}
+
+func (queryMetrics) Name() string {
+	return "query_metrics"
+}
+
+func (queryMetrics) Initialize(database *gorm.DB) error {
+	callbacks := database.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:start_create", startQueryTimer),
+		callbacks.Create().After("gorm:create").Register("metrics:observe_create", observeQuery("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:start_query", startQueryTimer),
+		callbacks.Query().After("gorm:query").Register("metrics:observe_query", observeQuery("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:start_update", startQueryTimer),
+		callbacks.Update().After("gorm:update").Register("metrics:observe_update", observeQuery("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:start_delete", startQueryTimer),
+		callbacks.Delete().After("gorm:delete").Register("metrics:observe_delete", observeQuery("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:start_row", startQueryTimer),
+		callbacks.Row().After("gorm:row").Register("metrics:observe_row", observeQuery("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:start_raw", startQueryTimer),
+		callbacks.Raw().After("gorm:raw").Register("metrics:observe_raw", observeQuery("raw")),
+	)
+}
+
+func startQueryTimer(tx *gorm.DB) {
+	tx.InstanceSet(queryStartedAtKey, time.Now())
+}
+
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		startedAt, ok := tx.InstanceGet(queryStartedAtKey)
+		if !ok {
+			return
+		}
+		table := tx.Statement.Table
+		if table == "" {
+			table = "none"
+		}
+		metrics.DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(startedAt.(time.Time)).Seconds())
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"skulla-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const querySpanKey = "tracing:span"
+
+// queryTracing is a GORM plugin that records every statement as a span, a
+// child of the span in the statement's context.
+type queryTracing struct{}
+
+func (queryTracing) Name() string {
+	return "query_tracing"
+}
+
+func (queryTracing) Initialize(database *gorm.DB) error {
+	callbacks := database.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:start_create", startQuerySpan("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:end_create", endQuerySpan),
+		callbacks.Query().Before("gorm:query").Register("tracing:start_query", startQuerySpan("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:end_query", endQuerySpan),
+		callbacks.Update().Before("gorm:update").Register("tracing:start_update", startQuerySpan("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:end_update", endQuerySpan),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:start_delete", startQuerySpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:end_delete", endQuerySpan),
+		callbacks.Row().Before("gorm:row").Register("tracing:start_row", startQuerySpan("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:end_row", endQuerySpan),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:start_raw", startQuerySpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:end_raw", endQuerySpan),
+	)
+}
+
+// startQuerySpan names the span after the operation and table, e.g.
+// "query Attendance".
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		name := operation
+		if tx.Statement.Table != "" {
+			name += " " + tx.Statement.Table
+		}
+		_, span := tracing.Tracer().Start(tx.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(tx.Dialector.Name()),
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(tx.Statement.Table),
+			),
+		)
+		tx.InstanceSet(querySpanKey, span)
+	}
+}
+
+// endQuerySpan adds the statement, without its values, and fails the span on
+// errors other than a missing record.
+func endQuerySpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		tracing.RecordError(span, tx.Error)
+	}
+}
@@ -1,7 +1,10 @@
 package db
 
+import "context"
+
 type Registration struct {
 	ID             uint    `gorm:"primaryKey"`
+	SchoolID       uint    `gorm:"not null;index:idx_registration_school_id"`
 	Status         string  `gorm:"size:255;not null"`
 	StudentID      uint    `gorm:"foreignKey:StudentID"`
 	Student        Student `gorm:"foreignKey:StudentID"`
@@ -14,28 +17,79 @@ func (Registration) TableName() string {
 
 type Student struct {
 	ID        uint   `gorm:"primaryKey"`
+	SchoolID  uint   `gorm:"not null;index:idx_student_school_id"`
 	FirstName string `gorm:"column:firstName;size:255"`
 	LastName  string `gorm:"column:lastName;size:255"`
+	Email     string `gorm:"column:email;size:255;index:idx_student_email"`
+	CardID    string `gorm:"column:card_id;size:100;index:idx_student_card_id"`
 }
 
 func (Student) TableName() string {
 	return "Student"
 }
 
-func ListRegistrations(studentClassId int) []Registration {
+func ListRegistrations(ctx context.Context, studentClassId int) []Registration {
 	var registration []Registration
 
-	db.Preload("Student").
-		Joins("JOIN Student ON Student.ID = Registration.student_id").
+	conn(ctx).Preload("Student").
+		Joins(`JOIN "Student" ON "Student".id = "Registration".student_id`).
 		Where("student_class_id = ?", studentClassId).
-		Order("Student.firstName ASC, Student.lastName ASC").
+		Order(`"Student"."firstName" ASC, "Student"."lastName" ASC`).
 		Find(&registration)
 
 	return registration
 }
 
-func RegistrationExists(registrationID uint) bool {
+func RegistrationExists(ctx context.Context, registrationID uint) bool {
 	var count int64
-	db.Model(&Registration{}).Where("id = ?", registrationID).Count(&count)
+	conn(ctx).Model(&Registration{}).Where("id = ?", registrationID).Count(&count)
 	return count > 0
 }
+
+// ExistingRegistrationIDs returns which of registrationIDs exist, in a single
+// query.
+func ExistingRegistrationIDs(ctx context.Context, registrationIDs []uint) (map[uint]bool, error) {
+	var found []uint
+	if err := conn(ctx).Model(&Registration{}).Where("id IN ?", registrationIDs).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+
+	existing := make(map[uint]bool, len(found))
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// IsStudentInStudentClasses reports whether the student has a registration
+// in one of studentClassIDs.
+func IsStudentInStudentClasses(ctx context.Context, studentID uint, studentClassIDs []uint) bool {
+	if len(studentClassIDs) == 0 {
+		return false
+	}
+
+	var count int64
+	conn(ctx).Model(&Registration{}).
+		Where("student_id = ?", studentID).
+		Where("student_class_id IN ?", studentClassIDs).
+		Count(&count)
+	return count > 0
+}
+
+// GetStudentByEmail returns the student whose account uses email.
+func GetStudentByEmail(ctx context.Context, email string) (Student, error) {
+	var student Student
+	err := conn(ctx).Where("email = ?", email).First(&student).Error
+	return student, err
+}
+
+// GetActiveRegistration returns the active registration of a student in a
+// StudentClass.
+func GetActiveRegistration(ctx context.Context, studentID uint, studentClassID uint) (Registration, error) {
+	var registration Registration
+	err := conn(ctx).Where("student_id = ?", studentID).
+		Where("student_class_id = ?", studentClassID).
+		Where("status = ?", ActiveRegistrationStatus).
+		First(&registration).Error
+	return registration, err
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoSchool    = errors.New("no school in context")
+	ErrWrongSchool = errors.New("record belongs to another school")
+)
+
+// School is the tenant every other row belongs to. Requests reach it either
+// through the school_id claim of their token or through its Subdomain.
+type School struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"size:255;not null"`
+	Subdomain string    `gorm:"size:100;not null;uniqueIndex:unique_school_subdomain"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (School) TableName() string {
+	return "School"
+}
+
+// tenantModels lists every model that carries a SchoolID. Queries on their
+// tables are scoped to the school of the statement's context.
+var tenantModels = []interface{ TableName() string }{
+	Course{}, Period{}, StudentClass{}, Student{}, Registration{},
+	Attendance{}, AttendanceChange{}, AttendanceRegister{}, DailyClassAttendanceSummary{},
+	Alert{}, Guardian{}, GuardianNotification{},
+	WebhookSubscription{}, OutboxEvent{}, WebhookDelivery{},
+	CheckInWindow{}, CheckIn{}, ClassSchedule{}, KioskDevice{}, KioskSwipe{},
+	SyncMutation{}, IdempotencyKey{}, Delegation{}, DelegationStudentClass{},
+}
+
+var tenantTables = make(map[string]bool)
+
+func init() {
+	for _, model := range tenantModels {
+		tenantTables[model.TableName()] = true
+	}
+}
+
+type schoolScope struct {
+	schoolID uint
+	all      bool
+}
+
+type schoolScopeKey struct{}
+
+// WithSchool returns a context whose queries only see rows of schoolID and
+// whose inserts are assigned to it.
+func WithSchool(ctx context.Context, schoolID uint) context.Context {
+	return context.WithValue(ctx, schoolScopeKey{}, schoolScope{schoolID: schoolID})
+}
+
+// AcrossSchools returns a context whose queries see the rows of every school.
+func AcrossSchools(ctx context.Context) context.Context {
+	return context.WithValue(ctx, schoolScopeKey{}, schoolScope{all: true})
+}
+
+// SchoolID returns the school ctx is scoped to, if any.
+func SchoolID(ctx context.Context) (uint, bool) {
+	scope, ok := ctx.Value(schoolScopeKey{}).(schoolScope)
+	return scope.schoolID, ok && !scope.all
+}
+
+// conn starts a session that carries ctx, so tenantScope can scope it.
+func conn(ctx context.Context) *gorm.DB {
+	return db.WithContext(ctx)
+}
+
+// tenantScope is a GORM plugin that scopes every statement on a tenant table
+// to the school of its context.
+type tenantScope struct{}
+
+func (tenantScope) Name() string {
+	return "tenant_scope"
+}
+
+func (tenantScope) Initialize(database *gorm.DB) error {
+	callbacks := database.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:assign_school", assignSchool); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:scope_query", scopeToSchool); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:scope_row", scopeToSchool); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:scope_update", scopeToSchool); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenant:scope_delete", scopeToSchool)
+}
+
+func statementScope(tx *gorm.DB) (schoolScope, bool) {
+	if tx.Error != nil || !tenantTables[tx.Statement.Table] {
+		return schoolScope{}, false
+	}
+	scope, ok := tx.Statement.Context.Value(schoolScopeKey{}).(schoolScope)
+	if !ok {
+		tx.AddError(ErrNoSchool)
+		return schoolScope{}, false
+	}
+	return scope, true
+}
+
+func scopeToSchool(tx *gorm.DB) {
+	scope, ok := statementScope(tx)
+	if !ok || scope.all {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: tx.Statement.Table, Name: "school_id"}, Value: scope.schoolID},
+	}})
+}
+
+// assignSchool sets the SchoolID of rows inserted under WithSchool. Rows
+// inserted AcrossSchools must already carry one.
+func assignSchool(tx *gorm.DB) {
+	scope, ok := statementScope(tx)
+	if !ok || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField("SchoolID")
+	if field == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	assign := func(row reflect.Value) {
+		current, zero := field.ValueOf(ctx, row)
+		if scope.all {
+			if zero {
+				tx.AddError(ErrNoSchool)
+			}
+			return
+		}
+		if !zero && current != scope.schoolID {
+			tx.AddError(ErrWrongSchool)
+			return
+		}
+		if err := field.Set(ctx, row, scope.schoolID); err != nil {
+			tx.AddError(err)
+		}
+	}
+
+	switch rows := tx.Statement.ReflectValue; rows.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			assign(reflect.Indirect(rows.Index(i)))
+		}
+	case reflect.Struct:
+		assign(rows)
+	}
+}
+
+func GetSchool(ctx context.Context, schoolID uint) (School, error) {
+	var school School
+	err := conn(ctx).First(&school, schoolID).Error
+	return school, err
+}
+
+func GetSchoolBySubdomain(ctx context.Context, subdomain string) (School, error) {
+	var school School
+	err := conn(ctx).Where("subdomain = ?", subdomain).First(&school).Error
+	return school, err
+}
+
+func ListSchools(ctx context.Context) []School {
+	var schools []School
+	conn(ctx).Order("id ASC").Find(&schools)
+	return schools
+}
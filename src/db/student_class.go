@@ -1,11 +1,13 @@
 package db
 
 import (
+	"context"
 	"time"
 )
 
 type StudentClass struct {
 	ID       uint   `gorm:"primaryKey"`
+	SchoolID uint   `gorm:"not null;index:idx_student_class_school_id"`
 	Name     string `gorm:"size:255;not null"`
 	CourseID uint   `gorm:"foreignKey:CourseID"`
 	Course   Course `gorm:"foreignKey:CourseID"`
@@ -18,29 +20,32 @@ func (StudentClass) TableName() string {
 }
 
 type Period struct {
-	ID    uint      `gorm:"primaryKey"`
-	Start time.Time `gorm:"type:datetime"`
-	End   time.Time `gorm:"type:datetime"`
+	ID       uint `gorm:"primaryKey"`
+	SchoolID uint `gorm:"not null;index:idx_period_school_id"`
+	Start    time.Time
+	End      time.Time
 }
 
 func (Period) TableName() string {
 	return "Period"
 }
 
-func ListStudentClasses(courseIds []uint, startDate *time.Time, endDate *time.Time) []StudentClass {
+// ListStudentClasses returns the classes of the given courses and
+// studentClassIDs, optionally limited to [startDate, endDate].
+func ListStudentClasses(ctx context.Context, courseIds []uint, studentClassIDs []uint, startDate *time.Time, endDate *time.Time) []StudentClass {
 	var studentClass []StudentClass
 
-	query := db.Preload("Course").Preload("Period")
-	query = query.Where("course_id IN ?", courseIds)
+	query := conn(ctx).Preload("Course").Preload("Period")
+	query = query.Where(`"StudentClass".course_id IN ? OR "StudentClass".id IN ?`, courseIds, studentClassIDs)
 
 	if startDate != nil || endDate != nil {
 		query = query.Joins("Period")
 
 		if startDate != nil {
-			query = query.Where("Period.end >= ?", startDate)
+			query = query.Where(`"Period"."end" >= ?`, startDate)
 		}
 		if endDate != nil {
-			query = query.Where("Period.start <= ?", endDate)
+			query = query.Where(`"Period"."start" <= ?`, endDate)
 		}
 	}
 
@@ -48,9 +53,9 @@ func ListStudentClasses(courseIds []uint, startDate *time.Time, endDate *time.Ti
 	return studentClass
 }
 
-func GetStudentClassCourseID(studentClassID uint) (uint, error) {
+func GetStudentClassCourseID(ctx context.Context, studentClassID uint) (uint, error) {
 	var studentClass StudentClass
-	err := db.First(&studentClass, studentClassID).Error
+	err := conn(ctx).First(&studentClass, studentClassID).Error
 	if err != nil {
 		return 0, err
 	}
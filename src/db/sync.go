@@ -0,0 +1,309 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"skulla-api/live"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outcomes of a synced mutation.
+const (
+	SyncApplied   = "APPLIED"
+	SyncConflict  = "CONFLICT"
+	SyncRejected  = "REJECTED"
+	SyncDuplicate = "DUPLICATE"
+)
+
+// SyncMutation remembers every mutation uploaded by an offline client.
+type SyncMutation struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint      `gorm:"not null;uniqueIndex:unique_sync_mutation_client_id"`
+	ClientID       string    `gorm:"size:100;not null;uniqueIndex:unique_sync_mutation_client_id"`
+	RegistrationID uint      `gorm:"not null"`
+	Date           string    `gorm:"type:date;not null"`
+	Outcome        string    `gorm:"size:20;not null"`
+	Version        int       `gorm:"not null"`
+	CreatedBy      string    `gorm:"size:500;uniqueIndex:unique_sync_mutation_client_id"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (SyncMutation) TableName() string {
+	return "SyncMutation"
+}
+
+// AttendanceMutation is an attendance edit queued by an offline client.
+type AttendanceMutation struct {
+	ClientID        string
+	ClientTimestamp time.Time
+	BaseVersion     int
+	RegistrationID  uint
+	Date            string
+	Status          string
+	Remarks         string
+}
+
+type MutationResult struct {
+	ClientID string           `json:"client_id"`
+	Outcome  string           `json:"outcome"`
+	Version  int              `json:"version,omitempty"`
+	Reason   string           `json:"reason,omitempty"`
+	Server   *AttendanceState `json:"server,omitempty"`
+}
+
+type SyncRosterClass struct {
+	StudentClassID uint           `json:"student_class_id"`
+	Name           string         `json:"name"`
+	Registrations  []Registration `json:"registrations"`
+}
+
+// ApplyAttendanceMutations applies mutations in client timestamp order. A
+// mutation whose base version is stale is returned as a conflict.
+func ApplyAttendanceMutations(ctx context.Context, mutations []AttendanceMutation, studentClassIDs []uint, userEmail string) ([]MutationResult, error) {
+	sort.SliceStable(mutations, func(i, j int) bool {
+		if !mutations[i].ClientTimestamp.Equal(mutations[j].ClientTimestamp) {
+			return mutations[i].ClientTimestamp.Before(mutations[j].ClientTimestamp)
+		}
+		return mutations[i].ClientID < mutations[j].ClientID
+	})
+
+	allowed := make(map[uint]bool)
+	for _, id := range studentClassIDs {
+		allowed[id] = true
+	}
+
+	var results []MutationResult
+	var events []live.Event
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		results = make([]MutationResult, 0, len(mutations))
+		events = nil
+
+		var registrations []Registration
+		var registrationIDs []uint
+		for _, mutation := range mutations {
+			registrationIDs = append(registrationIDs, mutation.RegistrationID)
+		}
+		if err := tx.Select("id", "student_class_id").Where("id IN ?", registrationIDs).Find(&registrations).Error; err != nil {
+			return err
+		}
+		classByRegistration := make(map[uint]uint)
+		for _, registration := range registrations {
+			classByRegistration[registration.ID] = registration.StudentClassID
+		}
+
+		var pending []BulkAttendanceRecord
+		for _, mutation := range mutations {
+			pending = append(pending, BulkAttendanceRecord{RegistrationID: mutation.RegistrationID, Date: mutation.Date})
+		}
+		registers, err := loadRegisters(tx, pending, classByRegistration)
+		if err != nil {
+			return err
+		}
+
+		// chains tracks records written earlier in this upload.
+		type chain struct{ base, version int }
+		chains := make(map[string]chain)
+
+		for _, mutation := range mutations {
+			date := normalizeDate(mutation.Date)
+			result := MutationResult{ClientID: mutation.ClientID}
+
+			var previous []SyncMutation
+			err := tx.Where("created_by = ?", userEmail).
+				Where("client_id = ?", mutation.ClientID).
+				Limit(1).
+				Find(&previous).Error
+			if err != nil {
+				return err
+			}
+			if len(previous) > 0 {
+				result.Outcome = SyncDuplicate
+				result.Version = previous[0].Version
+				results = append(results, result)
+				continue
+			}
+
+			studentClassID, exists := classByRegistration[mutation.RegistrationID]
+			switch {
+			case !exists:
+				result.Outcome = SyncRejected
+				result.Reason = "registration not found"
+			case !allowed[studentClassID]:
+				result.Outcome = SyncRejected
+				result.Reason = "no permission to access student class"
+			case registers[registerKey(studentClassID, date)].Status == RegisterLocked:
+				result.Outcome = SyncRejected
+				result.Reason = ErrRegisterLocked.Error()
+			default:
+				current, err := currentAttendanceState(tx.Clauses(clause.Locking{Strength: "UPDATE"}), mutation.RegistrationID, date)
+				if err != nil {
+					return err
+				}
+
+				key := attendanceKey(mutation.RegistrationID, date)
+				currentVersion := 0
+				if current != nil {
+					currentVersion = current.Version
+				}
+				chained, inChain := chains[key]
+
+				switch {
+				case current != nil && current.Status == mutation.Status && current.Remarks == mutation.Remarks:
+					result.Outcome = SyncApplied
+					result.Version = current.Version
+				case mutation.BaseVersion == currentVersion || (inChain && chained.version == currentVersion && mutation.BaseVersion == chained.base):
+					written, err := recordAttendance(tx, []BulkAttendanceRecord{{
+						RegistrationID: mutation.RegistrationID,
+						Date:           date,
+						Status:         mutation.Status,
+						Remarks:        mutation.Remarks,
+						UserEmail:      userEmail,
+					}})
+					if err != nil {
+						return err
+					}
+					events = append(events, written...)
+
+					if !inChain {
+						chained = chain{base: mutation.BaseVersion}
+					}
+					chained.version = currentVersion + 1
+					chains[key] = chained
+
+					result.Outcome = SyncApplied
+					result.Version = chained.version
+				default:
+					result.Outcome = SyncConflict
+					result.Version = currentVersion
+					result.Reason = fmt.Sprintf("record is at version %d, mutation was based on version %d", currentVersion, mutation.BaseVersion)
+					result.Server = current
+				}
+			}
+
+			err = tx.Create(&SyncMutation{
+				ClientID:       mutation.ClientID,
+				RegistrationID: mutation.RegistrationID,
+				Date:           date,
+				Outcome:        result.Outcome,
+				Version:        result.Version,
+				CreatedBy:      userEmail,
+			}).Error
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishAttendanceEvents(events)
+	return results, nil
+}
+
+// ListAttendanceChanges returns the records of the classes changed after the
+// cursor and before settledBefore, oldest change first.
+func ListAttendanceChanges(ctx context.Context, studentClassIDs []uint, after uint, settledBefore time.Time, limit int) ([]AttendanceState, uint, bool) {
+	attendances := []AttendanceState{}
+	if len(studentClassIDs) == 0 {
+		return attendances, after, false
+	}
+
+	var changes []AttendanceChange
+	conn(ctx).Table("AttendanceChange").
+		Joins(`JOIN "Registration" ON "Registration".id = "AttendanceChange".registration_id`).
+		Select(`"AttendanceChange".*`).
+		Where(`"Registration".student_class_id IN ?`, studentClassIDs).
+		Where(`"AttendanceChange".id > ?`, after).
+		Where(`"AttendanceChange".created_at <= ?`, settledBefore).
+		Order(`"AttendanceChange".id ASC`).
+		Limit(limit + 1).
+		Scan(&changes)
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+	if len(changes) == 0 {
+		return attendances, after, false
+	}
+
+	latest := make(map[string]int)
+	var pairs []string
+	var args []interface{}
+	for i, change := range changes {
+		key := attendanceKey(change.RegistrationID, change.Date)
+		if _, seen := latest[key]; !seen {
+			pairs = append(pairs, `("Attendance".registration_id = ? AND "Attendance".date = ?)`)
+			args = append(args, change.RegistrationID, normalizeDate(change.Date))
+		}
+		latest[key] = i
+	}
+
+	var candidates []AttendanceState
+	attendanceStates(conn(ctx)).
+		Where("("+strings.Join(pairs, " OR ")+")", args...).
+		Scan(&candidates)
+
+	type ordered struct {
+		index      int
+		attendance AttendanceState
+	}
+	var found []ordered
+	for _, attendance := range candidates {
+		attendance.Date = normalizeDate(attendance.Date)
+		if index, changed := latest[attendanceKey(attendance.RegistrationID, attendance.Date)]; changed {
+			found = append(found, ordered{index: index, attendance: attendance})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].index < found[j].index
+	})
+	for _, item := range found {
+		attendances = append(attendances, item.attendance)
+	}
+
+	return attendances, changes[len(changes)-1].ID, hasMore
+}
+
+// GetRoster returns the classes with their registrations and students.
+func GetRoster(ctx context.Context, studentClassIDs []uint) []SyncRosterClass {
+	roster := []SyncRosterClass{}
+	if len(studentClassIDs) == 0 {
+		return roster
+	}
+
+	var classes []StudentClass
+	conn(ctx).Select("id", "name").Where("id IN ?", studentClassIDs).Order("id ASC").Find(&classes)
+	for _, class := range classes {
+		roster = append(roster, SyncRosterClass{
+			StudentClassID: class.ID,
+			Name:           class.Name,
+			Registrations:  ListRegistrations(ctx, int(class.ID)),
+		})
+	}
+	return roster
+}
+
+// RosterFingerprint hashes a roster so clients can tell whether it changed
+// since their last sync without downloading it.
+func RosterFingerprint(roster []SyncRosterClass) string {
+	hash := sha256.New()
+	for _, class := range roster {
+		fmt.Fprintf(hash, "c%d:%s;", class.StudentClassID, class.Name)
+		for _, registration := range class.Registrations {
+			fmt.Fprintf(hash, "r%d:%d:%s:%s:%s;", registration.ID, registration.StudentID, registration.Status,
+				registration.Student.FirstName, registration.Student.LastName)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// timeSeries accumulates attendance statuses into ISO week and calendar month
+// buckets and renders them as chronologically sorted, gap-filled trends.
+type timeSeries struct {
+	weekly  map[string]*WeeklyTrend
+	monthly map[string]*MonthlyTrend
+}
+
+func newTimeSeries() *timeSeries {
+	return &timeSeries{
+		weekly:  make(map[string]*WeeklyTrend),
+		monthly: make(map[string]*MonthlyTrend),
+	}
+}
+
+func weekKey(date time.Time) string {
+	year, week := date.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+func monthKey(date time.Time) string {
+	return date.Format("2006-01")
+}
+
+// parseDate accepts both plain dates and the RFC 3339 timestamps drivers
+// produce when scanning DATE columns into strings.
+func parseDate(date string) (time.Time, error) {
+	if len(date) > len(dateLayout) {
+		date = date[:len(dateLayout)]
+	}
+	return time.Parse(dateLayout, date)
+}
+
+// normalizeDate returns date in YYYY-MM-DD form, or unchanged if it cannot be
+// parsed.
+func normalizeDate(date string) string {
+	parsedDate, err := parseDate(date)
+	if err != nil {
+		return date
+	}
+	return parsedDate.Format(dateLayout)
+}
+
+func percentage(count int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
+// add records count attendances with the given status on date.
+func (ts *timeSeries) add(date string, status string, count int) {
+	parsedDate, err := parseDate(date)
+	if err != nil {
+		return
+	}
+
+	week := weekKey(parsedDate)
+	if ts.weekly[week] == nil {
+		ts.weekly[week] = &WeeklyTrend{Week: week}
+	}
+	ts.weekly[week].TotalDays += count
+
+	month := monthKey(parsedDate)
+	if ts.monthly[month] == nil {
+		ts.monthly[month] = &MonthlyTrend{Month: month}
+	}
+	ts.monthly[month].TotalDays += count
+
+	if status == "PRESENT" {
+		ts.weekly[week].PresentCount += count
+		ts.monthly[month].PresentCount += count
+	}
+}
+
+// weeklyTrends returns one entry per ISO week between startDate and endDate,
+// zero-filled where no attendance was recorded.
+func (ts *timeSeries) weeklyTrends(startDate string, endDate string) []WeeklyTrend {
+	keys := seriesKeys(ts.weekly, startDate, endDate, func(start time.Time, end time.Time) []string {
+		var keys []string
+		for current := start; !current.After(end); current = current.AddDate(0, 0, 7) {
+			keys = append(keys, weekKey(current))
+		}
+		return append(keys, weekKey(end))
+	})
+
+	trends := make([]WeeklyTrend, 0, len(keys))
+	for _, key := range keys {
+		trend := WeeklyTrend{Week: key}
+		if bucket, exists := ts.weekly[key]; exists {
+			trend = *bucket
+		}
+		trend.Percentage = percentage(trend.PresentCount, trend.TotalDays)
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// monthlyTrends returns one entry per calendar month between startDate and
+// endDate, zero-filled where no attendance was recorded.
+func (ts *timeSeries) monthlyTrends(startDate string, endDate string) []MonthlyTrend {
+	keys := seriesKeys(ts.monthly, startDate, endDate, func(start time.Time, end time.Time) []string {
+		var keys []string
+		current := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		for !current.After(end) {
+			keys = append(keys, monthKey(current))
+			current = current.AddDate(0, 1, 0)
+		}
+		return keys
+	})
+
+	trends := make([]MonthlyTrend, 0, len(keys))
+	for _, key := range keys {
+		trend := MonthlyTrend{Month: key}
+		if bucket, exists := ts.monthly[key]; exists {
+			trend = *bucket
+		}
+		trend.Percentage = percentage(trend.PresentCount, trend.TotalDays)
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// seriesKeys returns the sorted bucket keys for [startDate, endDate].
+func seriesKeys[T any](buckets map[string]T, startDate string, endDate string, rangeKeys func(time.Time, time.Time) []string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+
+	for key := range buckets {
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	start, startErr := time.Parse(dateLayout, startDate)
+	end, endErr := time.Parse(dateLayout, endDate)
+	if startErr == nil && endErr == nil && !start.After(end) {
+		for _, key := range rangeKeys(start, end) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
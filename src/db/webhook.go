@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventAttendanceRecorded = "attendance.recorded"
+	EventAttendanceUpdated  = "attendance.updated"
+	EventAlertRaised        = "alert.raised"
+)
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryDead      = "DEAD"
+)
+
+type WebhookSubscription struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	SchoolID  uint      `gorm:"not null;index:idx_subscription_school_id"`
+	URL       string    `gorm:"size:1000;not null"`
+	Secret    string    `gorm:"size:255;not null" json:"-"`
+	Events    string    `gorm:"size:500;not null"`
+	CreatedBy string    `gorm:"size:500"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "WebhookSubscription"
+}
+
+func (s WebhookSubscription) subscribesTo(eventType string) bool {
+	for _, event := range strings.Split(s.Events, ",") {
+		if strings.TrimSpace(event) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxEvent is written in the same transaction as the change it describes
+// and later fanned out into one WebhookDelivery per matching subscription.
+type OutboxEvent struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement"`
+	SchoolID    uint       `gorm:"not null;index:idx_outbox_school_id"`
+	EventType   string     `gorm:"size:100;not null"`
+	Payload     string     `gorm:"type:text;not null"`
+	FannedOutAt *time.Time `gorm:"index:idx_outbox_fanned_out_at"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "OutboxEvent"
+}
+
+type WebhookDelivery struct {
+	ID             uint                `gorm:"primaryKey;autoIncrement"`
+	SchoolID       uint                `gorm:"not null;index:idx_delivery_school_id"`
+	SubscriptionID uint                `gorm:"not null;index:idx_delivery_subscription_id"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID"`
+	OutboxEventID  uint                `gorm:"not null"`
+	OutboxEvent    OutboxEvent         `gorm:"foreignKey:OutboxEventID"`
+	Status         string              `gorm:"size:20;not null;index:idx_delivery_status"`
+	Attempts       int                 `gorm:"not null"`
+	LastError      string              `gorm:"type:text"`
+	NextAttemptAt  time.Time           `gorm:"index:idx_delivery_status"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "WebhookDelivery"
+}
+
+type AttendanceEventPayload struct {
+	RegistrationID uint   `json:"registration_id"`
+	StudentClassID uint   `json:"student_class_id"`
+	Date           string `json:"date"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Remarks        string `json:"remarks"`
+	RecordedBy     string `json:"recorded_by"`
+	OnBehalfOf     string `json:"on_behalf_of,omitempty"`
+}
+
+type AlertEventPayload struct {
+	AlertID        uint   `json:"alert_id"`
+	RegistrationID uint   `json:"registration_id"`
+	StudentClassID uint   `json:"student_class_id"`
+	Rule           string `json:"rule"`
+	Severity       string `json:"severity"`
+	Message        string `json:"message"`
+	TriggeredOn    string `json:"triggered_on"`
+}
+
+func writeOutboxEvent(tx *gorm.DB, eventType string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&OutboxEvent{EventType: eventType, Payload: string(body)}).Error
+}
+
+func CreateWebhookSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	return conn(ctx).Create(subscription).Error
+}
+
+func ListWebhookSubscriptions(ctx context.Context) []WebhookSubscription {
+	var subscriptions []WebhookSubscription
+	conn(ctx).Order("id ASC").Find(&subscriptions)
+	return subscriptions
+}
+
+func DeleteWebhookSubscription(ctx context.Context, subscriptionID uint) (bool, error) {
+	result := conn(ctx).Delete(&WebhookSubscription{}, subscriptionID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FanOutOutboxEvents turns pending outbox events into deliveries for every
+// subscription to their event type, and returns how many events it processed.
+func FanOutOutboxEvents(ctx context.Context) (int, error) {
+	processed := 0
+	err := conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("fanned_out_at IS NULL").
+			Order("id ASC").
+			Limit(500).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		var subscriptions []WebhookSubscription
+		if err := tx.Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var eventIDs []uint
+		var deliveries []WebhookDelivery
+		for _, event := range events {
+			eventIDs = append(eventIDs, event.ID)
+			for _, subscription := range subscriptions {
+				if subscription.subscribesTo(event.EventType) {
+					deliveries = append(deliveries, WebhookDelivery{
+						SubscriptionID: subscription.ID,
+						OutboxEventID:  event.ID,
+						Status:         WebhookDeliveryPending,
+						NextAttemptAt:  now,
+					})
+				}
+			}
+		}
+
+		if len(deliveries) > 0 {
+			if err := tx.CreateInBatches(&deliveries, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		processed = len(events)
+		return tx.Model(&OutboxEvent{}).Where("id IN ?", eventIDs).Update("fanned_out_at", now).Error
+	})
+	return processed, err
+}
+
+func ListDueWebhookDeliveries(ctx context.Context, now time.Time) []WebhookDelivery {
+	var deliveries []WebhookDelivery
+	conn(ctx).Preload("Subscription").
+		Preload("OutboxEvent").
+		Where("status = ?", WebhookDeliveryPending).
+		Where("next_attempt_at <= ?", now).
+		Order("id ASC").
+		Find(&deliveries)
+	return deliveries
+}
+
+// ClaimWebhookDelivery reports whether this caller got the due delivery.
+func ClaimWebhookDelivery(ctx context.Context, deliveryID uint, now time.Time, leaseUntil time.Time) (bool, error) {
+	result := conn(ctx).Model(&WebhookDelivery{}).
+		Where("id = ?", deliveryID).
+		Where("status = ?", WebhookDeliveryPending).
+		Where("next_attempt_at <= ?", now).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected > 0, result.Error
+}
+
+func MarkWebhookDeliveryDelivered(ctx context.Context, deliveryID uint, deliveredAt time.Time) error {
+	return conn(ctx).Model(&WebhookDelivery{}).
+		Where("id = ?", deliveryID).
+		Updates(map[string]interface{}{
+			"status":       WebhookDeliveryDelivered,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   "",
+			"delivered_at": deliveredAt,
+		}).Error
+}
+
+// MarkWebhookDeliveryFailed records a failed attempt.
+func MarkWebhookDeliveryFailed(ctx context.Context, deliveryID uint, cause error, nextAttemptAt time.Time, maxAttempts int) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&WebhookDelivery{}).
+			Where("id = ?", deliveryID).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"last_error":      cause.Error(),
+				"next_attempt_at": nextAttemptAt,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&WebhookDelivery{}).
+			Where("id = ?", deliveryID).
+			Where("attempts >= ?", maxAttempts).
+			Update("status", WebhookDeliveryDead).Error
+	})
+}
+
+func ListDeadWebhookDeliveries(ctx context.Context) []WebhookDelivery {
+	var deliveries []WebhookDelivery
+	conn(ctx).Preload("Subscription").
+		Preload("OutboxEvent").
+		Where("status = ?", WebhookDeliveryDead).
+		Order("id ASC").
+		Find(&deliveries)
+	return deliveries
+}
+
+// ReplayWebhookDelivery queues a dead delivery for another round of attempts.
+func ReplayWebhookDelivery(ctx context.Context, deliveryID uint) (bool, error) {
+	result := conn(ctx).Model(&WebhookDelivery{}).
+		Where("id = ?", deliveryID).
+		Where("status = ?", WebhookDeliveryDead).
+		Updates(map[string]interface{}{
+			"status":          WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"skulla-api/config"
+	"skulla-api/db"
+	"time"
+)
+
+func alertRules(settings config.Alerts) db.AlertRules {
+	return db.AlertRules{
+		WindowDays:          settings.WindowDays,
+		MinSessions:         settings.MinSessions,
+		MinPercentage:       settings.MinPercentage,
+		CriticalPercentage:  settings.CriticalPercentage,
+		ConsecutiveAbsences: settings.ConsecutiveAbsences,
+		MonthlyLates:        settings.MonthlyLates,
+	}
+}
+
+// StartAlertEvaluation evaluates the alert rules until ctx is cancelled.
+func StartAlertEvaluation(ctx context.Context, settings config.Alerts) {
+	rules := alertRules(settings)
+	evaluate := func() {
+		forEachSchool(ctx, func(ctx context.Context) {
+			EvaluateAlerts(ctx, rules)
+		})
+	}
+
+	evaluate()
+	runEvery(ctx, settings.EvaluationInterval, evaluate)
+}
+
+func EvaluateAlerts(ctx context.Context, rules db.AlertRules) {
+	alerts, err := db.EvaluateAlerts(ctx, time.Now(), rules)
+	if err != nil {
+		log.Println("Failed to evaluate attendance alerts:", err)
+		return
+	}
+	if len(alerts) > 0 {
+		log.Printf("Raised %d attendance alerts", len(alerts))
+	}
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"skulla-api/config"
+	"skulla-api/db"
+	"time"
+)
+
+// StartIdempotencyKeyCleanup deletes expired idempotency keys until ctx is
+// cancelled.
+func StartIdempotencyKeyCleanup(ctx context.Context, settings config.Idempotency) {
+	runEvery(ctx, settings.CleanupInterval, func() {
+		deleted, err := db.DeleteExpiredIdempotencyKeys(db.AcrossSchools(ctx), time.Now())
+		if err != nil {
+			log.Println("Failed to delete expired idempotency keys:", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d expired idempotency keys", deleted)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"skulla-api/config"
+	"skulla-api/db"
+	"skulla-api/live"
+	"time"
+)
+
+// StartLiveSnapshots publishes roll-call snapshots until ctx is cancelled.
+func StartLiveSnapshots(ctx context.Context, settings config.Live) {
+	runEvery(ctx, settings.SnapshotInterval, func() {
+		now := time.Now()
+		forEachSchool(ctx, func(ctx context.Context) {
+			PublishLiveSnapshots(ctx, now)
+		})
+	})
+}
+
+func PublishLiveSnapshots(ctx context.Context, now time.Time) {
+	date := now.Format("2006-01-02")
+	for _, completion := range db.GetClassCompletions(ctx, db.ListActiveStudentClassIDs(ctx), date) {
+		live.Publish(live.Event{
+			Type:           live.EventSnapshot,
+			StudentClassID: completion.StudentClassID,
+			Data:           completion,
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"skulla-api/config"
+	"skulla-api/db"
+	"skulla-api/notify"
+)
+
+func notificationChannels(settings config.Notifications) map[string]notify.Channel {
+	channels := make(map[string]notify.Channel)
+
+	if smtp := settings.SMTP; smtp.Host != "" {
+		channels[db.NotificationChannelEmail] = notify.NewSMTPChannel(notify.SMTPConfig{
+			Host:     smtp.Host,
+			Port:     smtp.Port,
+			Username: smtp.Username,
+			Password: smtp.Password,
+			From:     smtp.From,
+		})
+	}
+
+	if settings.SMS.URL != "" {
+		channels[db.NotificationChannelSMS] = notify.NewSMSChannel(settings.SMS.URL, settings.SMS.APIKey)
+	}
+
+	if settings.WhatsApp.URL != "" {
+		channels[db.NotificationChannelWhatsApp] = notify.NewWhatsAppChannel(settings.WhatsApp.URL, settings.WhatsApp.APIKey)
+	}
+
+	return channels
+}
+
+// StartNotificationDispatch sends guardian notifications until ctx is
+// cancelled.
+func StartNotificationDispatch(ctx context.Context, settings config.Notifications) {
+	dispatcher := &notify.Dispatcher{
+		Channels:    notificationChannels(settings),
+		DigestHour:  settings.DigestHour,
+		MaxAttempts: settings.MaxAttempts,
+		MaxAgeDays:  settings.MaxAgeDays,
+	}
+
+	runEvery(ctx, settings.Interval, func() {
+		forEachSchool(ctx, func(ctx context.Context) {
+			if delivered := dispatcher.Dispatch(ctx); delivered > 0 {
+				log.Printf("Delivered %d guardian notifications", delivered)
+			}
+		})
+	})
+}
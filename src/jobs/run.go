@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// runEvery calls run on every tick of interval until ctx is cancelled. A run
+// in progress is finished before it returns.
+func runEvery(ctx context.Context, interval time.Duration, run func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
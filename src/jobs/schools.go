@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"context"
+	"skulla-api/db"
+)
+
+// forEachSchool calls run once per school with ctx scoped to it, and stops
+// once ctx is cancelled.
+func forEachSchool(ctx context.Context, run func(ctx context.Context)) {
+	for _, school := range db.ListSchools(ctx) {
+		if ctx.Err() != nil {
+			return
+		}
+		run(db.WithSchool(ctx, school.ID))
+	}
+}
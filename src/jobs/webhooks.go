@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"skulla-api/config"
+	"skulla-api/webhook"
+)
+
+// StartWebhookDispatch delivers webhooks until ctx is cancelled.
+func StartWebhookDispatch(ctx context.Context, settings config.Webhooks) {
+	dispatcher := &webhook.Dispatcher{
+		MaxAttempts:       settings.MaxAttempts,
+		AllowPrivateHosts: settings.AllowPrivateHosts,
+	}
+
+	runEvery(ctx, settings.Interval, func() {
+		forEachSchool(ctx, func(ctx context.Context) {
+			if delivered := dispatcher.Dispatch(ctx); delivered > 0 {
+				log.Printf("Delivered %d webhooks", delivered)
+			}
+		})
+	})
+}
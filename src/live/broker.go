@@ -0,0 +1,124 @@
+package live
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Event types streamed to live attendance board clients.
+const (
+	EventAttendance = "attendance"
+	EventSnapshot   = "snapshot"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 64
+
+// Event is a single update for the live attendance board. StudentClassID lets
+// subscribers filter the stream down to the classes they may see.
+type Event struct {
+	Type           string
+	StudentClassID uint
+	Data           any
+}
+
+// Broker fans events out to every current subscriber. The in-process
+// MemoryBroker is used by default; a networked implementation (e.g. Redis
+// pub/sub) can be installed with SetBroker when running several instances.
+type Broker interface {
+	Publish(event Event)
+	Subscribe() (events <-chan Event, cancel func())
+	// Close ends every subscription, so that open streams return on
+	// shutdown. Later subscriptions are closed right away.
+	Close()
+}
+
+// MemoryBroker delivers events to subscribers within the current process.
+// Publishing never blocks: events for a subscriber whose buffer is full are
+// dropped.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[chan Event]struct{})}
+}
+
+func (b *MemoryBroker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe() (<-chan Event, func()) {
+	subscriber := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(subscriber)
+		return subscriber, func() {}
+	}
+	b.subscribers[subscriber] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, subscribed := b.subscribers[subscriber]; subscribed {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return subscriber, cancel
+}
+
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for subscriber := range b.subscribers {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+var broker Broker = NewMemoryBroker()
+
+func SetBroker(b Broker) {
+	broker = b
+}
+
+func Publish(event Event) {
+	broker.Publish(event)
+}
+
+func Subscribe() (<-chan Event, func()) {
+	return broker.Subscribe()
+}
+
+func Close() {
+	broker.Close()
+}
+
+// Write encodes event in the Server-Sent Events wire format and flushes it.
+func Write(w *bufio.Writer, event Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,80 @@
+package live
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestMemoryBrokerFanOut(t *testing.T) {
+	broker := NewMemoryBroker()
+	first, cancelFirst := broker.Subscribe()
+	defer cancelFirst()
+	second, cancelSecond := broker.Subscribe()
+
+	broker.Publish(Event{Type: EventAttendance, StudentClassID: 1})
+
+	for _, events := range []<-chan Event{first, second} {
+		event := <-events
+		if event.Type != EventAttendance || event.StudentClassID != 1 {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	}
+
+	cancelSecond()
+	cancelSecond()
+	if _, open := <-second; open {
+		t.Error("Expected cancelled subscription to be closed")
+	}
+
+	broker.Publish(Event{Type: EventSnapshot, StudentClassID: 2})
+	if event := <-first; event.StudentClassID != 2 {
+		t.Errorf("Expected remaining subscriber to receive event, got %+v", event)
+	}
+}
+
+func TestMemoryBrokerDropsWhenSubscriberIsFull(t *testing.T) {
+	broker := NewMemoryBroker()
+	events, cancel := broker.Subscribe()
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		broker.Publish(Event{Type: EventAttendance, StudentClassID: uint(i)})
+	}
+
+	if len(events) != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+}
+
+func TestMemoryBrokerClose(t *testing.T) {
+	broker := NewMemoryBroker()
+	events, cancel := broker.Subscribe()
+
+	broker.Close()
+	if _, open := <-events; open {
+		t.Error("Expected closing the broker to close its subscriptions")
+	}
+	cancel()
+
+	late, cancelLate := broker.Subscribe()
+	defer cancelLate()
+	if _, open := <-late; open {
+		t.Error("Expected a subscription after closing to be closed")
+	}
+	broker.Publish(Event{Type: EventAttendance})
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+
+	if err := Write(w, Event{Type: EventSnapshot, Data: map[string]int{"recorded": 2}}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	expected := "event: snapshot\ndata: {\"recorded\":2}\n\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
@@ -1,30 +1,221 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"skulla-api/config"
 	"skulla-api/db"
+	"skulla-api/jobs"
+	"skulla-api/live"
+	"skulla-api/metrics"
 	"skulla-api/rest"
+	"skulla-api/tracing"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
 func main() {
+	appConfig, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration: ", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfig(appConfig, os.Args[2:])
+		return
+	}
+
+	if err := appConfig.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Exports traces when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), appConfig.Tracing)
+	if err != nil {
+		log.Fatal("Failed to set up tracing: ", err)
+	}
+
 	// Connects to database server
-	db.Connect()
+	db.Connect(appConfig.Database)
+
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
 
 	// Instantiate web server
 	app := fiber.New()
 
+	// Traces every request
+	app.Use(tracing.Middleware)
+
+	// Counts and times every request
+	app.Use(metrics.Middleware)
+
 	// Configure CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, If-Match, If-None-Match, Idempotency-Key, X-Kiosk-Key",
+		ExposeHeaders: "ETag, Idempotent-Replayed",
+		AllowMethods:  "GET, POST, PUT, DELETE, OPTIONS",
 	}))
 
-	rest.Init(app) //Init rest endpoints
-	err := app.Listen(":8080")
+	rest.Init(app, appConfig) //Init rest endpoints
+
+	// Stops the server and the background jobs on SIGTERM or Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var background sync.WaitGroup
+
+	// Evaluates at-risk attendance alerts in the background
+	background.Go(func() { jobs.StartAlertEvaluation(ctx, appConfig.Alerts) })
+
+	// Sends queued guardian notifications in the background
+	background.Go(func() { jobs.StartNotificationDispatch(ctx, appConfig.Notifications) })
+
+	// Delivers outbox events to webhook subscriptions in the background
+	background.Go(func() { jobs.StartWebhookDispatch(ctx, appConfig.Webhooks) })
+
+	// Publishes roll-call completion snapshots to live board clients
+	background.Go(func() { jobs.StartLiveSnapshots(ctx, appConfig.Live) })
+
+	// Forgets idempotency keys whose TTL has passed
+	background.Go(func() { jobs.StartIdempotencyKeyCleanup(ctx, appConfig.Idempotency) })
+
+	// Serves /metrics on its own port
+	metricsServer := &http.Server{Addr: fmt.Sprintf(":%d", appConfig.Metrics.Port), Handler: metricsMux()}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start metrics server: ", err)
+		}
+	}()
+
+	drained := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shutting down, waiting up to %s for requests in progress", appConfig.Server.ShutdownTimeout)
+		// Live board streams never finish on their own, so end them first.
+		live.Close()
+		if err := app.ShutdownWithTimeout(appConfig.Server.ShutdownTimeout); err != nil {
+			log.Println("Failed to finish requests in progress:", err)
+		}
+		metricsServer.Close()
+		close(drained)
+	}()
+
+	if err := app.Listen(fmt.Sprintf(":%d", appConfig.Server.Port)); err != nil {
+		log.Fatal("Failed to start server: ", err)
+	}
+
+	<-drained
+	background.Wait()
+	if err := db.Close(); err != nil {
+		log.Println("Failed to close database connections:", err)
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Println("Failed to flush traces:", err)
+	}
+	log.Println("Server stopped")
+}
+
+func runCommand(args []string) {
+	switch command := args[0]; command {
+	case "rebuild-summaries":
+		for _, school := range db.ListSchools(context.Background()) {
+			if err := db.RebuildDailyClassAttendanceSummaries(db.WithSchool(context.Background(), school.ID)); err != nil {
+				log.Fatal("Failed to rebuild attendance summaries:", err)
+			}
+		}
+		log.Println("Attendance summaries rebuilt")
+	case "migrate":
+		runMigrate(args[1:])
+	default:
+		log.Fatalf("Unknown command: %s", command)
+	}
+}
+
+// metricsMux serves the Prometheus metrics at /metrics.
+func metricsMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	return mux
+}
+
+// runConfig handles "config print", which prints the effective configuration
+// with its secrets redacted and then fails if it is invalid.
+func runConfig(appConfig config.Config, args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		log.Fatal("Usage: config print")
+	}
+
+	content, err := appConfig.Redacted().YAML()
 	if err != nil {
-		return
+		log.Fatal("Failed to render configuration: ", err)
+	}
+	fmt.Print(content)
+
+	if err := appConfig.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+}
+
+// runMigrate handles "migrate up", "migrate down [steps]" and "migrate status".
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: migrate up|down [steps]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp()
+		for _, migration := range applied {
+			log.Printf("Applied %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to migrate:", err)
+		}
+		log.Printf("%d migrations applied", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatalf("Invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := db.MigrateDown(steps)
+		for _, migration := range reverted {
+			log.Printf("Reverted %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to revert migrations:", err)
+		}
+		log.Printf("%d migrations reverted", len(reverted))
+	case "status":
+		migrations, err := db.MigrationStatus()
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, migration := range migrations {
+			status := "pending"
+			if migration.AppliedAt != nil {
+				status = "applied " + migration.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", migration.Version, migration.Name, status)
+		}
+	default:
+		log.Fatalf("Unknown migrate command: %s", args[0])
 	}
 }
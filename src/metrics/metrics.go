@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "omniscience"
+
+// Registry holds every metric of the service, along with the Go runtime and
+// process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database statement duration by GORM operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	JWKSFetchFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jwks_fetch_failures_total",
+		Help:      "Failed attempts to fetch the Supabase keys that verify tokens.",
+	})
+
+	AttendanceRecordsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "attendance_records_written_total",
+		Help:      "Attendance records created or updated, by status, counted once committed.",
+	}, []string{"status"})
+
+	BatchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_size",
+		Help:      "Records per batch request, by endpoint.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+	}, []string{"endpoint"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPRequestDuration, DBQueryDuration, JWKSFetchFailures,
+		AttendanceRecordsWritten, BatchSize,
+	)
+}
+
+// RegisterDBStats exports the statistics of the connection pool.
+func RegisterDBStats(sqlDB *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, namespace))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts and times every request by the route pattern it matched,
+// e.g. /student-classes/:id/schedule, so IDs don't multiply the series.
+// Requests that match no route are labelled "unmatched".
+func Middleware(c *fiber.Ctx) error {
+	started := time.Now()
+	middleware := c.Route()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+
+	// The route stays the middleware's own when no handler matched.
+	route := c.Route().Path
+	if c.Route() == middleware {
+		route = "unmatched"
+	}
+
+	labels := prometheus.Labels{"method": c.Method(), "route": route, "status": strconv.Itoa(status)}
+	HTTPRequests.With(labels).Inc()
+	HTTPRequestDuration.With(labels).Observe(time.Since(started).Seconds())
+	return err
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	if err := counter.Write(&metric); err != nil {
+		t.Fatalf("Failed to read counter: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware)
+	app.Get("/student-classes/:id/schedule", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/failing", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusConflict, "conflict")
+	})
+
+	matched := HTTPRequests.WithLabelValues("GET", "/student-classes/:id/schedule", "200")
+	failed := HTTPRequests.WithLabelValues("GET", "/failing", "409")
+	unmatched := HTTPRequests.WithLabelValues("GET", "unmatched", "404")
+	before := []float64{counterValue(t, matched), counterValue(t, failed), counterValue(t, unmatched)}
+
+	for _, path := range []string{"/student-classes/1/schedule", "/student-classes/2/schedule", "/failing", "/missing"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil), -1); err != nil {
+			t.Fatalf("Request to %s failed: %v", path, err)
+		}
+	}
+
+	after := []float64{counterValue(t, matched), counterValue(t, failed), counterValue(t, unmatched)}
+	for i, expected := range []float64{2, 1, 1} {
+		if after[i]-before[i] != expected {
+			t.Errorf("Counter %d: expected %v more requests, got %v", i, expected, after[i]-before[i])
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	JWKSFetchFailures.Inc()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	for _, name := range []string{"omniscience_jwks_fetch_failures_total", "go_goroutines", "omniscience_http_requests_total"} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("Expected %s in the metrics", name)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"skulla-api/db"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Channel delivers a message to a guardian over one transport.
+type Channel interface {
+	Send(guardian db.Guardian, message Message) error
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type SMTPChannel struct {
+	config SMTPConfig
+}
+
+func NewSMTPChannel(config SMTPConfig) *SMTPChannel {
+	return &SMTPChannel{config: config}
+}
+
+func (c *SMTPChannel) Send(guardian db.Guardian, message Message) error {
+	if guardian.Email == "" {
+		return fmt.Errorf("guardian %d has no email address", guardian.ID)
+	}
+	if strings.ContainsAny(guardian.Email, "\r\n") {
+		return fmt.Errorf("guardian %d has an invalid email address", guardian.ID)
+	}
+
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + c.config.From,
+		"To: " + guardian.Email,
+		"Subject: " + message.Subject,
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		message.Body,
+	}, "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+	return smtp.SendMail(addr, auth, c.config.From, []string{guardian.Email}, []byte(body))
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+func postJSON(url string, apiKey string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return nil
+}
+
+// SMSChannel posts messages to an HTTP SMS gateway.
+type SMSChannel struct {
+	url    string
+	apiKey string
+}
+
+func NewSMSChannel(url string, apiKey string) *SMSChannel {
+	return &SMSChannel{url: url, apiKey: apiKey}
+}
+
+func (c *SMSChannel) Send(guardian db.Guardian, message Message) error {
+	if guardian.Phone == "" {
+		return fmt.Errorf("guardian %d has no phone number", guardian.ID)
+	}
+	return postJSON(c.url, c.apiKey, map[string]string{
+		"to":      guardian.Phone,
+		"message": message.Body,
+	})
+}
+
+// WhatsAppChannel posts messages to a WhatsApp webhook.
+type WhatsAppChannel struct {
+	url    string
+	apiKey string
+}
+
+func NewWhatsAppChannel(url string, apiKey string) *WhatsAppChannel {
+	return &WhatsAppChannel{url: url, apiKey: apiKey}
+}
+
+func (c *WhatsAppChannel) Send(guardian db.Guardian, message Message) error {
+	if guardian.Phone == "" {
+		return fmt.Errorf("guardian %d has no phone number", guardian.ID)
+	}
+	return postJSON(c.url, c.apiKey, map[string]string{
+		"to":      guardian.Phone,
+		"subject": message.Subject,
+		"body":    message.Body,
+	})
+}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"skulla-api/db"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// startSMTPStub accepts SMTP sessions on a local port and sends the DATA of
+// every message it receives to the returned channel.
+func startSMTPStub(t *testing.T) (string, int, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start SMTP stub: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return "127.0.0.1", addr.Port, messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost stub")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "DATA"):
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			messages <- data.String()
+			reply("250 queued")
+		case strings.HasPrefix(command, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// startHTTPStub records the JSON bodies posted to it and answers with status.
+func startHTTPStub(t *testing.T, status int) (string, <-chan map[string]string) {
+	bodies := make(chan map[string]string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		body["authorization"] = r.Header.Get("Authorization")
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, bodies
+}
+
+var testGuardian = db.Guardian{ID: 1, Name: "Mary Doe", Email: "mary@test.com", Phone: "+258840000000"}
+
+func TestSMTPChannel_Send(t *testing.T) {
+	host, port, messages := startSMTPStub(t)
+	channel := NewSMTPChannel(SMTPConfig{Host: host, Port: port, From: "school@test.com"})
+
+	err := channel.Send(testGuardian, Message{Subject: "Attendance update", Body: "John was marked ABSENT"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	message := <-messages
+	if !strings.Contains(message, "To: mary@test.com") || !strings.Contains(message, "Subject: Attendance update") {
+		t.Errorf("Unexpected message headers: %s", message)
+	}
+	if !strings.Contains(message, "John was marked ABSENT") {
+		t.Errorf("Unexpected message body: %s", message)
+	}
+}
+
+func TestSMTPChannel_MissingEmail(t *testing.T) {
+	channel := NewSMTPChannel(SMTPConfig{Host: "127.0.0.1", Port: 25})
+
+	if err := channel.Send(db.Guardian{ID: 1}, Message{}); err == nil {
+		t.Error("Expected an error for a guardian without email")
+	}
+}
+
+func TestSMTPChannel_RefusesHeaderInjection(t *testing.T) {
+	channel := NewSMTPChannel(SMTPConfig{Host: "127.0.0.1", Port: 25})
+
+	guardian := db.Guardian{ID: 1, Email: "mary@test.com\r\nBcc: all@test.com"}
+	if err := channel.Send(guardian, Message{}); err == nil {
+		t.Error("Expected an error for an email address with a line break")
+	}
+}
+
+func TestSMSChannel_Send(t *testing.T) {
+	url, bodies := startHTTPStub(t, http.StatusAccepted)
+	channel := NewSMSChannel(url, "sms-key")
+
+	if err := channel.Send(testGuardian, Message{Body: "John was marked LATE"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	body := <-bodies
+	if body["to"] != testGuardian.Phone || body["message"] != "John was marked LATE" || body["authorization"] != "Bearer sms-key" {
+		t.Errorf("Unexpected SMS request: %v", body)
+	}
+}
+
+func TestWhatsAppChannel_GatewayError(t *testing.T) {
+	url, _ := startHTTPStub(t, http.StatusBadGateway)
+	channel := NewWhatsAppChannel(url, "")
+
+	err := channel.Send(testGuardian, Message{Body: "John was marked ABSENT"})
+	if err == nil || !strings.Contains(err.Error(), strconv.Itoa(http.StatusBadGateway)) {
+		t.Errorf("Expected a status error, got %v", err)
+	}
+}
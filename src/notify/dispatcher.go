@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"skulla-api/db"
+	"strings"
+	"time"
+)
+
+// Dispatcher sends queued guardian notifications. Notifications for the same
+// guardian and channel that are due together go out as a single message.
+// Guardians who opted into a daily digest receive one message a day at
+// DigestHour, holding everything queued since the previous digest.
+// Notifications about attendance more than MaxAgeDays older than the day they
+// were queued are cancelled instead of sent.
+type Dispatcher struct {
+	Channels    map[string]Channel
+	DigestHour  int
+	MaxAttempts int
+	MaxAgeDays  int
+	Now         func() time.Time
+}
+
+func (d *Dispatcher) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// retryDelay backs off quadratically: 1, 4, 9, ... minutes.
+func retryDelay(attempt int) time.Duration {
+	return time.Duration(attempt*attempt) * time.Minute
+}
+
+// digestDue reports whether a digest notification queued at queuedAt is due
+// at now. It waits for the first DigestHour after it was queued, so one queued
+// after today's digest went out joins tomorrow's.
+func (d *Dispatcher) digestDue(queuedAt time.Time, now time.Time) bool {
+	queuedAt = queuedAt.In(now.Location())
+	digestAt := time.Date(queuedAt.Year(), queuedAt.Month(), queuedAt.Day(), d.DigestHour, 0, 0, 0, now.Location())
+	if !queuedAt.Before(digestAt) {
+		digestAt = digestAt.AddDate(0, 0, 1)
+	}
+	return !now.Before(digestAt)
+}
+
+// tooOld reports whether notification was queued more than MaxAgeDays after
+// the date of its attendance.
+func (d *Dispatcher) tooOld(notification db.GuardianNotification, now time.Time) bool {
+	date, err := time.ParseInLocation("2006-01-02", notification.Date, now.Location())
+	if err != nil {
+		return false
+	}
+	queuedAt := notification.CreatedAt.In(now.Location())
+	queuedOn := time.Date(queuedAt.Year(), queuedAt.Month(), queuedAt.Day(), 0, 0, 0, 0, now.Location())
+	return date.AddDate(0, 0, d.MaxAgeDays).Before(queuedOn)
+}
+
+// Dispatch sends every due notification and returns how many were delivered.
+func (d *Dispatcher) Dispatch(ctx context.Context) int {
+	now := d.now()
+	delivered := 0
+
+	var batch []db.GuardianNotification
+	flush := func() {
+		if len(batch) > 0 {
+			delivered += d.send(ctx, batch, now)
+		}
+		batch = nil
+	}
+
+	var stale []uint
+	for _, notification := range db.ListDueGuardianNotifications(ctx, now) {
+		if d.tooOld(notification, now) {
+			stale = append(stale, notification.ID)
+			continue
+		}
+		if notification.Guardian.DailyDigest && !d.digestDue(notification.CreatedAt, now) {
+			continue
+		}
+		if len(batch) > 0 && (batch[0].GuardianID != notification.GuardianID || batch[0].Channel != notification.Channel) {
+			flush()
+		}
+		batch = append(batch, notification)
+	}
+	flush()
+
+	if err := db.CancelGuardianNotifications(ctx, stale); err != nil {
+		log.Println("Failed to cancel old notifications:", err)
+	}
+	return delivered
+}
+
+func (d *Dispatcher) send(ctx context.Context, batch []db.GuardianNotification, now time.Time) int {
+	var ids []uint
+	attempts := 0
+	for _, notification := range batch {
+		ids = append(ids, notification.ID)
+		attempts = max(attempts, notification.Attempts+1)
+	}
+
+	err := fmt.Errorf("channel %s is not configured", batch[0].Channel)
+	if channel, exists := d.Channels[batch[0].Channel]; exists {
+		err = channel.Send(batch[0].Guardian, composeMessage(batch))
+	}
+
+	if err != nil {
+		log.Printf("Failed to notify guardian %d over %s: %v", batch[0].GuardianID, batch[0].Channel, err)
+		if markErr := db.MarkGuardianNotificationsFailed(ctx, ids, err, now.Add(retryDelay(attempts)), d.MaxAttempts); markErr != nil {
+			log.Println("Failed to record notification failure:", markErr)
+		}
+		return 0
+	}
+
+	if err := db.MarkGuardianNotificationsSent(ctx, ids, now); err != nil {
+		log.Println("Failed to record notification delivery:", err)
+	}
+	return len(batch)
+}
+
+func composeMessage(batch []db.GuardianNotification) Message {
+	var lines []string
+	var students []string
+	seen := make(map[uint]bool)
+	for _, notification := range batch {
+		student := notification.Registration.Student
+		name := strings.TrimSpace(fmt.Sprintf("%s %s", student.FirstName, student.LastName))
+		if !seen[student.ID] {
+			seen[student.ID] = true
+			students = append(students, name)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s was marked %s", notification.Date, name, notification.AttendanceStatus))
+	}
+
+	return Message{
+		Subject: fmt.Sprintf("Attendance update for %s", strings.Join(students, ", ")),
+		Body:    strings.Join(lines, "\n"),
+	}
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"skulla-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testSchool = db.WithSchool(context.Background(), 1)
+
+// setupTestDB returns a session scoped to testSchool.
+func setupTestDB(t *testing.T) *gorm.DB {
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	err = testDB.AutoMigrate(
+		&db.Student{},
+		&db.Registration{},
+		&db.Guardian{},
+		&db.GuardianNotification{},
+	)
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	db.SetDB(testDB)
+	testDB = testDB.WithContext(testSchool)
+
+	testDB.Create(&db.Student{ID: 1, FirstName: "John", LastName: "Doe"})
+	testDB.Create(&db.Registration{ID: 1, StudentID: 1, StudentClassID: 1, Status: db.ActiveRegistrationStatus})
+	return testDB
+}
+
+func queueTestNotification(t *testing.T, testDB *gorm.DB, guardian db.Guardian, date string, status string) {
+	queueTestNotificationAt(t, testDB, guardian, date, status, time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC))
+}
+
+func queueTestNotificationAt(t *testing.T, testDB *gorm.DB, guardian db.Guardian, date string, status string, queuedAt time.Time) {
+	notification := db.GuardianNotification{
+		GuardianID:       guardian.ID,
+		Channel:          db.NotificationChannelSMS,
+		RegistrationID:   1,
+		Date:             date,
+		AttendanceStatus: status,
+		DeliveryStatus:   db.DeliveryStatusPending,
+		NextAttemptAt:    queuedAt,
+		CreatedAt:        queuedAt,
+	}
+	if err := testDB.Create(&notification).Error; err != nil {
+		t.Fatalf("Failed to queue notification: %v", err)
+	}
+}
+
+func loadNotifications(testDB *gorm.DB) []db.GuardianNotification {
+	var notifications []db.GuardianNotification
+	testDB.Order("id ASC").Find(&notifications)
+	return notifications
+}
+
+func TestDispatcher_BatchesPerGuardianAndChannel(t *testing.T) {
+	testDB := setupTestDB(t)
+	guardian := db.Guardian{ID: 1, StudentID: 1, Name: "Mary Doe", Phone: "+258840000000", Channels: "SMS"}
+	testDB.Create(&guardian)
+	queueTestNotification(t, testDB, guardian, "2024-01-15", "ABSENT")
+	queueTestNotification(t, testDB, guardian, "2024-01-15", "LATE")
+
+	url, bodies := startHTTPStub(t, http.StatusOK)
+	dispatcher := &Dispatcher{
+		Channels:    map[string]Channel{db.NotificationChannelSMS: NewSMSChannel(url, "")},
+		DigestHour:  16,
+		MaxAttempts: 3,
+		Now:         func() time.Time { return time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC) },
+	}
+
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 2 {
+		t.Fatalf("Expected 2 delivered notifications, got %d", delivered)
+	}
+
+	body := <-bodies
+	if !strings.Contains(body["message"], "John Doe was marked ABSENT") || !strings.Contains(body["message"], "John Doe was marked LATE") {
+		t.Errorf("Expected both records in one message, got %q", body["message"])
+	}
+
+	for _, notification := range loadNotifications(testDB) {
+		if notification.DeliveryStatus != db.DeliveryStatusSent || notification.SentAt == nil {
+			t.Errorf("Expected notification %d to be sent, got %+v", notification.ID, notification)
+		}
+	}
+}
+
+func TestDispatcher_DailyDigestWaitsForDigestHour(t *testing.T) {
+	testDB := setupTestDB(t)
+	guardian := db.Guardian{ID: 1, StudentID: 1, Name: "Mary Doe", Phone: "+258840000000", Channels: "SMS", DailyDigest: true}
+	testDB.Create(&guardian)
+	queueTestNotification(t, testDB, guardian, "2024-01-15", "ABSENT")
+
+	url, _ := startHTTPStub(t, http.StatusOK)
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	dispatcher := &Dispatcher{
+		Channels:    map[string]Channel{db.NotificationChannelSMS: NewSMSChannel(url, "")},
+		DigestHour:  16,
+		MaxAttempts: 3,
+		Now:         func() time.Time { return now },
+	}
+
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 0 {
+		t.Errorf("Expected no delivery before the digest hour, got %d", delivered)
+	}
+
+	now = time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC)
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 1 {
+		t.Errorf("Expected 1 delivery at the digest hour, got %d", delivered)
+	}
+}
+
+func TestDispatcher_DailyDigestOncePerDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	guardian := db.Guardian{ID: 1, StudentID: 1, Name: "Mary Doe", Phone: "+258840000000", Channels: "SMS", DailyDigest: true}
+	testDB.Create(&guardian)
+	queueTestNotificationAt(t, testDB, guardian, "2024-01-15", "ABSENT", time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC))
+
+	url, bodies := startHTTPStub(t, http.StatusOK)
+	now := time.Date(2024, 1, 15, 17, 30, 0, 0, time.UTC)
+	dispatcher := &Dispatcher{
+		Channels:    map[string]Channel{db.NotificationChannelSMS: NewSMSChannel(url, "")},
+		DigestHour:  16,
+		MaxAttempts: 3,
+		Now:         func() time.Time { return now },
+	}
+
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 0 {
+		t.Errorf("Expected a notification queued after the digest hour to wait for the next digest, got %d", delivered)
+	}
+
+	queueTestNotificationAt(t, testDB, guardian, "2024-01-16", "LATE", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC))
+	now = time.Date(2024, 1, 16, 16, 0, 0, 0, time.UTC)
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 2 {
+		t.Fatalf("Expected both notifications in the next digest, got %d", delivered)
+	}
+
+	body := <-bodies
+	if !strings.Contains(body["message"], "2024-01-15: John Doe was marked ABSENT") || !strings.Contains(body["message"], "2024-01-16: John Doe was marked LATE") {
+		t.Errorf("Expected one message with both days, got %q", body["message"])
+	}
+}
+
+func TestDispatcher_RetriesThenFails(t *testing.T) {
+	testDB := setupTestDB(t)
+	guardian := db.Guardian{ID: 1, StudentID: 1, Name: "Mary Doe", Phone: "+258840000000", Channels: "SMS"}
+	testDB.Create(&guardian)
+	queueTestNotification(t, testDB, guardian, "2024-01-15", "ABSENT")
+
+	url, _ := startHTTPStub(t, http.StatusInternalServerError)
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	dispatcher := &Dispatcher{
+		Channels:    map[string]Channel{db.NotificationChannelSMS: NewSMSChannel(url, "")},
+		DigestHour:  16,
+		MaxAttempts: 2,
+		Now:         func() time.Time { return now },
+	}
+
+	dispatcher.Dispatch(testSchool)
+	notification := loadNotifications(testDB)[0]
+	if notification.DeliveryStatus != db.DeliveryStatusPending || notification.Attempts != 1 || notification.LastError == "" {
+		t.Fatalf("Expected a pending retry after the first failure, got %+v", notification)
+	}
+
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 0 || loadNotifications(testDB)[0].Attempts != 1 {
+		t.Errorf("Expected no retry before the backoff elapsed")
+	}
+
+	now = now.Add(retryDelay(1))
+	dispatcher.Dispatch(testSchool)
+	notification = loadNotifications(testDB)[0]
+	if notification.DeliveryStatus != db.DeliveryStatusFailed || notification.Attempts != 2 {
+		t.Errorf("Expected the notification to fail after 2 attempts, got %+v", notification)
+	}
+}
+
+func TestDispatcher_CancelsNotificationsQueuedTooLate(t *testing.T) {
+	testDB := setupTestDB(t)
+	guardian := db.Guardian{ID: 1, StudentID: 1, Name: "Mary Doe", Phone: "+258840000000", Channels: "SMS"}
+	testDB.Create(&guardian)
+	queueTestNotification(t, testDB, guardian, "2024-01-12", "ABSENT")
+	queueTestNotification(t, testDB, guardian, "2024-01-15", "LATE")
+
+	url, bodies := startHTTPStub(t, http.StatusOK)
+	dispatcher := &Dispatcher{
+		Channels:    map[string]Channel{db.NotificationChannelSMS: NewSMSChannel(url, "")},
+		DigestHour:  16,
+		MaxAttempts: 3,
+		MaxAgeDays:  2,
+		Now:         func() time.Time { return time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC) },
+	}
+
+	if delivered := dispatcher.Dispatch(testSchool); delivered != 1 {
+		t.Fatalf("Expected 1 delivered notification, got %d", delivered)
+	}
+	if body := <-bodies; strings.Contains(body["message"], "2024-01-12") {
+		t.Errorf("Expected the old record to be left out, got %q", body["message"])
+	}
+
+	notifications := loadNotifications(testDB)
+	if notifications[0].DeliveryStatus != db.DeliveryStatusCancelled || notifications[1].DeliveryStatus != db.DeliveryStatusSent {
+		t.Errorf("Expected the old notification to be cancelled, got %+v", notifications)
+	}
+}
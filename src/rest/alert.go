@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"errors"
+	"skulla-api/db"
+	"slices"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+func ListAlerts(c *fiber.Ctx) error {
+	studentClassID, err := ParseOptionalUintQueryParam(c, "student_class_id")
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	var acknowledged *bool
+	if acknowledgedStr := c.Query("acknowledged"); acknowledgedStr != "" {
+		parsed, err := strconv.ParseBool(acknowledgedStr)
+		if err != nil {
+			return ReturnBadRequest(c, "invalid acknowledged format. Use true or false")
+		}
+		acknowledged = &parsed
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	studentClassIDs := teacherStudentClassIDs(c.UserContext(), userEmail)
+	if studentClassID != nil {
+		if !slices.Contains(studentClassIDs, *studentClassID) {
+			return ReturnUnauthorized(c, "User does not have permission to access student class")
+		}
+		studentClassIDs = []uint{*studentClassID}
+	}
+
+	alerts := db.ListAlerts(c.UserContext(), studentClassIDs, acknowledged)
+	return c.JSON(alerts)
+}
+
+func AcknowledgeAlert(c *fiber.Ctx) error {
+	alertID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ReturnBadRequest(c, "invalid id format")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	alert, err := db.GetAlert(c.UserContext(), uint(alertID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnNotFound(c, "Alert not found")
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load alert")
+	}
+
+	if !slices.Contains(teacherStudentClassIDs(c.UserContext(), userEmail), alert.StudentClassID) {
+		return ReturnUnauthorized(c, "User does not have permission to access student class")
+	}
+
+	alert, err = db.AcknowledgeAlert(c.UserContext(), alert.ID, userEmail)
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to acknowledge alert")
+	}
+
+	return c.JSON(alert)
+}
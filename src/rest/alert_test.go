@@ -0,0 +1,227 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"skulla-api/db"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var testAlertRules = db.AlertRules{
+	WindowDays:          30,
+	MinSessions:         2,
+	MinPercentage:       90,
+	CriticalPercentage:  75,
+	ConsecutiveAbsences: 3,
+	MonthlyLates:        1,
+}
+
+func evaluateTestAlerts(t *testing.T) []db.Alert {
+	alerts, err := db.EvaluateAlerts(testSchoolContext(), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), testAlertRules)
+	if err != nil {
+		t.Fatalf("Failed to evaluate alerts: %v", err)
+	}
+	return alerts
+}
+
+func TestEvaluateAlerts_RaisesOncePerRule(t *testing.T) {
+	setupTestApp(t)
+
+	alerts := evaluateTestAlerts(t)
+	if len(alerts) != 4 {
+		t.Fatalf("Expected 4 alerts, got %d: %+v", len(alerts), alerts)
+	}
+
+	rules := make(map[string]int)
+	for _, alert := range alerts {
+		rules[alert.Rule]++
+		if alert.Rule == db.AlertRuleLowAttendance && alert.Severity != db.AlertSeverityHigh {
+			t.Errorf("Expected HIGH severity for low attendance, got %s", alert.Severity)
+		}
+	}
+	if rules[db.AlertRuleLowAttendance] != 3 || rules[db.AlertRuleMonthlyLates] != 1 {
+		t.Errorf("Unexpected rules raised: %v", rules)
+	}
+
+	if again := evaluateTestAlerts(t); len(again) != 0 {
+		t.Errorf("Expected no new alerts while existing ones are open, got %d", len(again))
+	}
+}
+
+func TestEvaluateAlerts_RaisesAgainOnlyAfterClearing(t *testing.T) {
+	app := setupTestApp(t)
+
+	for _, alert := range evaluateTestAlerts(t) {
+		if _, err := db.AcknowledgeAlert(testSchoolContext(), alert.ID, testTeacherEmail); err != nil {
+			t.Fatalf("Failed to acknowledge alert: %v", err)
+		}
+	}
+	if again := evaluateTestAlerts(t); len(again) != 0 {
+		t.Fatalf("Expected no new alerts while the conditions still hold, got %+v", again)
+	}
+
+	recordStatus := func(status string, expectedVersion int) {
+		reqBody := []map[string]interface{}{
+			{"registration_id": 2, "date": "2024-01-16", "status": status, "expected_version": expectedVersion},
+		}
+		resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	recordStatus("PRESENT", 1)
+	if again := evaluateTestAlerts(t); len(again) != 0 {
+		t.Fatalf("Expected no new alerts once the conditions cleared, got %+v", again)
+	}
+
+	recordStatus("LATE", 2)
+	again := evaluateTestAlerts(t)
+	rules := make(map[string]int)
+	for _, alert := range again {
+		if alert.RegistrationID != 2 {
+			t.Errorf("Expected alerts only for registration 2, got %+v", alert)
+		}
+		rules[alert.Rule]++
+	}
+	if len(again) != 2 || rules[db.AlertRuleLowAttendance] != 1 || rules[db.AlertRuleMonthlyLates] != 1 {
+		t.Errorf("Expected both rules to fire again for registration 2, got %+v", again)
+	}
+}
+
+func TestEvaluateAlerts_MinSessions(t *testing.T) {
+	setupTestApp(t)
+
+	rules := testAlertRules
+	rules.MinSessions = 3
+	alerts, err := db.EvaluateAlerts(testSchoolContext(), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), rules)
+	if err != nil {
+		t.Fatalf("Failed to evaluate alerts: %v", err)
+	}
+
+	var lowAttendance []uint
+	for _, alert := range alerts {
+		if alert.Rule == db.AlertRuleLowAttendance {
+			lowAttendance = append(lowAttendance, alert.RegistrationID)
+		}
+	}
+	if len(lowAttendance) != 1 || lowAttendance[0] != 1 {
+		t.Errorf("Expected low attendance only for the registration with 3 sessions, got %v", lowAttendance)
+	}
+}
+
+func TestListAlerts_Success(t *testing.T) {
+	app := setupTestApp(t)
+	evaluateTestAlerts(t)
+
+	resp, err := makeRequest(app, "GET", "/alerts?student_class_id=1", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var alerts []db.Alert
+	if err := json.Unmarshal(resp.Body.Bytes(), &alerts); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(alerts) != 3 {
+		t.Errorf("Expected 3 alerts for class 1, got %d", len(alerts))
+	}
+}
+
+func TestListAlerts_OtherTeacherSeesNone(t *testing.T) {
+	app := setupTestApp(t)
+	evaluateTestAlerts(t)
+
+	resp, err := makeRequest(app, "GET", "/alerts", testTeacherEmail2, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var alerts []db.Alert
+	if err := json.Unmarshal(resp.Body.Bytes(), &alerts); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(alerts) != 0 {
+		t.Errorf("Expected no alerts, got %d", len(alerts))
+	}
+}
+
+func TestListAlerts_Unauthorized_WrongTeacher(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/alerts?student_class_id=1", testTeacherEmail2, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.Code)
+	}
+}
+
+func TestAcknowledgeAlert_Success(t *testing.T) {
+	app := setupTestApp(t)
+	alerts := evaluateTestAlerts(t)
+
+	resp, err := makeRequest(app, "POST", fmt.Sprintf("/alerts/%d/acknowledge", alerts[0].ID), testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, err = makeRequest(app, "GET", "/alerts?acknowledged=true", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var acknowledged []db.Alert
+	if err := json.Unmarshal(resp.Body.Bytes(), &acknowledged); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(acknowledged) != 1 || acknowledged[0].AcknowledgedBy != testTeacherEmail {
+		t.Errorf("Expected one alert acknowledged by %s, got %+v", testTeacherEmail, acknowledged)
+	}
+}
+
+func TestAcknowledgeAlert_Unauthorized_WrongTeacher(t *testing.T) {
+	app := setupTestApp(t)
+	alerts := evaluateTestAlerts(t)
+
+	resp, err := makeRequest(app, "POST", fmt.Sprintf("/alerts/%d/acknowledge", alerts[0].ID), testTeacherEmail2, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.Code)
+	}
+}
+
+func TestAcknowledgeAlert_NotFound(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "POST", "/alerts/9999/acknowledge", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
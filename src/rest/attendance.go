@@ -1,18 +1,24 @@
 package rest
 
 import (
+	"errors"
 	"fmt"
 	"skulla-api/db"
+	"skulla-api/metrics"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 )
 
 type RecordAttendanceRequest struct {
-	RegistrationID uint   `json:"registration_id"`
-	Date           string `json:"date"`
-	Status         string `json:"status"`
-	Remarks        string `json:"remarks"`
+	RegistrationID  uint   `json:"registration_id"`
+	Date            string `json:"date"`
+	Status          string `json:"status"`
+	Remarks         string `json:"remarks"`
+	ExpectedVersion *int   `json:"expected_version"`
+	Draft           bool   `json:"draft"`
 }
 
 var validStatuses = map[string]bool{
@@ -35,47 +41,148 @@ func validateAttendanceRecord(req RecordAttendanceRequest, index int) error {
 		return fmt.Errorf("record %d: status must be one of: PRESENT, ABSENT, LATE, EXCUSED", index)
 	}
 
-	if !db.RegistrationExists(req.RegistrationID) {
-		return fmt.Errorf("record %d: registration not found", index)
+	if req.ExpectedVersion != nil && *req.ExpectedVersion < 0 {
+		return fmt.Errorf("record %d: expected_version cannot be negative", index)
 	}
 
 	return nil
 }
 
+// attendanceETag formats a record version as a strong entity tag.
+func attendanceETag(version int) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// parseIfMatch reads the version from an If-Match header holding an ETag
+// returned by GetAttendance. It returns nil when the header is absent.
+func parseIfMatch(c *fiber.Ctx) (*int, error) {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("If-Match must be an ETag returned by GET /attendance")
+	if len(header) < 3 || !strings.HasPrefix(header, "\"") || !strings.HasSuffix(header, "\"") {
+		return nil, invalid
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version < 0 {
+		return nil, invalid
+	}
+	return &version, nil
+}
+
+// returnVersionConflict answers a write that expected another version with
+// 409 and the stored record.
+func returnVersionConflict(c *fiber.Ctx, conflict *db.VersionConflictError, index *int) error {
+	body := fiber.Map{
+		"error":   "Attendance record was modified by someone else",
+		"current": conflict.Current,
+	}
+	if index != nil {
+		body["index"] = *index
+	}
+	return c.Status(fiber.StatusConflict).JSON(body)
+}
+
+// GetAttendance returns one attendance record with its version as ETag.
+func GetAttendance(c *fiber.Ctx) error {
+	registrationID, err := ParseUintQueryParam(c, "registration_id", true)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	date := c.Query("date")
+	if err := ValidateDateString(date, "date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	state, err := db.GetAttendanceState(c.UserContext(), registrationID, date)
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load attendance")
+	}
+	if state == nil {
+		return ReturnNotFound(c, "Attendance not found")
+	}
+
+	etag := attendanceETag(state.Version)
+	c.Set(fiber.HeaderETag, etag)
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(state)
+}
+
 func RecordAttendance(c *fiber.Ctx) error {
 	var req RecordAttendanceRequest
 	if err := c.BodyParser(&req); err != nil {
 		return ReturnBadRequest(c, "Invalid request body")
 	}
 
-	if err := validateAttendanceRecord(req, 0); err != nil {
-		if err.Error() == "record 0: registration not found" {
-			return ReturnNotFound(c, "registration not found")
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+	if ifMatch != nil {
+		if req.ExpectedVersion != nil && *req.ExpectedVersion != *ifMatch {
+			return ReturnBadRequest(c, "If-Match and expected_version disagree")
 		}
+		req.ExpectedVersion = ifMatch
+	}
+
+	if err := validateAttendanceRecord(req, 0); err != nil {
 		return ReturnBadRequest(c, err.Error())
 	}
 
+	if !db.RegistrationExists(c.UserContext(), req.RegistrationID) {
+		return ReturnNotFound(c, "registration not found")
+	}
+
 	userEmail, err := GetUserEmailFromToken(c)
 	if err != nil {
 		return ReturnUnauthorized(c, "Unable to extract user email from token")
 	}
 
-	err = db.CreateOrUpdateAttendance(req.RegistrationID, req.Date, req.Status, req.Remarks, userEmail)
+	err = db.CreateOrUpdateBulkAttendance(c.UserContext(), []db.BulkAttendanceRecord{bulkAttendanceRecord(req, userEmail)})
+	var conflict *db.VersionConflictError
+	if errors.As(err, &conflict) {
+		return returnVersionConflict(c, conflict, nil)
+	}
+	if errors.Is(err, db.ErrRegisterLocked) {
+		return ReturnConflict(c, err.Error())
+	}
 	if err != nil {
 		log.Error(err)
 		return ReturnInternalError(c, "Failed to record attendance")
 	}
 
+	state, err := db.GetAttendanceState(c.UserContext(), req.RegistrationID, req.Date)
+	if err != nil || state == nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load recorded attendance")
+	}
+
+	c.Set(fiber.HeaderETag, attendanceETag(state.Version))
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message":         "Attendance recorded successfully",
 		"registration_id": req.RegistrationID,
 		"date":            req.Date,
 		"status":          req.Status,
 		"remarks":         req.Remarks,
+		"version":         state.Version,
 	})
 }
 
+const bulkModePartial = "partial"
+
+// RecordBulkAttendance writes all records or none, or with mode=partial the
+// valid ones.
 func RecordBulkAttendance(c *fiber.Ctx) error {
+	mode := c.Query("mode")
+	if mode != "" && mode != bulkModePartial {
+		return ReturnBadRequest(c, "mode must be partial when set")
+	}
+
 	var requests []RecordAttendanceRequest
 	if err := c.BodyParser(&requests); err != nil {
 		return ReturnBadRequest(c, "Invalid request body. Expected JSON array of attendance records")
@@ -84,30 +191,54 @@ func RecordBulkAttendance(c *fiber.Ctx) error {
 	if len(requests) == 0 {
 		return ReturnBadRequest(c, "At least one attendance record is required")
 	}
+	metrics.BatchSize.WithLabelValues("attendance_bulk").Observe(float64(len(requests)))
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnUnauthorized(c, "Unable to extract user email from token")
+	}
 
+	validationErrors := make([]error, len(requests))
+	var registrationIDs []uint
 	for i, req := range requests {
-		if err := validateAttendanceRecord(req, i); err != nil {
-			return ReturnBadRequest(c, err.Error())
-		}
+		validationErrors[i] = validateAttendanceRecord(req, i)
+		registrationIDs = append(registrationIDs, req.RegistrationID)
 	}
 
-	userEmail, err := GetUserEmailFromToken(c)
+	registrations, err := db.ExistingRegistrationIDs(c.UserContext(), registrationIDs)
 	if err != nil {
-		return ReturnUnauthorized(c, "Unable to extract user email from token")
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to validate registrations")
+	}
+	for i, req := range requests {
+		if validationErrors[i] == nil && !registrations[req.RegistrationID] {
+			validationErrors[i] = fmt.Errorf("record %d: registration not found", i)
+		}
+	}
+
+	if mode == bulkModePartial {
+		return recordPartialBulkAttendance(c, requests, validationErrors, userEmail)
+	}
+
+	for _, err := range validationErrors {
+		if err != nil {
+			return ReturnBadRequest(c, err.Error())
+		}
 	}
 
 	var bulkRecords []db.BulkAttendanceRecord
 	for _, req := range requests {
-		bulkRecords = append(bulkRecords, db.BulkAttendanceRecord{
-			RegistrationID: req.RegistrationID,
-			Date:           req.Date,
-			Status:         req.Status,
-			Remarks:        req.Remarks,
-			UserEmail:      userEmail,
-		})
+		bulkRecords = append(bulkRecords, bulkAttendanceRecord(req, userEmail))
 	}
 
-	err = db.CreateOrUpdateBulkAttendance(bulkRecords)
+	err = db.CreateOrUpdateBulkAttendance(c.UserContext(), bulkRecords)
+	var conflict *db.VersionConflictError
+	if errors.As(err, &conflict) {
+		return returnVersionConflict(c, conflict, &conflict.Index)
+	}
+	if errors.Is(err, db.ErrRegisterLocked) {
+		return ReturnConflict(c, err.Error())
+	}
 	if err != nil {
 		log.Error(err)
 		return ReturnInternalError(c, "Failed to record bulk attendance. All records have been rolled back.")
@@ -119,6 +250,65 @@ func RecordBulkAttendance(c *fiber.Ctx) error {
 	})
 }
 
+func bulkAttendanceRecord(req RecordAttendanceRequest, userEmail string) db.BulkAttendanceRecord {
+	return db.BulkAttendanceRecord{
+		RegistrationID:  req.RegistrationID,
+		Date:            req.Date,
+		Status:          req.Status,
+		Remarks:         req.Remarks,
+		UserEmail:       userEmail,
+		ExpectedVersion: req.ExpectedVersion,
+		Draft:           req.Draft,
+	}
+}
+
+// recordPartialBulkAttendance writes the records that passed validation and
+// reports every record, in request order.
+func recordPartialBulkAttendance(c *fiber.Ctx, requests []RecordAttendanceRequest, validationErrors []error, userEmail string) error {
+	results := make([]db.BulkAttendanceResult, len(requests))
+	var bulkRecords []db.BulkAttendanceRecord
+	var indexes []int
+	for i, req := range requests {
+		if validationErrors[i] != nil {
+			results[i] = db.BulkAttendanceResult{
+				Index:          i,
+				RegistrationID: req.RegistrationID,
+				Date:           req.Date,
+				Result:         db.BulkResultError,
+				Error:          validationErrors[i].Error(),
+			}
+			continue
+		}
+		bulkRecords = append(bulkRecords, bulkAttendanceRecord(req, userEmail))
+		indexes = append(indexes, i)
+	}
+
+	if len(bulkRecords) > 0 {
+		recorded, err := db.RecordPartialBulkAttendance(c.UserContext(), bulkRecords)
+		if err != nil {
+			log.Error(err)
+			return ReturnInternalError(c, "Failed to record bulk attendance. All records have been rolled back.")
+		}
+		for i, result := range recorded {
+			result.Index = indexes[i]
+			results[indexes[i]] = result
+		}
+	}
+
+	processed := 0
+	for _, result := range results {
+		if result.Result != db.BulkResultError {
+			processed++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message":           "Bulk attendance processed",
+		"records_processed": processed,
+		"results":           results,
+	})
+}
+
 func GetStudentAttendanceReport(c *fiber.Ctx) error {
 	studentID, err := ParseUintQueryParam(c, "student_id", true)
 	if err != nil {
@@ -130,6 +320,11 @@ func GetStudentAttendanceReport(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, err.Error())
 	}
 
+	includeDrafts, err := ParseBoolQueryParam(c, "include_drafts")
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 	startDate, endDate = GetDateRangeWithDefaults(startDate, endDate)
@@ -142,12 +337,16 @@ func GetStudentAttendanceReport(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, err.Error())
 	}
 
+	if err := ValidateReportRange(startDate, endDate); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
 	if studentClassID != nil {
-		report := db.GetDetailedStudentAttendanceReport(studentID, startDate, endDate, studentClassID)
+		report := db.GetDetailedStudentAttendanceReport(c.UserContext(), studentID, startDate, endDate, studentClassID, includeDrafts)
 		return c.JSON(report)
 	}
 
-	aggregatedReport := db.GetAggregatedStudentAttendanceReport(studentID, startDate, endDate)
+	aggregatedReport := db.GetAggregatedStudentAttendanceReport(c.UserContext(), studentID, startDate, endDate, includeDrafts)
 	return c.JSON(aggregatedReport)
 }
 
@@ -157,6 +356,11 @@ func GetClassAttendanceReport(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, err.Error())
 	}
 
+	includeDrafts, err := ParseBoolQueryParam(c, "include_drafts")
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 	startDate, endDate = GetDateRangeWithDefaults(startDate, endDate)
@@ -169,6 +373,10 @@ func GetClassAttendanceReport(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, err.Error())
 	}
 
+	if err := ValidateReportRange(startDate, endDate); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
 	period := c.Query("period")
 	if period == "" {
 		period = "all"
@@ -185,7 +393,71 @@ func GetClassAttendanceReport(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, "period must be one of: day, week, month, all")
 	}
 
-	report := db.GetClassAttendanceReport(studentClassID, startDate, endDate, period)
+	report := db.GetClassAttendanceReport(c.UserContext(), studentClassID, startDate, endDate, period, includeDrafts)
+
+	return c.JSON(report)
+}
+
+func GetCourseAttendanceReport(c *fiber.Ctx) error {
+	courseID, err := ParseUintQueryParam(c, "course_id", true)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	includeDrafts, err := ParseBoolQueryParam(c, "include_drafts")
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	startDate, endDate = GetDateRangeWithDefaults(startDate, endDate)
+
+	if err := ValidateDateString(startDate, "start_date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if err := ValidateDateString(endDate, "end_date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if err := ValidateReportRange(startDate, endDate); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	course, err := db.GetCourse(c.UserContext(), courseID)
+	if err != nil {
+		return ReturnNotFound(c, "Course not found")
+	}
+
+	report := db.GetCourseAttendanceReport(c.UserContext(), course, startDate, endDate, includeDrafts)
+
+	return c.JSON(report)
+}
+
+func GetSchoolAttendanceReport(c *fiber.Ctx) error {
+	includeDrafts, err := ParseBoolQueryParam(c, "include_drafts")
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	startDate, endDate = GetDateRangeWithDefaults(startDate, endDate)
+
+	if err := ValidateDateString(startDate, "start_date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if err := ValidateDateString(endDate, "end_date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if err := ValidateReportRange(startDate, endDate); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	report := db.GetSchoolAttendanceReport(c.UserContext(), startDate, endDate, includeDrafts)
 
 	return c.JSON(report)
 }
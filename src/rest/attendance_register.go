@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"errors"
+	"skulla-api/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+// teacherRegisterParams parses the :id and :date parameters of a register
+// route and checks that the caller teaches the class.
+func teacherRegisterParams(c *fiber.Ctx) (uint, string, string, error) {
+	studentClassID, err := teacherStudentClassParam(c)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	date := c.Params("date")
+	if err := ValidateDateString(date, "date"); err != nil {
+		return 0, "", "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return 0, "", "", fiber.NewError(fiber.StatusUnauthorized, "Unable to extract user email from token")
+	}
+
+	return studentClassID, date, userEmail, nil
+}
+
+func ListAttendanceRegisters(c *fiber.Ctx) error {
+	studentClassID, err := teacherStudentClassParam(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	startDate, endDate := GetDateRangeWithDefaults(c.Query("start_date"), c.Query("end_date"))
+
+	if err := ValidateDateString(startDate, "start_date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if err := ValidateDateString(endDate, "end_date"); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	return c.JSON(db.ListAttendanceRegisters(c.UserContext(), studentClassID, startDate, endDate))
+}
+
+// SubmitRegisterRequest is the optional body of a submit.
+type SubmitRegisterRequest struct {
+	MissingStatus string `json:"missing_status"`
+}
+
+// SubmitAttendanceRegister makes a draft register count in reports and sends
+// the guardian notifications held back while it was a draft.
+func SubmitAttendanceRegister(c *fiber.Ctx) error {
+	studentClassID, date, userEmail, err := teacherRegisterParams(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	var req SubmitRegisterRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return ReturnBadRequest(c, "Invalid request body")
+		}
+	}
+	if req.MissingStatus != "" && !validStatuses[req.MissingStatus] {
+		return ReturnBadRequest(c, "missing_status must be one of: PRESENT, ABSENT, LATE, EXCUSED")
+	}
+
+	register, err := db.SubmitAttendanceRegister(c.UserContext(), studentClassID, date, userEmail, req.MissingStatus)
+	var incomplete *db.IncompleteRegisterError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ReturnNotFound(c, "No attendance was recorded for this class on this date")
+	case errors.As(err, &incomplete):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "Every active registration needs attendance before the register is submitted",
+			"missing": incomplete.Missing,
+		})
+	case errors.Is(err, db.ErrRegisterNotDraft):
+		return ReturnConflict(c, err.Error())
+	case err != nil:
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to submit attendance register")
+	}
+
+	return c.JSON(register)
+}
+
+// LockAttendanceRegister stops further attendance writes to a submitted
+// register.
+func LockAttendanceRegister(c *fiber.Ctx) error {
+	studentClassID, date, userEmail, err := teacherRegisterParams(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	register, err := db.LockAttendanceRegister(c.UserContext(), studentClassID, date, userEmail)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ReturnNotFound(c, "No attendance was recorded for this class on this date")
+	case errors.Is(err, db.ErrRegisterNotSubmitted), errors.Is(err, db.ErrRegisterLocked):
+		return ReturnConflict(c, err.Error())
+	case err != nil:
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to lock attendance register")
+	}
+
+	return c.JSON(register)
+}
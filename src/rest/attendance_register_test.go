@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"encoding/json"
+	"skulla-api/db"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func classReportDay(t *testing.T, app *fiber.App, query string) []db.DailyAttendance {
+	resp, err := makeRequest(app, "GET", "/attendance/class-report?student_class_id=1&start_date=2024-02-01&end_date=2024-02-01&period=day"+query, testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var report db.ClassAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return report.DailyData
+}
+
+func TestAttendanceRegister_DraftUntilSubmitted(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT", "draft": true},
+		{"registration_id": 2, "date": "2024-02-01", "status": "ABSENT", "draft": true},
+	}
+	resp, _ := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if daily := classReportDay(t, app, ""); len(daily) != 0 {
+		t.Errorf("Expected draft register to be left out of reports, got %+v", daily)
+	}
+	if daily := classReportDay(t, app, "&include_drafts=true"); len(daily) != 1 || daily[0].AbsentCount != 1 {
+		t.Errorf("Expected draft register in the preview, got %+v", daily)
+	}
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusConflict {
+		t.Fatalf("Expected status 409 for an incomplete register, got %d", resp.Code)
+	}
+	var incomplete struct {
+		Missing []uint `json:"missing"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &incomplete)
+	if len(incomplete.Missing) != 1 || incomplete.Missing[0] != 3 {
+		t.Errorf("Expected registration 3 to be missing, got %v", incomplete.Missing)
+	}
+
+	reqBody = []map[string]interface{}{{"registration_id": 3, "date": "2024-02-01", "status": "LATE"}}
+	makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+	var register db.AttendanceRegister
+	json.Unmarshal(resp.Body.Bytes(), &register)
+	if register.Status != db.RegisterSubmitted || register.SubmittedBy != testTeacherEmail || register.SubmittedAt == nil {
+		t.Errorf("Unexpected register: %+v", register)
+	}
+
+	if daily := classReportDay(t, app, ""); len(daily) != 1 || daily[0].LateCount != 1 {
+		t.Errorf("Expected submitted register in reports, got %+v", daily)
+	}
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusConflict {
+		t.Errorf("Expected status 409 for a second submit, got %d", resp.Code)
+	}
+}
+
+func TestAttendanceRegister_Lock(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := map[string]interface{}{
+		"student_class_id": 1,
+		"date":             "2024-02-01",
+		"status":           "PRESENT",
+		"draft":            true,
+	}
+	resp, _ := makeRequest(app, "POST", "/attendance/class", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/lock", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusConflict {
+		t.Errorf("Expected status 409 when locking a draft, got %d", resp.Code)
+	}
+
+	makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, nil)
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/lock", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	write := map[string]interface{}{"registration_id": 1, "date": "2024-02-01", "status": "ABSENT"}
+	resp, _ = makeRequest(app, "POST", "/attendance", testTeacherEmail, write)
+	if resp.Code != fiber.StatusConflict {
+		t.Errorf("Expected status 409 for a locked register, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequest(app, "POST", "/attendance/bulk?mode=partial", testTeacherEmail, []interface{}{write})
+	var partial struct {
+		Results []db.BulkAttendanceResult `json:"results"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &partial)
+	if len(partial.Results) != 1 || partial.Results[0].Result != db.BulkResultError {
+		t.Errorf("Expected partial write to a locked register to fail, got %+v", partial.Results)
+	}
+
+	sync := syncAs(t, app, testTeacherEmail, "", []map[string]interface{}{syncMutation("locked-1", 0, 1, 1, "2024-02-01", "ABSENT")})
+	if len(sync.Results) != 1 || sync.Results[0].Outcome != db.SyncRejected {
+		t.Errorf("Expected sync to a locked register to be rejected, got %+v", sync.Results)
+	}
+
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 1, "2024-02-01"); state.Status != "PRESENT" || state.Version != 1 {
+		t.Errorf("Expected locked attendance to be unchanged, got %+v", state)
+	}
+}
+
+func TestAttendanceRegister_SubmittedUnlessDraftRequested(t *testing.T) {
+	app := setupTestApp(t)
+
+	write := map[string]interface{}{"registration_id": 1, "date": "2024-02-01", "status": "ABSENT"}
+	resp, _ := makeRequest(app, "POST", "/attendance", testTeacherEmail, write)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if daily := classReportDay(t, app, ""); len(daily) != 1 || daily[0].AbsentCount != 1 {
+		t.Errorf("Expected a write without draft to count in reports, got %+v", daily)
+	}
+
+	// A later draft write does not take the register back into a draft.
+	write = map[string]interface{}{"registration_id": 2, "date": "2024-02-01", "status": "PRESENT", "draft": true}
+	makeRequest(app, "POST", "/attendance", testTeacherEmail, write)
+	if daily := classReportDay(t, app, ""); len(daily) != 1 || daily[0].PresentCount != 1 {
+		t.Errorf("Expected the register to stay submitted, got %+v", daily)
+	}
+}
+
+func TestAttendanceRegister_SubmitMarksMissing(t *testing.T) {
+	app := setupTestApp(t)
+
+	write := map[string]interface{}{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT", "draft": true}
+	resp, _ := makeRequest(app, "POST", "/attendance", testTeacherEmail, write)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, map[string]interface{}{"missing_status": "GONE"})
+	if resp.Code != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid missing_status, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, map[string]interface{}{"missing_status": "ABSENT"})
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if daily := classReportDay(t, app, ""); len(daily) != 1 || daily[0].PresentCount != 1 || daily[0].AbsentCount != 2 {
+		t.Errorf("Expected the unmarked registrations to be recorded ABSENT, got %+v", daily)
+	}
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 3, "2024-02-01"); state == nil || state.Status != "ABSENT" {
+		t.Errorf("Expected registration 3 to be ABSENT, got %+v", state)
+	}
+}
+
+func TestAttendanceRegister_ExistingAttendanceCountsAsSubmitted(t *testing.T) {
+	app := setupTestApp(t)
+
+	write := map[string]interface{}{"registration_id": 1, "date": "2024-01-15", "status": "LATE", "draft": true}
+	resp, _ := makeRequest(app, "POST", "/attendance", testTeacherEmail, write)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, _ = makeRequest(app, "GET", "/student-classes/1/registers?start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil)
+	var registers []db.AttendanceRegister
+	json.Unmarshal(resp.Body.Bytes(), &registers)
+	if len(registers) != 1 || registers[0].Date != "2024-01-15" || registers[0].Status != db.RegisterSubmitted {
+		t.Errorf("Expected a submitted register for 2024-01-15, got %+v", registers)
+	}
+}
+
+func TestAttendanceRegister_Errors(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		name     string
+		email    string
+		path     string
+		expected int
+	}{
+		{"nothing recorded", testTeacherEmail, "/student-classes/1/registers/2024-03-01/submit", fiber.StatusNotFound},
+		{"invalid date", testTeacherEmail, "/student-classes/1/registers/01-03-2024/submit", fiber.StatusBadRequest},
+		{"other teacher", testTeacherEmail2, "/student-classes/1/registers/2024-01-15/lock", fiber.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := makeRequest(app, "POST", tc.path, tc.email, nil)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tc.expected, resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
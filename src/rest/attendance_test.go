@@ -310,6 +310,34 @@ func TestGetStudentAttendanceReport_InvalidDateFormat(t *testing.T) {
 	}
 }
 
+func TestAttendanceReports_RangeTooLong(t *testing.T) {
+	app := setupTestApp(t)
+
+	paths := []string{
+		"/attendance/report?student_id=1",
+		"/attendance/class-report?student_class_id=1",
+		"/attendance/course-report?course_id=1",
+		"/attendance/school-report?include_drafts=false",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			resp, err := makeRequest(app, "GET", path+"&start_date=2024-01-01&end_date=2025-01-01", testTeacherEmail, nil)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != fiber.StatusBadRequest {
+				t.Errorf("Expected status 400 for 367 days, got %d", resp.Code)
+			}
+
+			resp, _ = makeRequest(app, "GET", path+"&start_date=2024-01-01&end_date=2024-12-31", testTeacherEmail, nil)
+			if resp.Code != fiber.StatusOK {
+				t.Errorf("Expected status 200 for 366 days, got %d. Body: %s", resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
+
 func TestGetClassAttendanceReport_Success(t *testing.T) {
 	app := setupTestApp(t)
 
@@ -444,3 +472,648 @@ func TestGetClassAttendanceReport_InvalidStudentClassId(t *testing.T) {
 		t.Errorf("Expected status 400, got %d", resp.Code)
 	}
 }
+
+func TestGetStudentAttendanceReport_TrendsSortedAndGapFilled(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/report?student_id=1&student_class_id=1&start_date=2023-12-01&end_date=2024-02-29", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var report db.DetailedAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	expectedMonths := []string{"2023-12", "2024-01", "2024-02"}
+	if len(report.MonthlyTrends) != len(expectedMonths) {
+		t.Fatalf("Expected %d monthly trends, got %d", len(expectedMonths), len(report.MonthlyTrends))
+	}
+	for i, month := range expectedMonths {
+		if report.MonthlyTrends[i].Month != month {
+			t.Errorf("Expected month %s at index %d, got %s", month, i, report.MonthlyTrends[i].Month)
+		}
+	}
+	if report.MonthlyTrends[0].TotalDays != 0 || report.MonthlyTrends[1].TotalDays != 3 {
+		t.Errorf("Unexpected monthly totals: %+v", report.MonthlyTrends)
+	}
+
+	if len(report.WeeklyTrends) != 14 {
+		t.Errorf("Expected 14 weekly trends, got %d", len(report.WeeklyTrends))
+	}
+	for i := 1; i < len(report.WeeklyTrends); i++ {
+		if report.WeeklyTrends[i-1].Week >= report.WeeklyTrends[i].Week {
+			t.Errorf("Weekly trends not sorted: %s before %s", report.WeeklyTrends[i-1].Week, report.WeeklyTrends[i].Week)
+		}
+	}
+}
+
+func TestGetClassAttendanceReport_StudentSummariesSortedByName(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/class-report?student_class_id=1&start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var report db.ClassAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	expectedNames := []string{"Bob Johnson", "Jane Smith", "John Doe"}
+	if len(report.StudentSummaries) != len(expectedNames) {
+		t.Fatalf("Expected %d student summaries, got %d", len(expectedNames), len(report.StudentSummaries))
+	}
+	for i, name := range expectedNames {
+		if report.StudentSummaries[i].StudentName != name {
+			t.Errorf("Expected %s at index %d, got %s", name, i, report.StudentSummaries[i].StudentName)
+		}
+	}
+}
+
+func TestGetClassAttendanceReport_Counts(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/class-report?student_class_id=1&start_date=2024-01-01&end_date=2024-01-31&period=day", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var report db.ClassAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	summary := report.OverallSummary
+	if summary.TotalDays != 5 || summary.PresentCount != 3 || summary.AbsentCount != 1 || summary.LateCount != 1 {
+		t.Errorf("Unexpected overall summary: %+v", summary)
+	}
+
+	for _, studentSummary := range report.StudentSummaries {
+		if studentSummary.StudentName == "John Doe" && (studentSummary.TotalDays != 3 || studentSummary.PresentCount != 2) {
+			t.Errorf("Unexpected summary for John Doe: %+v", studentSummary)
+		}
+	}
+
+	if len(report.DailyData) != 3 {
+		t.Fatalf("Expected 3 days of data, got %d", len(report.DailyData))
+	}
+	if report.DailyData[0].PresentCount != 2 {
+		t.Errorf("Expected 2 present on first day, got %d", report.DailyData[0].PresentCount)
+	}
+}
+
+func TestGetStudentAttendanceReport_WithoutClassId_ByClassCounts(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/report?student_id=1&start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var report db.AggregatedStudentAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(report.ByClass) != 2 {
+		t.Fatalf("Expected 2 classes, got %d", len(report.ByClass))
+	}
+
+	math := report.ByClass[0]
+	if math.StudentClassName != "Math 101" || math.Summary.TotalDays != 3 || len(math.Records) != 3 {
+		t.Errorf("Unexpected Math 101 report: %+v", math)
+	}
+
+	physics := report.ByClass[1]
+	if physics.StudentClassName != "Physics 101" || physics.Summary.ExcusedCount != 1 || len(physics.Records) != 2 {
+		t.Errorf("Unexpected Physics 101 report: %+v", physics)
+	}
+}
+
+func TestRecordAttendance_RefreshesDailySummary(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := map[string]interface{}{
+		"registration_id": 1,
+		"date":            "2024-01-15",
+		"status":          "ABSENT",
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, err = makeRequest(app, "GET", "/attendance/class-report?student_class_id=1&start_date=2024-01-15&end_date=2024-01-15&period=day", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var report db.ClassAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(report.DailyData) != 1 {
+		t.Fatalf("Expected 1 day of data, got %d", len(report.DailyData))
+	}
+
+	daily := report.DailyData[0]
+	if daily.Date != "2024-01-15" || daily.PresentCount != 1 || daily.AbsentCount != 1 {
+		t.Errorf("Unexpected daily summary: %+v", daily)
+	}
+}
+
+func TestRecordAttendance_SummaryFollowsStatusChanges(t *testing.T) {
+	app := setupTestApp(t)
+
+	// The second record of registration 1 replaces the first one.
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT"},
+		{"registration_id": 1, "date": "2024-02-01", "status": "LATE"},
+		{"registration_id": 2, "date": "2024-02-01", "status": "ABSENT"},
+	}
+	resp, _ := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	write := map[string]interface{}{"registration_id": 2, "date": "2024-02-01", "status": "EXCUSED"}
+	resp, _ = makeRequest(app, "POST", "/attendance", testTeacherEmail, write)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	daily := classReportDay(t, app, "")
+	if len(daily) != 1 || daily[0].PresentCount != 0 || daily[0].LateCount != 1 || daily[0].AbsentCount != 0 || daily[0].ExcusedCount != 1 {
+		t.Errorf("Unexpected daily summary: %+v", daily)
+	}
+
+	if err := db.RebuildDailyClassAttendanceSummaries(testSchoolContext()); err != nil {
+		t.Fatalf("Failed to rebuild summaries: %v", err)
+	}
+	if rebuilt := classReportDay(t, app, ""); len(rebuilt) != 1 || rebuilt[0] != daily[0] {
+		t.Errorf("Expected the summary to match a rebuild, got %+v and %+v", daily, rebuilt)
+	}
+}
+
+func TestGetCourseAttendanceReport_Success(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/course-report?course_id=1&start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var report db.CourseAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if report.CourseName != "Mathematics" {
+		t.Errorf("Expected course 'Mathematics', got %s", report.CourseName)
+	}
+
+	if report.OverallSummary.TotalDays != 5 || report.OverallSummary.PresentCount != 3 {
+		t.Errorf("Unexpected overall summary: %+v", report.OverallSummary)
+	}
+
+	if len(report.Classes) != 2 {
+		t.Fatalf("Expected 2 classes, got %d", len(report.Classes))
+	}
+
+	if report.Classes[0].StudentClassName != "Math 101" || report.Classes[0].Rank != 1 {
+		t.Errorf("Expected Math 101 ranked first, got %+v", report.Classes[0])
+	}
+
+	if report.PreviousStartDate != "2023-12-01" || report.PreviousEndDate != "2023-12-31" {
+		t.Errorf("Unexpected previous period: %s to %s", report.PreviousStartDate, report.PreviousEndDate)
+	}
+}
+
+func TestGetCourseAttendanceReport_CourseNotFound(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/course-report?course_id=9999", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestGetCourseAttendanceReport_MissingCourseId(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/course-report", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.Code)
+	}
+}
+
+func TestGetSchoolAttendanceReport_RanksClasses(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/school-report?start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var report db.SchoolAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if report.TotalClasses != 2 || len(report.Classes) != 2 {
+		t.Fatalf("Expected 2 classes, got %d", len(report.Classes))
+	}
+
+	first, second := report.Classes[0], report.Classes[1]
+	if first.StudentClassName != "Math 101" || first.Summary.Percentage != 60 {
+		t.Errorf("Unexpected first class: %+v", first)
+	}
+	if second.StudentClassName != "Physics 101" || second.Summary.Percentage != 50 || second.Rank != 2 {
+		t.Errorf("Unexpected second class: %+v", second)
+	}
+	if first.PercentageDelta != 60 {
+		t.Errorf("Expected delta 60 against an empty previous period, got %v", first.PercentageDelta)
+	}
+}
+
+func TestGetStudentAttendanceReport_AbsencePatterns(t *testing.T) {
+	app := setupTestApp(t)
+
+	// 2024-01-18 has no attendance in the student's classes, so it counts as a holiday.
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-01-19", "status": "ABSENT"},
+		{"registration_id": 1, "date": "2024-01-22", "status": "ABSENT"},
+		{"registration_id": 2, "date": "2024-01-23", "status": "PRESENT"},
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, err = makeRequest(app, "GET", "/attendance/report?student_id=1&student_class_id=1&start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var report db.DetailedAttendanceReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	patterns := report.Patterns
+	if patterns.LongestAbsenceStreak != 2 || patterns.CurrentAbsenceStreak != 2 {
+		t.Errorf("Expected longest and current streak of 2, got %d and %d", patterns.LongestAbsenceStreak, patterns.CurrentAbsenceStreak)
+	}
+
+	if patterns.FirstAbsenceDate != "2024-01-16" || patterns.LastAbsenceDate != "2024-01-22" {
+		t.Errorf("Unexpected absence dates: %s to %s", patterns.FirstAbsenceDate, patterns.LastAbsenceDate)
+	}
+
+	if patterns.AbsencesAdjacentToWeekends != 2 {
+		t.Errorf("Expected 2 absences adjacent to weekends, got %d", patterns.AbsencesAdjacentToWeekends)
+	}
+
+	if patterns.AbsencesAdjacentToHolidays != 1 {
+		t.Errorf("Expected 1 absence adjacent to holidays, got %d", patterns.AbsencesAdjacentToHolidays)
+	}
+
+	if len(patterns.AbsencesByWeekday) != 7 || patterns.AbsencesByWeekday[0].Weekday != "Monday" || patterns.AbsencesByWeekday[0].Count != 1 {
+		t.Errorf("Unexpected weekday distribution: %+v", patterns.AbsencesByWeekday)
+	}
+}
+
+func TestGetStudentAttendanceReport_HolidaysIgnoreDraftRegisters(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-01-19", "status": "ABSENT"},
+		{"registration_id": 3, "date": "2024-01-18", "status": "PRESENT", "draft": true},
+	}
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	// 2024-01-18 only has a draft register, so it is a holiday unless drafts
+	// are included.
+	for query, holidays := range map[string]int{"": 1, "&include_drafts=true": 0} {
+		resp, err := makeRequest(app, "GET", "/attendance/report?student_id=1&student_class_id=1&start_date=2024-01-01&end_date=2024-01-31"+query, testTeacherEmail, nil)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		var report db.DetailedAttendanceReport
+		if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if report.Patterns.AbsencesAdjacentToHolidays != holidays {
+			t.Errorf("%q: expected %d absences adjacent to holidays, got %d", query, holidays, report.Patterns.AbsencesAdjacentToHolidays)
+		}
+	}
+}
+
+func TestGetAttendance_ETag(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance?registration_id=1&date=2024-01-15", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.Code)
+	}
+	if etag := resp.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("Expected ETag \"1\", got %s", etag)
+	}
+
+	var state db.AttendanceState
+	json.Unmarshal(resp.Body.Bytes(), &state)
+	if state.Status != "PRESENT" || state.Version != 1 || state.StudentClassID != 1 {
+		t.Errorf("Unexpected attendance: %+v", state)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "GET", "/attendance?registration_id=1&date=2024-01-15", testTeacherEmail, nil, map[string]string{"If-None-Match": `"1"`})
+	if resp.Code != fiber.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequest(app, "GET", "/attendance?registration_id=1&date=2024-02-01", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestRecordAttendance_IfMatch(t *testing.T) {
+	app := setupTestApp(t)
+	reqBody := map[string]interface{}{"registration_id": 1, "date": "2024-01-15", "status": "LATE"}
+
+	resp, err := makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, map[string]string{"If-Match": `"1"`})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+	if etag := resp.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("Expected ETag \"2\", got %s", etag)
+	}
+
+	reqBody["status"] = "ABSENT"
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, map[string]string{"If-Match": `"1"`})
+	if resp.Code != fiber.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", resp.Code)
+	}
+
+	var conflict struct {
+		Current db.AttendanceState `json:"current"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &conflict)
+	if conflict.Current.Status != "LATE" || conflict.Current.Version != 2 {
+		t.Errorf("Expected conflict to return the stored record, got %+v", conflict.Current)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, map[string]string{"If-Match": "1"})
+	if resp.Code != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unquoted If-Match, got %d", resp.Code)
+	}
+}
+
+func TestRecordAttendance_ExpectedVersion(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		name            string
+		date            string
+		expectedVersion int
+		expected        int
+	}{
+		{"create when absent", "2024-02-01", 0, fiber.StatusCreated},
+		{"create when present", "2024-01-15", 0, fiber.StatusConflict},
+		{"stale version", "2024-01-16", 5, fiber.StatusConflict},
+		{"current version", "2024-01-16", 1, fiber.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reqBody := map[string]interface{}{"registration_id": 1, "date": tc.date, "status": "PRESENT", "expected_version": tc.expectedVersion}
+			resp, err := makeRequest(app, "POST", "/attendance", testTeacherEmail, reqBody)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tc.expected, resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecordBulkAttendance_VersionConflictRollsBack(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-01-15", "status": "LATE", "expected_version": 1},
+		{"registration_id": 2, "date": "2024-01-15", "status": "LATE", "expected_version": 3},
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", resp.Code)
+	}
+
+	var conflict struct {
+		Index   int                `json:"index"`
+		Current db.AttendanceState `json:"current"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &conflict)
+	if conflict.Index != 1 || conflict.Current.RegistrationID != 2 || conflict.Current.Version != 1 {
+		t.Errorf("Unexpected conflict: %+v", conflict)
+	}
+
+	state, _ := db.GetAttendanceState(testSchoolContext(), 1, "2024-01-15")
+	if state.Status != "PRESENT" || state.Version != 1 {
+		t.Errorf("Expected first record to be rolled back, got %+v", state)
+	}
+}
+
+func TestRecordBulkAttendance_CreateOnlyConflictsWithConcurrentCreate(t *testing.T) {
+	app := setupTestApp(t)
+
+	// Both records pass the version check before either is written, as two
+	// concurrent requests would, so only the insert can catch the second.
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT", "expected_version": 0},
+		{"registration_id": 1, "date": "2024-02-01", "status": "ABSENT", "expected_version": 0},
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusConflict {
+		t.Fatalf("Expected status 409, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var conflict struct {
+		Index int `json:"index"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &conflict)
+	if conflict.Index != 1 {
+		t.Errorf("Expected the second record to conflict, got %+v", conflict)
+	}
+
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 1, "2024-02-01"); state != nil {
+		t.Errorf("Expected the batch to be rolled back, got %+v", state)
+	}
+}
+
+func TestRecordBulkAttendance_PartialMode(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT"},
+		{"registration_id": 1, "date": "2024-01-15", "status": "LATE"},
+		{"registration_id": 2, "date": "2024-01-15", "status": "PRESENT"},
+		{"registration_id": 999, "date": "2024-01-15", "status": "PRESENT"},
+		{"registration_id": 2, "date": "2024-01-16", "status": "UNKNOWN"},
+		{"registration_id": 4, "date": "2024-01-15", "status": "ABSENT", "expected_version": 7},
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance/bulk?mode=partial", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var response struct {
+		RecordsProcessed int                       `json:"records_processed"`
+		Results          []db.BulkAttendanceResult `json:"results"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &response)
+
+	expected := []struct {
+		result  string
+		version int
+	}{
+		{db.BulkResultCreated, 1},
+		{db.BulkResultUpdated, 2},
+		{db.BulkResultUnchanged, 1},
+		{db.BulkResultError, 0},
+		{db.BulkResultError, 0},
+		{db.BulkResultError, 1},
+	}
+	if len(response.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(response.Results))
+	}
+	for i, want := range expected {
+		got := response.Results[i]
+		if got.Index != i || got.Result != want.result || got.Version != want.version {
+			t.Errorf("Result %d: expected %s at version %d, got %+v", i, want.result, want.version, got)
+		}
+		if (got.Result == db.BulkResultError) != (got.Error != "") {
+			t.Errorf("Result %d: expected a reason only for errors, got %q", i, got.Error)
+		}
+	}
+	if response.RecordsProcessed != 3 {
+		t.Errorf("Expected 3 records processed, got %d", response.RecordsProcessed)
+	}
+
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 1, "2024-02-01"); state == nil || state.Status != "PRESENT" {
+		t.Errorf("Expected created record to be committed, got %+v", state)
+	}
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 4, "2024-01-15"); state.Status != "PRESENT" {
+		t.Errorf("Expected conflicting record to be skipped, got %s", state.Status)
+	}
+}
+
+func TestRecordBulkAttendance_PartialModeRepeatedRecord(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 3, "date": "2024-02-01", "status": "ABSENT", "expected_version": 0},
+		{"registration_id": 3, "date": "2024-02-01", "status": "LATE", "expected_version": 1},
+		{"registration_id": 3, "date": "2024-02-01", "status": "LATE"},
+	}
+
+	resp, _ := makeRequest(app, "POST", "/attendance/bulk?mode=partial", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var response struct {
+		Results []db.BulkAttendanceResult `json:"results"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &response)
+
+	expected := []string{db.BulkResultCreated, db.BulkResultUpdated, db.BulkResultUnchanged}
+	for i, want := range expected {
+		if response.Results[i].Result != want {
+			t.Errorf("Result %d: expected %s, got %+v", i, want, response.Results[i])
+		}
+	}
+
+	state, _ := db.GetAttendanceState(testSchoolContext(), 3, "2024-02-01")
+	if state.Status != "LATE" || state.Version != 2 {
+		t.Errorf("Expected LATE at version 2, got %+v", state)
+	}
+}
+
+func TestRecordBulkAttendance_InvalidMode(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT"}}
+	resp, _ := makeRequest(app, "POST", "/attendance/bulk?mode=lenient", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.Code)
+	}
+}
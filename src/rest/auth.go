@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"encoding/base64"
@@ -8,16 +9,21 @@ import (
 	"fmt"
 	"math/big"
 	"net/http"
-	"os"
+	"skulla-api/db"
+	"skulla-api/metrics"
+	"skulla-api/tracing"
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const supabaseURL = "https://ovqjkfjuzpxhvrjuagpf.supabase.co"
-
 var publicKey *ecdsa.PublicKey
 
 type JWK struct {
@@ -32,17 +38,33 @@ type JWKS struct {
 	Keys []JWK `json:"keys"`
 }
 
-func init() {
-	err := fetchSupabasePublicKey()
+// fetchSupabasePublicKey loads the key that verifies tokens in its own span,
+// counting failures in the metrics.
+func fetchSupabasePublicKey(ctx context.Context) error {
+	jwksURL := fmt.Sprintf("%s/auth/v1/.well-known/jwks.json", settings.Auth.SupabaseURL)
+
+	ctx, span := tracing.Tracer().Start(ctx, "GET JWKS",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.URLFull(jwksURL)),
+	)
+	defer span.End()
+
+	err := loadSupabasePublicKey(ctx, jwksURL)
 	if err != nil {
-		fmt.Printf("Warning: Failed to fetch Supabase public key: %v\n", err)
+		metrics.JWKSFetchFailures.Inc()
+		tracing.RecordError(span, err)
 	}
+	return err
 }
 
-func fetchSupabasePublicKey() error {
-	jwksURL := fmt.Sprintf("%s/auth/v1/.well-known/jwks.json", supabaseURL)
+func loadSupabasePublicKey(ctx context.Context, jwksURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	resp, err := http.Get(jwksURL)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -109,19 +131,70 @@ func GetUserEmailFromToken(c *fiber.Ctx) (string, error) {
 	return "", fmt.Errorf("unable to extract user email from JWT token")
 }
 
+const (
+	RoleAdmin = "admin"
+	RoleStaff = "staff"
+)
+
+// userRole returns the role in the token's app_metadata claim.
+func userRole(claims jwt.MapClaims) string {
+	if metadata, ok := claims["app_metadata"].(map[string]interface{}); ok {
+		if role, ok := metadata["role"].(string); ok {
+			return role
+		}
+	}
+	return ""
+}
+
+// RequireRole only lets requests through whose token carries one of roles.
+// It runs after AuthMiddleware.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, _ := c.Locals("user").(jwt.MapClaims)
+		if !slices.Contains(roles, userRole(claims)) {
+			return ReturnUnauthorized(c, "User does not have permission for this action")
+		}
+		return c.Next()
+	}
+}
+
+// AuthMiddleware verifies the bearer token and scopes the request to its
+// school.
 func AuthMiddleware(c *fiber.Ctx) error {
 	if c.Method() == "OPTIONS" {
 		return c.Next()
 	}
 
+	parent := c.UserContext()
+	ctx, span := tracing.Tracer().Start(parent, "auth")
+	c.SetUserContext(ctx)
+	claims, schoolID, err := authenticate(c)
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	span.End()
+	c.SetUserContext(parent)
+
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	c.Locals("user", claims)
+	c.SetUserContext(db.WithSchool(parent, schoolID))
+	return c.Next()
+}
+
+// authenticate returns the claims of the request's token and the school it
+// acts on, or a *fiber.Error to answer with.
+func authenticate(c *fiber.Ctx) (jwt.MapClaims, uint, error) {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return ReturnUnauthorized(c, "Missing authorization header")
+		return nil, 0, fiber.NewError(fiber.StatusUnauthorized, "Missing authorization header")
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenString == authHeader {
-		return ReturnUnauthorized(c, "Invalid authorization header format. Use: Bearer <token>")
+		return nil, 0, fiber.NewError(fiber.StatusUnauthorized, "Invalid authorization header format. Use: Bearer <token>")
 	}
 
 	var token *jwt.Token
@@ -133,8 +206,8 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		})
 	} else {
 		if publicKey == nil {
-			if err := fetchSupabasePublicKey(); err != nil {
-				return ReturnInternalError(c, "Failed to verify token")
+			if err := fetchSupabasePublicKey(c.UserContext()); err != nil {
+				return nil, 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to verify token")
 			}
 		}
 
@@ -150,13 +223,17 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		if err != nil {
 			log.Error(err)
 		}
-		return ReturnUnauthorized(c, "Invalid or expired token")
+		return nil, 0, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
 	}
 
-	c.Locals("user", token.Claims)
-	return c.Next()
+	claims, _ := token.Claims.(jwt.MapClaims)
+	schoolID, err := resolveSchool(c, claims)
+	if err != nil {
+		return nil, 0, err
+	}
+	return claims, schoolID, nil
 }
 
 func IsTestMode() bool {
-	return os.Getenv("TEST_MODE") == "true"
+	return settings.Auth.TestMode
 }
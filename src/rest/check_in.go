@@ -0,0 +1,273 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"skulla-api/checkin"
+	"skulla-api/db"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCheckInDurationMinutes  = 15
+	defaultCheckInLateAfterMinutes = 10
+	maxCheckInDurationMinutes      = 120
+)
+
+type CheckInWindowRequest struct {
+	StudentClassID   uint `json:"student_class_id"`
+	DurationMinutes  *int `json:"duration_minutes"`
+	LateAfterMinutes *int `json:"late_after_minutes"`
+}
+
+type CheckInRequest struct {
+	Token    string `json:"token"`
+	DeviceID string `json:"device_id"`
+}
+
+func validateCheckInWindowRequest(req CheckInWindowRequest) (int, int, error) {
+	if req.StudentClassID == 0 {
+		return 0, 0, fmt.Errorf("student_class_id is required")
+	}
+
+	duration := defaultCheckInDurationMinutes
+	if req.DurationMinutes != nil {
+		duration = *req.DurationMinutes
+	}
+	if duration < 1 || duration > maxCheckInDurationMinutes {
+		return 0, 0, fmt.Errorf("duration_minutes must be between 1 and %d", maxCheckInDurationMinutes)
+	}
+
+	lateAfter := min(defaultCheckInLateAfterMinutes, duration)
+	if req.LateAfterMinutes != nil {
+		lateAfter = *req.LateAfterMinutes
+	}
+	if lateAfter < 0 || lateAfter > duration {
+		return 0, 0, fmt.Errorf("late_after_minutes must be between 0 and duration_minutes")
+	}
+
+	return duration, lateAfter, nil
+}
+
+func OpenCheckInWindow(c *fiber.Ctx) error {
+	var req CheckInWindowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	duration, lateAfter, err := validateCheckInWindowRequest(req)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if !slices.Contains(teacherStudentClassIDs(c.UserContext(), userEmail), req.StudentClassID) {
+		return ReturnUnauthorized(c, "User does not have permission to access student class")
+	}
+
+	secret, err := checkin.NewSecret()
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to open check-in window")
+	}
+
+	now := time.Now()
+	window := db.CheckInWindow{
+		StudentClassID: req.StudentClassID,
+		Date:           now.Format("2006-01-02"),
+		Secret:         secret,
+		OpensAt:        now,
+		LateAfter:      now.Add(time.Duration(lateAfter) * time.Minute),
+		ClosesAt:       now.Add(time.Duration(duration) * time.Minute),
+		CreatedBy:      userEmail,
+	}
+	if err := db.CreateCheckInWindow(c.UserContext(), &window); err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to open check-in window")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(window)
+}
+
+// teacherCheckInWindow loads the window in the :id parameter and checks that
+// the caller teaches its class and that it is still open.
+func teacherCheckInWindow(c *fiber.Ctx) (db.CheckInWindow, error) {
+	windowID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return db.CheckInWindow{}, fiber.NewError(fiber.StatusBadRequest, "invalid id format")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return db.CheckInWindow{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	window, err := db.GetCheckInWindow(c.UserContext(), uint(windowID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return window, fiber.NewError(fiber.StatusNotFound, "Check-in window not found")
+	}
+	if err != nil {
+		return window, err
+	}
+
+	if !slices.Contains(teacherStudentClassIDs(c.UserContext(), userEmail), window.StudentClassID) {
+		return window, fiber.NewError(fiber.StatusUnauthorized, "User does not have permission to access student class")
+	}
+
+	if !window.IsOpen(time.Now()) {
+		return window, fiber.NewError(fiber.StatusBadRequest, "Check-in window is closed")
+	}
+
+	return window, nil
+}
+
+func GetCheckInToken(c *fiber.Ctx) error {
+	window, err := teacherCheckInWindow(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	token, expiresAt := checkin.Token(window, time.Now())
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// GetCheckInQRCode renders the current token as an SVG, or as a PNG when
+// format=png. Displays should refetch it when the token rotates.
+func GetCheckInQRCode(c *fiber.Ctx) error {
+	window, err := teacherCheckInWindow(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	token, expiresAt := checkin.Token(window, time.Now())
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderExpires, expiresAt.UTC().Format(time.RFC1123))
+
+	switch c.Query("format", "svg") {
+	case "svg":
+		svg, err := checkin.SVG(token)
+		if err != nil {
+			log.Error(err)
+			return ReturnInternalError(c, "Failed to render QR code")
+		}
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+		return c.SendString(svg)
+	case "png":
+		size := c.QueryInt("size", 256)
+		if size < 64 || size > 1024 {
+			return ReturnBadRequest(c, "size must be between 64 and 1024")
+		}
+		png, err := checkin.PNG(token, size)
+		if err != nil {
+			log.Error(err)
+			return ReturnInternalError(c, "Failed to render QR code")
+		}
+		c.Set(fiber.HeaderContentType, "image/png")
+		return c.Send(png)
+	default:
+		return ReturnBadRequest(c, "format must be one of: svg, png")
+	}
+}
+
+// CheckIn records the calling student as PRESENT, or LATE once the window's
+// late cutoff has passed.
+func CheckIn(c *fiber.Ctx) error {
+	var req CheckInRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if req.Token == "" {
+		return ReturnBadRequest(c, "token is required")
+	}
+
+	if strings.TrimSpace(req.DeviceID) == "" {
+		return ReturnBadRequest(c, "device_id is required")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	windowID, err := checkin.WindowID(req.Token)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	window, err := db.GetCheckInWindow(c.UserContext(), windowID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnBadRequest(c, checkin.ErrInvalidToken.Error())
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load check-in window")
+	}
+
+	now := time.Now()
+	if err := checkin.Verify(window, req.Token, now); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if !window.IsOpen(now) {
+		return ReturnBadRequest(c, "Check-in window is closed")
+	}
+
+	student, err := db.GetStudentByEmail(c.UserContext(), userEmail)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnNotFound(c, "Student not found")
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load student")
+	}
+
+	registration, err := db.GetActiveRegistration(c.UserContext(), student.ID, window.StudentClassID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnUnauthorized(c, "Student is not registered in this class")
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load registration")
+	}
+
+	status := "PRESENT"
+	if now.After(window.LateAfter) {
+		status = "LATE"
+	}
+
+	err = db.RecordCheckIn(c.UserContext(), window, db.CheckIn{
+		RegistrationID: registration.ID,
+		DeviceID:       req.DeviceID,
+		Status:         status,
+	}, userEmail)
+	if errors.Is(err, db.ErrAlreadyCheckedIn) || errors.Is(err, db.ErrDeviceUsed) || errors.Is(err, db.ErrAlreadyMarked) || errors.Is(err, db.ErrRegisterLocked) {
+		return ReturnConflict(c, err.Error())
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to record check-in")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":         "Checked in successfully",
+		"registration_id": registration.ID,
+		"date":            window.Date,
+		"status":          status,
+	})
+}
@@ -0,0 +1,221 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"skulla-api/checkin"
+	"skulla-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func openTestCheckInWindow(t *testing.T, app *fiber.App, reqBody map[string]interface{}) db.CheckInWindow {
+	resp, err := makeRequest(app, "POST", "/check-in-windows", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var window db.CheckInWindow
+	json.Unmarshal(resp.Body.Bytes(), &window)
+	return window
+}
+
+func currentCheckInToken(t *testing.T, app *fiber.App, windowID uint) string {
+	resp, err := makeRequest(app, "GET", fmt.Sprintf("/check-in-windows/%d/token", windowID), testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	return body["token"].(string)
+}
+
+func checkInAs(t *testing.T, app *fiber.App, email string, token string, deviceID string) (int, map[string]interface{}) {
+	reqBody := map[string]interface{}{"token": token, "device_id": deviceID}
+	resp, err := makeRequest(app, "POST", "/check-in", email, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp.Code, body
+}
+
+func TestCheckIn(t *testing.T) {
+	app := setupTestApp(t)
+
+	window := openTestCheckInWindow(t, app, map[string]interface{}{"student_class_id": 1})
+	token := currentCheckInToken(t, app, window.ID)
+
+	code, body := checkInAs(t, app, testStudentEmail, token, "phone-john")
+	if code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %v", code, body)
+	}
+	if body["status"] != "PRESENT" {
+		t.Errorf("Expected PRESENT, got %v", body["status"])
+	}
+
+	studentClassID := uint(1)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	report := db.GetDetailedStudentAttendanceReport(testSchoolContext(), 1, window.Date, tomorrow, &studentClassID, false)
+	if len(report.Records) != 1 || report.Records[0].Status != "PRESENT" {
+		t.Errorf("Expected one PRESENT attendance record, got %+v", report.Records)
+	}
+
+	code, _ = checkInAs(t, app, testStudentEmail, token, "phone-john")
+	if code != fiber.StatusConflict {
+		t.Errorf("Expected replayed check-in to return 409, got %d", code)
+	}
+
+	code, _ = checkInAs(t, app, testStudentEmail2, token, "phone-john")
+	if code != fiber.StatusConflict {
+		t.Errorf("Expected shared device to return 409, got %d", code)
+	}
+
+	code, body = checkInAs(t, app, testStudentEmail2, token, "phone-jane")
+	if code != fiber.StatusCreated {
+		t.Errorf("Expected status 201, got %d: %v", code, body)
+	}
+}
+
+func TestCheckIn_KeepsTeacherMark(t *testing.T) {
+	app := setupTestApp(t)
+
+	window := openTestCheckInWindow(t, app, map[string]interface{}{"student_class_id": 1})
+	token := currentCheckInToken(t, app, window.ID)
+
+	records := []map[string]interface{}{{"registration_id": 1, "date": window.Date, "status": "ABSENT", "remarks": "Sick"}}
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, records)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	code, body := checkInAs(t, app, testStudentEmail, token, "phone-john")
+	if code != fiber.StatusConflict {
+		t.Fatalf("Expected status 409 after the teacher marked attendance, got %d: %v", code, body)
+	}
+
+	state, _ := db.GetAttendanceState(testSchoolContext(), 1, window.Date)
+	if state == nil || state.Status != "ABSENT" || state.Remarks != "Sick" || state.Version != 1 {
+		t.Errorf("Expected the teacher's mark to be kept, got %+v", state)
+	}
+
+	code, _ = checkInAs(t, app, testStudentEmail, token, "phone-john")
+	if code != fiber.StatusConflict {
+		t.Errorf("Expected the check-in to stay refused, got %d", code)
+	}
+}
+
+func TestCheckIn_Late(t *testing.T) {
+	app := setupTestApp(t)
+
+	window := openTestCheckInWindow(t, app, map[string]interface{}{"student_class_id": 1, "late_after_minutes": 0})
+	token := currentCheckInToken(t, app, window.ID)
+
+	code, body := checkInAs(t, app, testStudentEmail, token, "phone-john")
+	if code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %v", code, body)
+	}
+	if body["status"] != "LATE" {
+		t.Errorf("Expected LATE, got %v", body["status"])
+	}
+}
+
+func TestCheckIn_Rejected(t *testing.T) {
+	app := setupTestApp(t)
+
+	window := openTestCheckInWindow(t, app, map[string]interface{}{"student_class_id": 1})
+	stored, _ := db.GetCheckInWindow(testSchoolContext(), window.ID)
+	expired, _ := checkin.Token(stored, time.Now().Add(-2*checkin.RotationInterval))
+	token := currentCheckInToken(t, app, window.ID)
+
+	testCases := []struct {
+		name     string
+		email    string
+		token    string
+		expected int
+	}{
+		{"expired token", testStudentEmail, expired, fiber.StatusBadRequest},
+		{"forged token", testStudentEmail, fmt.Sprintf("%d.1.forged", window.ID), fiber.StatusBadRequest},
+		{"unknown window", testStudentEmail, "999.1.forged", fiber.StatusBadRequest},
+		{"not a student", testTeacherEmail, token, fiber.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, body := checkInAs(t, app, tc.email, tc.token, "device")
+			if code != tc.expected {
+				t.Errorf("Expected status %d, got %d: %v", tc.expected, code, body)
+			}
+		})
+	}
+}
+
+func TestOpenCheckInWindow_Validation(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		body     map[string]interface{}
+		expected int
+	}{
+		{map[string]interface{}{}, fiber.StatusBadRequest},
+		{map[string]interface{}{"student_class_id": 1, "duration_minutes": 0}, fiber.StatusBadRequest},
+		{map[string]interface{}{"student_class_id": 1, "duration_minutes": 5, "late_after_minutes": 6}, fiber.StatusBadRequest},
+		{map[string]interface{}{"student_class_id": 4}, fiber.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		resp, err := makeRequest(app, "POST", "/check-in-windows", testTeacherEmail, tc.body)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != tc.expected {
+			t.Errorf("Expected status %d for %v, got %d", tc.expected, tc.body, resp.Code)
+		}
+	}
+}
+
+func TestGetCheckInQRCode(t *testing.T) {
+	app := setupTestApp(t)
+	window := openTestCheckInWindow(t, app, map[string]interface{}{"student_class_id": 1})
+
+	resp, err := makeRequest(app, "GET", fmt.Sprintf("/check-in-windows/%d/qr", window.ID), testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK || !strings.HasPrefix(resp.Body.String(), "<svg") {
+		t.Errorf("Expected SVG QR code, got %d", resp.Code)
+	}
+
+	resp, err = makeRequest(app, "GET", fmt.Sprintf("/check-in-windows/%d/qr?format=png", window.ID), testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK || !strings.HasPrefix(resp.Body.String(), "\x89PNG") {
+		t.Errorf("Expected PNG QR code, got %d", resp.Code)
+	}
+
+	resp, err = makeRequest(app, "GET", fmt.Sprintf("/check-in-windows/%d/qr", window.ID), testTeacherEmail2, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for another teacher, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"skulla-api/db"
+	"skulla-api/metrics"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type AttendanceException struct {
+	RegistrationID uint   `json:"registration_id"`
+	Status         string `json:"status"`
+	Remarks        string `json:"remarks"`
+}
+
+// MarkClassRequest marks every active registration of a class on Date with
+// Status, or with their statuses on CopyFrom.
+type MarkClassRequest struct {
+	StudentClassID uint                  `json:"student_class_id"`
+	Date           string                `json:"date"`
+	Status         string                `json:"status"`
+	Remarks        string                `json:"remarks"`
+	CopyFrom       string                `json:"copy_from"`
+	Exceptions     []AttendanceException `json:"exceptions"`
+	Draft          bool                  `json:"draft"`
+}
+
+func validateMarkClassRequest(req MarkClassRequest) error {
+	if req.StudentClassID == 0 {
+		return fmt.Errorf("student_class_id is required")
+	}
+
+	if req.Date == "" {
+		return fmt.Errorf("date is required")
+	}
+
+	if err := ValidateDateString(req.Date, "date"); err != nil {
+		return err
+	}
+
+	if req.CopyFrom != "" {
+		if err := ValidateDateString(req.CopyFrom, "copy_from"); err != nil {
+			return err
+		}
+		if req.CopyFrom == req.Date {
+			return fmt.Errorf("copy_from must be another date than date")
+		}
+	} else if req.Status == "" {
+		return fmt.Errorf("status is required unless copy_from is set")
+	}
+
+	if req.Status != "" && !validStatuses[req.Status] {
+		return fmt.Errorf("status must be one of: PRESENT, ABSENT, LATE, EXCUSED")
+	}
+
+	seen := make(map[uint]bool)
+	for i, exception := range req.Exceptions {
+		if !validStatuses[exception.Status] {
+			return fmt.Errorf("exception %d: status must be one of: PRESENT, ABSENT, LATE, EXCUSED", i)
+		}
+		if seen[exception.RegistrationID] {
+			return fmt.Errorf("exception %d: registration %d is listed twice", i, exception.RegistrationID)
+		}
+		seen[exception.RegistrationID] = true
+	}
+
+	return nil
+}
+
+// MarkClassAttendance records attendance for a whole class in one
+// transaction, from a default status or from the marks of a previous session.
+func MarkClassAttendance(c *fiber.Ctx) error {
+	var req MarkClassRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if err := validateMarkClassRequest(req); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnUnauthorized(c, "Unable to extract user email from token")
+	}
+
+	if !slices.Contains(teacherStudentClassIDs(c.UserContext(), userEmail), req.StudentClassID) {
+		return ReturnUnauthorized(c, "User does not have permission to access student class")
+	}
+
+	active := make(map[uint]bool)
+	var registrationIDs []uint
+	for _, registration := range db.ListRegistrations(c.UserContext(), int(req.StudentClassID)) {
+		if registration.Status == "ACTIVE" {
+			active[registration.ID] = true
+			registrationIDs = append(registrationIDs, registration.ID)
+		}
+	}
+
+	exceptions := make(map[uint]AttendanceException)
+	for i, exception := range req.Exceptions {
+		if !active[exception.RegistrationID] {
+			return ReturnBadRequest(c, fmt.Sprintf("exception %d: registration %d is not active in this class", i, exception.RegistrationID))
+		}
+		exceptions[exception.RegistrationID] = exception
+	}
+
+	copied := map[uint]db.Attendance{}
+	if req.CopyFrom != "" {
+		copied, err = db.GetClassAttendanceOnDate(c.UserContext(), req.StudentClassID, req.CopyFrom)
+		if err != nil {
+			log.Error(err)
+			return ReturnInternalError(c, "Failed to load attendance to copy")
+		}
+	}
+
+	var records []db.BulkAttendanceRecord
+	skipped := []uint{}
+	for _, registrationID := range registrationIDs {
+		record := db.BulkAttendanceRecord{
+			RegistrationID: registrationID,
+			Date:           req.Date,
+			Status:         req.Status,
+			Remarks:        req.Remarks,
+			UserEmail:      userEmail,
+			Draft:          req.Draft || req.CopyFrom != "",
+		}
+		if previous, ok := copied[registrationID]; ok {
+			record.Status = previous.Status
+			record.Remarks = previous.Remarks
+		}
+		if exception, ok := exceptions[registrationID]; ok {
+			record.Status = exception.Status
+			record.Remarks = exception.Remarks
+		}
+
+		if record.Status == "" {
+			skipped = append(skipped, registrationID)
+			continue
+		}
+		records = append(records, record)
+	}
+	metrics.BatchSize.WithLabelValues("attendance_class").Observe(float64(len(records)))
+
+	if len(records) == 0 {
+		return ReturnBadRequest(c, "No active registrations to mark")
+	}
+
+	err = db.CreateOrUpdateBulkAttendance(c.UserContext(), records)
+	if errors.Is(err, db.ErrRegisterLocked) {
+		return ReturnConflict(c, err.Error())
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to record class attendance. All records have been rolled back.")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":           "Class attendance recorded successfully",
+		"records_processed": len(records),
+		"skipped":           skipped,
+	})
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"encoding/json"
+	"skulla-api/db"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMarkClassAttendance_DefaultWithExceptions(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := map[string]interface{}{
+		"student_class_id": 1,
+		"date":             "2024-02-01",
+		"status":           "PRESENT",
+		"exceptions": []map[string]interface{}{
+			{"registration_id": 2, "status": "ABSENT", "remarks": "Sick"},
+		},
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance/class", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &response)
+	if response["records_processed"] != float64(3) {
+		t.Errorf("Expected 3 records processed, got %v", response["records_processed"])
+	}
+
+	expected := map[uint]string{1: "PRESENT", 2: "ABSENT", 3: "PRESENT"}
+	for registrationID, status := range expected {
+		state, _ := db.GetAttendanceState(testSchoolContext(), registrationID, "2024-02-01")
+		if state == nil || state.Status != status {
+			t.Errorf("Registration %d: expected %s, got %+v", registrationID, status, state)
+		}
+	}
+
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 4, "2024-02-01"); state != nil {
+		t.Errorf("Expected other classes to be untouched, got %+v", state)
+	}
+}
+
+func TestMarkClassAttendance_CopyFrom(t *testing.T) {
+	app := setupTestApp(t)
+	createTestGuardian(t, app, map[string]interface{}{"student_id": 1, "name": "Mary Doe", "phone": "+258840000000", "channels": []string{"SMS"}})
+
+	reqBody := map[string]interface{}{
+		"student_class_id": 1,
+		"date":             "2024-02-01",
+		"copy_from":        "2024-01-16",
+	}
+
+	resp, _ := makeRequest(app, "POST", "/attendance/class", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var response struct {
+		RecordsProcessed int    `json:"records_processed"`
+		Skipped          []uint `json:"skipped"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &response)
+	if response.RecordsProcessed != 2 || len(response.Skipped) != 1 || response.Skipped[0] != 3 {
+		t.Errorf("Expected registration 3 to be skipped, got %+v", response)
+	}
+
+	expected := map[uint]db.Attendance{1: {Status: "ABSENT", Remarks: "Sick"}, 2: {Status: "LATE", Remarks: "Traffic"}}
+	for registrationID, copied := range expected {
+		state, _ := db.GetAttendanceState(testSchoolContext(), registrationID, "2024-02-01")
+		if state == nil || state.Status != copied.Status || state.Remarks != copied.Remarks {
+			t.Errorf("Registration %d: expected copied %s with remarks %q, got %+v", registrationID, copied.Status, copied.Remarks, state)
+		}
+	}
+
+	registers := db.ListAttendanceRegisters(testSchoolContext(), 1, "2024-02-01", "2024-02-01")
+	if len(registers) != 1 || registers[0].Status != db.RegisterDraft {
+		t.Errorf("Expected the copied register to start as a draft, got %+v", registers)
+	}
+	if notifications := db.ListDueGuardianNotifications(testSchoolContext(), time.Now().Add(time.Hour)); len(notifications) != 0 {
+		t.Errorf("Expected no guardian notifications for a draft, got %d", len(notifications))
+	}
+
+	reqBody["status"] = "PRESENT"
+	reqBody["date"] = "2024-02-02"
+	resp, _ = makeRequest(app, "POST", "/attendance/class", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 3, "2024-02-02"); state == nil || state.Status != "PRESENT" {
+		t.Errorf("Expected unmarked registration to get the default status, got %+v", state)
+	}
+}
+
+func TestMarkClassAttendance_Errors(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		name     string
+		email    string
+		body     map[string]interface{}
+		expected int
+	}{
+		{"missing date", testTeacherEmail, map[string]interface{}{"student_class_id": 1, "status": "PRESENT"}, fiber.StatusBadRequest},
+		{"missing status", testTeacherEmail, map[string]interface{}{"student_class_id": 1, "date": "2024-02-01"}, fiber.StatusBadRequest},
+		{"invalid date", testTeacherEmail, map[string]interface{}{"student_class_id": 1, "date": "01/02/2024", "status": "PRESENT"}, fiber.StatusBadRequest},
+		{"copy from same date", testTeacherEmail, map[string]interface{}{"student_class_id": 1, "date": "2024-02-01", "copy_from": "2024-02-01"}, fiber.StatusBadRequest},
+		{"exception outside class", testTeacherEmail, map[string]interface{}{"student_class_id": 1, "date": "2024-02-01", "status": "PRESENT", "exceptions": []map[string]interface{}{{"registration_id": 4, "status": "ABSENT"}}}, fiber.StatusBadRequest},
+		{"other teacher", testTeacherEmail2, map[string]interface{}{"student_class_id": 1, "date": "2024-02-01", "status": "PRESENT"}, fiber.StatusUnauthorized},
+		{"no registrations", testTeacherEmail, map[string]interface{}{"student_class_id": 2, "date": "2024-02-01", "status": "PRESENT"}, fiber.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := makeRequest(app, "POST", "/attendance/class", tc.email, tc.body)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tc.expected, resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
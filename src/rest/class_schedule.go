@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"fmt"
+	"skulla-api/db"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type ClassScheduleRequest struct {
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+func validateClassScheduleRequest(req ClassScheduleRequest, index int) error {
+	if req.Weekday < 1 || req.Weekday > 7 {
+		return fmt.Errorf("session %d: weekday must be between 1 (Monday) and 7 (Sunday)", index)
+	}
+
+	for _, value := range []string{req.StartTime, req.EndTime} {
+		if _, err := time.Parse("15:04", value); err != nil {
+			return fmt.Errorf("session %d: start_time and end_time must use HH:MM", index)
+		}
+	}
+
+	if req.StartTime >= req.EndTime {
+		return fmt.Errorf("session %d: start_time must be before end_time", index)
+	}
+
+	return nil
+}
+
+// teacherStudentClassParam parses the :id parameter and checks that the caller
+// teaches the class.
+func teacherStudentClassParam(c *fiber.Ctx) (uint, error) {
+	studentClassID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid id format")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if !slices.Contains(teacherStudentClassIDs(c.UserContext(), userEmail), uint(studentClassID)) {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, "User does not have permission to access student class")
+	}
+
+	return uint(studentClassID), nil
+}
+
+func GetClassSchedule(c *fiber.Ctx) error {
+	studentClassID, err := teacherStudentClassParam(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	return c.JSON(db.ListClassSchedule(c.UserContext(), studentClassID))
+}
+
+func ReplaceClassSchedule(c *fiber.Ctx) error {
+	var requests []ClassScheduleRequest
+	if err := c.BodyParser(&requests); err != nil {
+		return ReturnBadRequest(c, "Invalid request body. Expected JSON array of sessions")
+	}
+
+	for i, req := range requests {
+		if err := validateClassScheduleRequest(req, i); err != nil {
+			return ReturnBadRequest(c, err.Error())
+		}
+	}
+
+	studentClassID, err := teacherStudentClassParam(c)
+	if err != nil {
+		return ReturnError(c, err)
+	}
+
+	schedule := make([]db.ClassSchedule, 0, len(requests))
+	for _, req := range requests {
+		schedule = append(schedule, db.ClassSchedule{
+			Weekday:   req.Weekday,
+			StartTime: req.StartTime,
+			EndTime:   req.EndTime,
+		})
+	}
+
+	if err := db.ReplaceClassSchedule(c.UserContext(), studentClassID, schedule); err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to save class schedule")
+	}
+
+	return c.JSON(db.ListClassSchedule(c.UserContext(), studentClassID))
+}
@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"skulla-api/db"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+type DelegationRequest struct {
+	GranteeEmail    string     `json:"grantee_email"`
+	StudentClassIDs []uint     `json:"student_class_ids"`
+	ValidFrom       *time.Time `json:"valid_from"`
+	ValidTo         time.Time  `json:"valid_to"`
+}
+
+func validateDelegationRequest(req DelegationRequest, grantorEmail string, now time.Time) error {
+	if !strings.Contains(req.GranteeEmail, "@") {
+		return fmt.Errorf("grantee_email must be an email address")
+	}
+
+	if strings.EqualFold(req.GranteeEmail, grantorEmail) {
+		return fmt.Errorf("grantee_email must be another teacher")
+	}
+
+	if len(req.StudentClassIDs) == 0 {
+		return fmt.Errorf("at least one student_class_id is required")
+	}
+
+	if req.ValidTo.IsZero() {
+		return fmt.Errorf("valid_to is required")
+	}
+
+	validFrom := now
+	if req.ValidFrom != nil {
+		validFrom = *req.ValidFrom
+	}
+	if !req.ValidTo.After(validFrom) || !req.ValidTo.After(now) {
+		return fmt.Errorf("valid_to must be in the future and after valid_from")
+	}
+
+	return nil
+}
+
+func ListDelegations(c *fiber.Ctx) error {
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	return c.JSON(db.ListDelegations(c.UserContext(), userEmail))
+}
+
+// CreateDelegation lets the caller hand some of their own classes to a
+// substitute for a limited time. Delegated classes cannot be delegated again.
+func CreateDelegation(c *fiber.Ctx) error {
+	var req DelegationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnUnauthorized(c, "Unable to extract user email from token")
+	}
+
+	now := time.Now()
+	if err := validateDelegationRequest(req, userEmail, now); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	ownedIDs := ownedStudentClassIDs(c.UserContext(), userEmail)
+	delegation := db.Delegation{
+		GrantorEmail: userEmail,
+		GranteeEmail: req.GranteeEmail,
+		ValidFrom:    now,
+		ValidTo:      req.ValidTo,
+	}
+	if req.ValidFrom != nil {
+		delegation.ValidFrom = *req.ValidFrom
+	}
+	for _, studentClassID := range req.StudentClassIDs {
+		if !slices.Contains(ownedIDs, studentClassID) {
+			return ReturnUnauthorized(c, fmt.Sprintf("User does not have permission to delegate student class %d", studentClassID))
+		}
+		if !slices.ContainsFunc(delegation.StudentClasses, func(class db.DelegationStudentClass) bool {
+			return class.StudentClassID == studentClassID
+		}) {
+			delegation.StudentClasses = append(delegation.StudentClasses, db.DelegationStudentClass{StudentClassID: studentClassID})
+		}
+	}
+
+	if err := db.CreateDelegation(c.UserContext(), &delegation); err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to create delegation")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(delegation)
+}
+
+// RevokeDelegation ends a delegation early. Only its grantor can revoke it.
+func RevokeDelegation(c *fiber.Ctx) error {
+	delegationID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ReturnBadRequest(c, "invalid id format")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	delegation, err := db.GetDelegation(c.UserContext(), uint(delegationID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnNotFound(c, "Delegation not found")
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load delegation")
+	}
+
+	if delegation.GrantorEmail != userEmail {
+		return ReturnUnauthorized(c, "Only the grantor can revoke a delegation")
+	}
+
+	delegation, err = db.RevokeDelegation(c.UserContext(), delegation.ID, time.Now())
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to revoke delegation")
+	}
+
+	return c.JSON(delegation)
+}
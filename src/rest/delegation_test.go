@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"skulla-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func createTestDelegation(t *testing.T, app *fiber.App, reqBody map[string]interface{}) db.Delegation {
+	resp, err := makeRequest(app, "POST", "/delegations", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var delegation db.Delegation
+	if err := json.Unmarshal(resp.Body.Bytes(), &delegation); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return delegation
+}
+
+func TestDelegation_GrantsAccessToSubstitute(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, _ := makeRequest(app, "GET", "/registrations?studentClassId=1", testTeacherEmail2, nil)
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Fatalf("Expected status 401 before delegation, got %d", resp.Code)
+	}
+
+	createTestDelegation(t, app, map[string]interface{}{
+		"grantee_email":     testTeacherEmail2,
+		"student_class_ids": []uint{1},
+		"valid_to":          time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	})
+
+	resp, _ = makeRequest(app, "GET", "/registrations?studentClassId=1", testTeacherEmail2, nil)
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, _ = makeRequest(app, "GET", "/student-classes", testTeacherEmail2, nil)
+	var classes []db.StudentClass
+	json.Unmarshal(resp.Body.Bytes(), &classes)
+	if len(classes) != 2 {
+		t.Errorf("Expected own and delegated class, got %d classes", len(classes))
+	}
+
+	resp, _ = makeRequest(app, "POST", "/webhooks", testTeacherEmail, map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"secret": "0123456789abcdef",
+		"events": []string{db.EventAttendanceRecorded},
+	})
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, _ = makeRequest(app, "POST", "/attendance/class", testTeacherEmail2, map[string]interface{}{
+		"student_class_id": 1,
+		"date":             "2024-02-01",
+		"status":           "PRESENT",
+	})
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if _, err := db.FanOutOutboxEvents(testSchoolContext()); err != nil {
+		t.Fatalf("Failed to fan out events: %v", err)
+	}
+	deliveries := db.ListDueWebhookDeliveries(testSchoolContext(), time.Now())
+	if len(deliveries) != 3 {
+		t.Fatalf("Expected 3 deliveries, got %d", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		var payload db.AttendanceEventPayload
+		json.Unmarshal([]byte(delivery.OutboxEvent.Payload), &payload)
+		if payload.RecordedBy != testTeacherEmail2 || payload.OnBehalfOf != testTeacherEmail {
+			t.Errorf("Expected substitute as author on behalf of the teacher, got %+v", payload)
+		}
+	}
+}
+
+func TestDelegation_RevokedOrExpired(t *testing.T) {
+	app := setupTestApp(t)
+
+	delegation := createTestDelegation(t, app, map[string]interface{}{
+		"grantee_email":     testTeacherEmail2,
+		"student_class_ids": []uint{1, 3},
+		"valid_to":          time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	})
+
+	resp, _ := makeRequest(app, "POST", fmt.Sprintf("/delegations/%d/revoke", delegation.ID), testTeacherEmail2, nil)
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 when the grantee revokes, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequest(app, "POST", fmt.Sprintf("/delegations/%d/revoke", delegation.ID), testTeacherEmail, nil)
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, _ = makeRequest(app, "GET", "/registrations?studentClassId=3", testTeacherEmail2, nil)
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 after revocation, got %d", resp.Code)
+	}
+
+	createTestDelegation(t, app, map[string]interface{}{
+		"grantee_email":     testTeacherEmail2,
+		"student_class_ids": []uint{1},
+		"valid_from":        time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+		"valid_to":          time.Now().Add(48 * time.Hour).Format(time.RFC3339),
+	})
+
+	resp, _ = makeRequest(app, "GET", "/registrations?studentClassId=1", testTeacherEmail2, nil)
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 before the window opens, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequest(app, "GET", "/delegations", testTeacherEmail2, nil)
+	var delegations []db.Delegation
+	json.Unmarshal(resp.Body.Bytes(), &delegations)
+	if len(delegations) != 2 {
+		t.Errorf("Expected 2 delegations, got %d", len(delegations))
+	}
+}
+
+func TestCreateDelegation_ValidationErrors(t *testing.T) {
+	app := setupTestApp(t)
+
+	validTo := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	testCases := []struct {
+		body     map[string]interface{}
+		expected int
+	}{
+		{map[string]interface{}{"student_class_ids": []uint{1}, "valid_to": validTo}, fiber.StatusBadRequest},
+		{map[string]interface{}{"grantee_email": testTeacherEmail, "student_class_ids": []uint{1}, "valid_to": validTo}, fiber.StatusBadRequest},
+		{map[string]interface{}{"grantee_email": testTeacherEmail2, "valid_to": validTo}, fiber.StatusBadRequest},
+		{map[string]interface{}{"grantee_email": testTeacherEmail2, "student_class_ids": []uint{1}}, fiber.StatusBadRequest},
+		{map[string]interface{}{"grantee_email": testTeacherEmail2, "student_class_ids": []uint{1}, "valid_to": time.Now().Add(-time.Hour).Format(time.RFC3339)}, fiber.StatusBadRequest},
+		{map[string]interface{}{"grantee_email": testTeacherEmail2, "student_class_ids": []uint{4}, "valid_to": validTo}, fiber.StatusUnauthorized},
+	}
+
+	for i, testCase := range testCases {
+		resp, err := makeRequest(app, "POST", "/delegations", testTeacherEmail, testCase.body)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+
+		if resp.Code != testCase.expected {
+			t.Errorf("Test case %d: Expected status %d, got %d. Body: %s", i, testCase.expected, resp.Code, strings.TrimSpace(resp.Body.String()))
+		}
+	}
+}
@@ -1,19 +1,67 @@
 package rest
 
 import (
+	"context"
+	"skulla-api/config"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 )
 
-func Init(app *fiber.App) {
+// settings is the configuration the endpoints were initialized with.
+var settings config.Config
+
+func Init(app *fiber.App, appConfig config.Config) {
+	settings = appConfig
+	if !settings.Auth.TestMode {
+		if err := fetchSupabasePublicKey(context.Background()); err != nil {
+			log.Warnf("Failed to fetch Supabase public key: %v", err)
+		}
+	}
+
 	SetupSwagger(app)
 
+	app.Get("/healthz", Healthz)
+	app.Get("/readyz", Readyz)
+
 	app.Get("/student-classes", AuthMiddleware, ListStudentClass)
+	app.Get("/student-classes/:id/schedule", AuthMiddleware, GetClassSchedule)
+	app.Put("/student-classes/:id/schedule", AuthMiddleware, ReplaceClassSchedule)
+	app.Get("/student-classes/:id/registers", AuthMiddleware, ListAttendanceRegisters)
+	app.Post("/student-classes/:id/registers/:date/submit", AuthMiddleware, SubmitAttendanceRegister)
+	app.Post("/student-classes/:id/registers/:date/lock", AuthMiddleware, LockAttendanceRegister)
 	app.Get("/registrations", AuthMiddleware, ListRegistrations)
-	app.Post("/attendance", AuthMiddleware, RecordAttendance)
-	app.Post("/attendance/bulk", AuthMiddleware, RecordBulkAttendance)
+	app.Get("/attendance", AuthMiddleware, GetAttendance)
+	app.Post("/attendance", AuthMiddleware, IdempotencyMiddleware, RecordAttendance)
+	app.Post("/attendance/bulk", AuthMiddleware, IdempotencyMiddleware, RecordBulkAttendance)
+	app.Post("/attendance/class", AuthMiddleware, IdempotencyMiddleware, MarkClassAttendance)
+	app.Post("/sync", AuthMiddleware, Sync)
 	app.Get("/attendance/report", AuthMiddleware, GetStudentAttendanceReport)
 	app.Get("/attendance/class-report", AuthMiddleware, GetClassAttendanceReport)
+	app.Get("/attendance/course-report", AuthMiddleware, GetCourseAttendanceReport)
+	app.Get("/attendance/school-report", AuthMiddleware, GetSchoolAttendanceReport)
+	app.Get("/attendance/live", AuthMiddleware, StreamAttendanceBoard)
+	app.Post("/check-in-windows", AuthMiddleware, OpenCheckInWindow)
+	app.Get("/check-in-windows/:id/token", AuthMiddleware, GetCheckInToken)
+	app.Get("/check-in-windows/:id/qr", AuthMiddleware, GetCheckInQRCode)
+	app.Post("/check-in", AuthMiddleware, CheckIn)
+	app.Get("/kiosk-devices", AuthMiddleware, RequireRole(RoleAdmin, RoleStaff), ListKioskDevices)
+	app.Post("/kiosk-devices", AuthMiddleware, RequireRole(RoleAdmin, RoleStaff), CreateKioskDevice)
+	app.Post("/kiosk/swipes", KioskAuthMiddleware, RecordKioskSwipe)
+	app.Post("/kiosk/swipes/batch", KioskAuthMiddleware, RecordKioskSwipeBatch)
+	app.Get("/alerts", AuthMiddleware, ListAlerts)
+	app.Post("/alerts/:id/acknowledge", AuthMiddleware, AcknowledgeAlert)
+	app.Get("/guardians", AuthMiddleware, ListGuardians)
+	app.Post("/guardians", AuthMiddleware, CreateGuardian)
+	app.Put("/guardians/:id", AuthMiddleware, UpdateGuardian)
+	app.Get("/delegations", AuthMiddleware, ListDelegations)
+	app.Post("/delegations", AuthMiddleware, CreateDelegation)
+	app.Post("/delegations/:id/revoke", AuthMiddleware, RevokeDelegation)
+	app.Get("/webhooks", AuthMiddleware, RequireRole(RoleAdmin), ListWebhookSubscriptions)
+	app.Post("/webhooks", AuthMiddleware, RequireRole(RoleAdmin), CreateWebhookSubscription)
+	app.Delete("/webhooks/:id", AuthMiddleware, RequireRole(RoleAdmin), DeleteWebhookSubscription)
+	app.Get("/webhooks/dead-letters", AuthMiddleware, RequireRole(RoleAdmin), ListDeadWebhookDeliveries)
+	app.Post("/webhooks/deliveries/:id/replay", AuthMiddleware, RequireRole(RoleAdmin), ReplayWebhookDelivery)
 
 	log.Info("REST API started")
 }
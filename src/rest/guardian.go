@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"skulla-api/db"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+type GuardianRequest struct {
+	StudentID    uint     `json:"student_id"`
+	Name         string   `json:"name"`
+	Email        string   `json:"email"`
+	Phone        string   `json:"phone"`
+	Channels     []string `json:"channels"`
+	NotifyAbsent *bool    `json:"notify_absent"`
+	NotifyLate   *bool    `json:"notify_late"`
+	DailyDigest  bool     `json:"daily_digest"`
+}
+
+var validChannels = map[string]bool{
+	db.NotificationChannelEmail:    true,
+	db.NotificationChannelSMS:      true,
+	db.NotificationChannelWhatsApp: true,
+}
+
+func validateGuardianRequest(req GuardianRequest) error {
+	if req.StudentID == 0 {
+		return fmt.Errorf("student_id is required")
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if req.Email != "" {
+		// The address goes into email headers.
+		address, err := mail.ParseAddress(req.Email)
+		if err != nil || address.Address != req.Email || strings.ContainsAny(req.Email, "\r\n") {
+			return fmt.Errorf("email must be a valid email address")
+		}
+	}
+
+	for _, channel := range req.Channels {
+		if !validChannels[channel] {
+			return fmt.Errorf("channels must be any of: EMAIL, SMS, WHATSAPP")
+		}
+		if channel == db.NotificationChannelEmail && req.Email == "" {
+			return fmt.Errorf("email is required for the EMAIL channel")
+		}
+		if channel != db.NotificationChannelEmail && req.Phone == "" {
+			return fmt.Errorf("phone is required for the %s channel", channel)
+		}
+	}
+
+	return nil
+}
+
+func applyGuardianRequest(guardian *db.Guardian, req GuardianRequest) {
+	guardian.StudentID = req.StudentID
+	guardian.Name = strings.TrimSpace(req.Name)
+	guardian.Email = req.Email
+	guardian.Phone = req.Phone
+	guardian.Channels = strings.Join(req.Channels, ",")
+	guardian.NotifyAbsent = req.NotifyAbsent == nil || *req.NotifyAbsent
+	guardian.NotifyLate = req.NotifyLate == nil || *req.NotifyLate
+	guardian.DailyDigest = req.DailyDigest
+}
+
+// teachesStudent reports whether the user teaches, or substitutes in, a class
+// the student is registered in.
+func teachesStudent(c *fiber.Ctx, studentID uint) bool {
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return false
+	}
+	return db.IsStudentInStudentClasses(c.UserContext(), studentID, teacherStudentClassIDs(c.UserContext(), userEmail))
+}
+
+func ListGuardians(c *fiber.Ctx) error {
+	studentID, err := ParseUintQueryParam(c, "student_id", true)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if !teachesStudent(c, studentID) {
+		return ReturnUnauthorized(c, "User does not have permission to access student")
+	}
+
+	guardians := db.ListGuardians(c.UserContext(), studentID)
+	return c.JSON(guardians)
+}
+
+func CreateGuardian(c *fiber.Ctx) error {
+	var req GuardianRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if err := validateGuardianRequest(req); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if !db.StudentExists(c.UserContext(), req.StudentID) {
+		return ReturnNotFound(c, "Student not found")
+	}
+
+	if !teachesStudent(c, req.StudentID) {
+		return ReturnUnauthorized(c, "User does not have permission to access student")
+	}
+
+	var guardian db.Guardian
+	applyGuardianRequest(&guardian, req)
+	if err := db.CreateGuardian(c.UserContext(), &guardian); err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to create guardian")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(guardian)
+}
+
+func UpdateGuardian(c *fiber.Ctx) error {
+	guardianID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ReturnBadRequest(c, "invalid id format")
+	}
+
+	var req GuardianRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if err := validateGuardianRequest(req); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	guardian, err := db.GetGuardian(c.UserContext(), uint(guardianID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnNotFound(c, "Guardian not found")
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to load guardian")
+	}
+
+	if !db.StudentExists(c.UserContext(), req.StudentID) {
+		return ReturnNotFound(c, "Student not found")
+	}
+
+	if !teachesStudent(c, guardian.StudentID) || !teachesStudent(c, req.StudentID) {
+		return ReturnUnauthorized(c, "User does not have permission to access student")
+	}
+
+	applyGuardianRequest(&guardian, req)
+	if err := db.UpdateGuardian(c.UserContext(), &guardian); err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to update guardian")
+	}
+
+	return c.JSON(guardian)
+}
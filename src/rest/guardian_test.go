@@ -0,0 +1,255 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"skulla-api/db"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func createTestGuardian(t *testing.T, app *fiber.App, reqBody map[string]interface{}) db.Guardian {
+	resp, err := makeRequest(app, "POST", "/guardians", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var guardian db.Guardian
+	if err := json.Unmarshal(resp.Body.Bytes(), &guardian); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return guardian
+}
+
+func TestCreateGuardian_Success(t *testing.T) {
+	app := setupTestApp(t)
+
+	guardian := createTestGuardian(t, app, map[string]interface{}{
+		"student_id": 1,
+		"name":       "Mary Doe",
+		"email":      "mary@test.com",
+		"channels":   []string{"EMAIL"},
+	})
+
+	if guardian.Channels != "EMAIL" || !guardian.NotifyAbsent || !guardian.NotifyLate {
+		t.Errorf("Unexpected guardian preferences: %+v", guardian)
+	}
+
+	resp, err := makeRequest(app, "GET", "/guardians?student_id=1", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var guardians []db.Guardian
+	if err := json.Unmarshal(resp.Body.Bytes(), &guardians); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(guardians) != 1 {
+		t.Errorf("Expected 1 guardian, got %d", len(guardians))
+	}
+}
+
+func TestCreateGuardian_ValidationErrors(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []map[string]interface{}{
+		{"name": "Mary Doe"},
+		{"student_id": 1},
+		{"student_id": 1, "name": "Mary Doe", "channels": []string{"PIGEON"}},
+		{"student_id": 1, "name": "Mary Doe", "channels": []string{"EMAIL"}},
+		{"student_id": 1, "name": "Mary Doe", "email": "mary@test.com", "channels": []string{"SMS"}},
+		{"student_id": 1, "name": "Mary Doe", "email": "not-an-email", "channels": []string{"EMAIL"}},
+		{"student_id": 1, "name": "Mary Doe", "email": "Mary <mary@test.com>", "channels": []string{"EMAIL"}},
+		{"student_id": 1, "name": "Mary Doe", "email": "mary@test.com\r\nBcc: all@test.com", "channels": []string{"EMAIL"}},
+	}
+
+	for i, reqBody := range testCases {
+		resp, err := makeRequest(app, "POST", "/guardians", testTeacherEmail, reqBody)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+
+		if resp.Code != fiber.StatusBadRequest {
+			t.Errorf("Test case %d: Expected status 400, got %d", i, resp.Code)
+		}
+	}
+}
+
+func TestCreateGuardian_StudentNotFound(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := map[string]interface{}{"student_id": 9999, "name": "Mary Doe"}
+
+	resp, err := makeRequest(app, "POST", "/guardians", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestUpdateGuardian_Success(t *testing.T) {
+	app := setupTestApp(t)
+
+	guardian := createTestGuardian(t, app, map[string]interface{}{"student_id": 1, "name": "Mary Doe"})
+
+	reqBody := map[string]interface{}{
+		"student_id":   1,
+		"name":         "Mary Doe",
+		"phone":        "+258840000000",
+		"channels":     []string{"SMS", "WHATSAPP"},
+		"notify_late":  false,
+		"daily_digest": true,
+	}
+
+	resp, err := makeRequest(app, "PUT", fmt.Sprintf("/guardians/%d", guardian.ID), testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var updated db.Guardian
+	if err := json.Unmarshal(resp.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if updated.Channels != "SMS,WHATSAPP" || updated.NotifyLate || !updated.DailyDigest {
+		t.Errorf("Unexpected guardian preferences: %+v", updated)
+	}
+}
+
+func TestUpdateGuardian_NotFound(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := map[string]interface{}{"student_id": 1, "name": "Mary Doe"}
+
+	resp, err := makeRequest(app, "PUT", "/guardians/9999", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestGuardians_RequireTeacherOfStudent(t *testing.T) {
+	app := setupTestApp(t)
+	guardian := createTestGuardian(t, app, map[string]interface{}{"student_id": 1, "name": "Mary Doe"})
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"list", "GET", "/guardians?student_id=1", nil},
+		{"create", "POST", "/guardians", map[string]interface{}{"student_id": 1, "name": "Peter Doe"}},
+		{"update", "PUT", fmt.Sprintf("/guardians/%d", guardian.ID), map[string]interface{}{"student_id": 1, "name": "Mary Smith"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := makeRequest(app, tc.method, tc.path, testTeacherEmail2, tc.body)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != fiber.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d. Body: %s", resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecordAttendance_QueuesGuardianNotifications(t *testing.T) {
+	app := setupTestApp(t)
+
+	createTestGuardian(t, app, map[string]interface{}{
+		"student_id":  1,
+		"name":        "Mary Doe",
+		"email":       "mary@test.com",
+		"phone":       "+258840000000",
+		"channels":    []string{"EMAIL", "SMS"},
+		"notify_late": false,
+	})
+
+	records := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-01-20", "status": "ABSENT"},
+		{"registration_id": 4, "date": "2024-01-20", "status": "LATE"},
+		{"registration_id": 2, "date": "2024-01-20", "status": "ABSENT"},
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, records)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		if resp.Code != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	notifications := db.ListDueGuardianNotifications(testSchoolContext(), time.Now())
+	if len(notifications) != 2 {
+		t.Fatalf("Expected 2 queued notifications (one per channel), got %d", len(notifications))
+	}
+
+	for _, notification := range notifications {
+		if notification.AttendanceStatus != "ABSENT" || notification.Date != "2024-01-20" {
+			t.Errorf("Unexpected notification: %+v", notification)
+		}
+	}
+}
+
+func TestRecordAttendance_CancelsSupersededGuardianNotifications(t *testing.T) {
+	app := setupTestApp(t)
+
+	createTestGuardian(t, app, map[string]interface{}{
+		"student_id": 1,
+		"name":       "Mary Doe",
+		"phone":      "+258840000000",
+		"channels":   []string{"SMS"},
+	})
+
+	recordStatus := func(status string) {
+		records := []map[string]interface{}{{"registration_id": 1, "date": "2024-01-20", "status": status}}
+		resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, records)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	recordStatus("ABSENT")
+	recordStatus("LATE")
+	notifications := db.ListDueGuardianNotifications(testSchoolContext(), time.Now())
+	if len(notifications) != 1 || notifications[0].AttendanceStatus != "LATE" {
+		t.Fatalf("Expected only the LATE notification to stay queued, got %+v", notifications)
+	}
+
+	recordStatus("PRESENT")
+	if notifications := db.ListDueGuardianNotifications(testSchoolContext(), time.Now()); len(notifications) != 0 {
+		t.Fatalf("Expected no queued notifications after the correction, got %+v", notifications)
+	}
+
+	recordStatus("ABSENT")
+	notifications = db.ListDueGuardianNotifications(testSchoolContext(), time.Now())
+	if len(notifications) != 1 || notifications[0].AttendanceStatus != "ABSENT" {
+		t.Errorf("Expected the ABSENT notification to be queued again, got %+v", notifications)
+	}
+}
@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"context"
+	"skulla-api/db"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// readinessTimeout bounds how long a readiness check waits for the database.
+const readinessTimeout = 2 * time.Second
+
+// Healthz answers as long as the process can serve requests.
+func Healthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz answers 200 when the database and the token keys are available.
+func Readyz(c *fiber.Ctx) error {
+	checks := fiber.Map{"database": "ok", "jwks": "ok"}
+	ready := true
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), readinessTimeout)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		log.Errorf("Readiness check failed to ping the database: %v", err)
+		checks["database"] = "unreachable"
+		ready = false
+	}
+
+	if IsTestMode() {
+		checks["jwks"] = "not needed in test mode"
+	} else if publicKey == nil {
+		if err := fetchSupabasePublicKey(ctx); err != nil {
+			log.Errorf("Readiness check failed to fetch the Supabase public key: %v", err)
+			checks["jwks"] = "not loaded"
+			ready = false
+		}
+	}
+
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "not ready", "checks": checks})
+	}
+	return c.JSON(fiber.Map{"status": "ready", "checks": checks})
+}
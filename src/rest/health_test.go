@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"encoding/json"
+	"skulla-api/db"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHealthz(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/healthz", "", nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK {
+		t.Errorf("Expected status 200 without a token, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/readyz", "", nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+
+	resp, err = makeRequest(app, "GET", "/readyz", "", nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 without a database, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var body struct {
+		Checks map[string]string `json:"checks"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	if body.Checks["database"] != "unreachable" {
+		t.Errorf("Expected the database check to fail, got %+v", body.Checks)
+	}
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"skulla-api/db"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// requestFingerprint identifies a request by method, URL and body, so that a
+// key reused for a different request can be told apart from a retry.
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// IdempotencyMiddleware replays the stored response to a retried write. It
+// must run after AuthMiddleware.
+func IdempotencyMiddleware(c *fiber.Ctx) error {
+	key := c.Get(IdempotencyKeyHeader)
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return ReturnBadRequest(c, "Idempotency-Key cannot be longer than 255 characters")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnUnauthorized(c, "Unable to extract user email from token")
+	}
+
+	entry := db.IdempotencyKey{
+		Key:         key,
+		UserEmail:   userEmail,
+		Method:      c.Method(),
+		Path:        c.Path(),
+		Fingerprint: requestFingerprint(c),
+		ExpiresAt:   time.Now().Add(settings.Idempotency.KeyTTL),
+	}
+	existing, err := db.ReserveIdempotencyKey(c.UserContext(), &entry, settings.Idempotency.InFlightLease)
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to check Idempotency-Key")
+	}
+
+	if existing != nil {
+		if existing.Fingerprint != entry.Fingerprint {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": "Idempotency-Key was already used for a different request",
+			})
+		}
+		if existing.StatusCode == 0 {
+			return ReturnConflict(c, "A request with this Idempotency-Key is still being processed")
+		}
+		c.Set(IdempotentReplayedHeader, "true")
+		if existing.ContentType != "" {
+			c.Set(fiber.HeaderContentType, existing.ContentType)
+		}
+		return c.Status(existing.StatusCode).SendString(existing.ResponseBody)
+	}
+
+	if err := c.Next(); err != nil {
+		releaseIdempotencyKey(c.UserContext(), entry.ID)
+		return err
+	}
+
+	// Server errors are not stored, so the client can retry them.
+	status := c.Response().StatusCode()
+	if status >= fiber.StatusInternalServerError {
+		releaseIdempotencyKey(c.UserContext(), entry.ID)
+		return nil
+	}
+
+	contentType := string(c.Response().Header.ContentType())
+	if err := db.CompleteIdempotencyKey(c.UserContext(), entry.ID, status, contentType, string(c.Response().Body())); err != nil {
+		log.Error(err)
+		releaseIdempotencyKey(c.UserContext(), entry.ID)
+	}
+	return nil
+}
+
+func releaseIdempotencyKey(ctx context.Context, id uint) {
+	if err := db.ReleaseIdempotencyKey(ctx, id); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"skulla-api/db"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIdempotencyKey_ReplaysBulkAttendance(t *testing.T) {
+	app := setupTestApp(t)
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-01-15", "status": "LATE"},
+		{"registration_id": 2, "date": "2024-01-15", "status": "ABSENT"},
+	}
+	headers := map[string]string{IdempotencyKeyHeader: "bulk-retry-1"}
+
+	first, err := makeRequestWithHeaders(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody, headers)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if first.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", first.Code, first.Body.String())
+	}
+
+	retry, _ := makeRequestWithHeaders(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody, headers)
+	if retry.Code != fiber.StatusCreated {
+		t.Fatalf("Expected replayed status 201, got %d", retry.Code)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed body %s, got %s", first.Body.String(), retry.Body.String())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("Expected replayed response to be marked")
+	}
+	if contentType := retry.Header().Get(fiber.HeaderContentType); contentType != fiber.MIMEApplicationJSON {
+		t.Errorf("Expected JSON content type, got %s", contentType)
+	}
+
+	state, _ := db.GetAttendanceState(testSchoolContext(), 1, "2024-01-15")
+	if state.Version != 2 {
+		t.Errorf("Expected the retry not to write again, got version %d", state.Version)
+	}
+
+	other, _ := makeRequestWithHeaders(app, "POST", "/attendance/bulk", testTeacherEmail2, reqBody, headers)
+	if other.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("Expected keys to be scoped to the caller")
+	}
+}
+
+func TestIdempotencyKey_RejectsDifferentRequest(t *testing.T) {
+	app := setupTestApp(t)
+	headers := map[string]string{IdempotencyKeyHeader: "single-1"}
+
+	reqBody := map[string]interface{}{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT"}
+	resp, _ := makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, headers)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	reqBody["status"] = "ABSENT"
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, headers)
+	if resp.Code != fiber.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance/bulk", testTeacherEmail, []interface{}{reqBody}, headers)
+	if resp.Code != fiber.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for another endpoint, got %d", resp.Code)
+	}
+
+	state, _ := db.GetAttendanceState(testSchoolContext(), 1, "2024-02-01")
+	if state.Status != "PRESENT" {
+		t.Errorf("Expected the first request to be kept, got %s", state.Status)
+	}
+}
+
+func TestIdempotencyKey_StoresClientErrors(t *testing.T) {
+	app := setupTestApp(t)
+	headers := map[string]string{IdempotencyKeyHeader: "conflict-1"}
+	reqBody := map[string]interface{}{"registration_id": 1, "date": "2024-01-15", "status": "LATE", "expected_version": 0}
+
+	resp, _ := makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, headers)
+	if resp.Code != fiber.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, headers)
+	if resp.Code != fiber.StatusConflict || resp.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("Expected the 409 to be replayed, got %d", resp.Code)
+	}
+}
+
+func TestIdempotencyKey_InProgressAndExpired(t *testing.T) {
+	app := setupTestApp(t)
+	reqBody := map[string]interface{}{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT"}
+
+	jsonBody, _ := json.Marshal(reqBody)
+	fingerprint := sha256.Sum256(append([]byte("POST /attendance\n"), jsonBody...))
+	inFlight := db.IdempotencyKey{Key: "in-flight", UserEmail: testTeacherEmail, Method: "POST", Path: "/attendance", Fingerprint: hex.EncodeToString(fingerprint[:]), ExpiresAt: time.Now().Add(time.Hour)}
+	if existing, err := db.ReserveIdempotencyKey(testSchoolContext(), &inFlight, time.Minute); err != nil || existing != nil {
+		t.Fatalf("Failed to reserve key: %v", err)
+	}
+
+	resp, _ := makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, map[string]string{IdempotencyKeyHeader: "in-flight"})
+	if resp.Code != fiber.StatusConflict {
+		t.Errorf("Expected status 409 while the first request is in flight, got %d", resp.Code)
+	}
+
+	abandoned := inFlight
+	abandoned.ID, abandoned.Key, abandoned.CreatedAt = 0, "abandoned", time.Now().Add(-2*settings.Idempotency.InFlightLease)
+	if _, err := db.ReserveIdempotencyKey(testSchoolContext(), &abandoned, time.Minute); err != nil {
+		t.Fatalf("Failed to reserve key: %v", err)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, map[string]string{IdempotencyKeyHeader: "abandoned"})
+	if resp.Code != fiber.StatusCreated || resp.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("Expected a key in flight past its lease to be reclaimed, got %d", resp.Code)
+	}
+
+	expired := db.IdempotencyKey{Key: "expired", UserEmail: testTeacherEmail, Method: "POST", Path: "/attendance", Fingerprint: "old", StatusCode: 201, ExpiresAt: time.Now().Add(-time.Minute)}
+	if _, err := db.ReserveIdempotencyKey(testSchoolContext(), &expired, time.Minute); err != nil {
+		t.Fatalf("Failed to store key: %v", err)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", testTeacherEmail, reqBody, map[string]string{IdempotencyKeyHeader: "expired"})
+	if resp.Code != fiber.StatusCreated || resp.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("Expected an expired key to run the request again, got %d", resp.Code)
+	}
+
+	if deleted, err := db.DeleteExpiredIdempotencyKeys(testSchoolContext(), time.Now().Add(48*time.Hour)); err != nil || deleted != 3 {
+		t.Errorf("Expected 3 expired keys to be deleted, got %d (%v)", deleted, err)
+	}
+}
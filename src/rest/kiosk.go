@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"skulla-api/db"
+	"skulla-api/metrics"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+)
+
+// KioskKeyHeader carries the device key issued when a kiosk is registered.
+const KioskKeyHeader = "X-Kiosk-Key"
+
+const maxKioskSwipeBatch = 1000
+
+type KioskDeviceRequest struct {
+	Name string `json:"name"`
+}
+
+type KioskSwipeRequest struct {
+	CardID   string     `json:"card_id"`
+	SwipedAt *time.Time `json:"swiped_at"`
+}
+
+func ListKioskDevices(c *fiber.Ctx) error {
+	return c.JSON(db.ListKioskDevices(c.UserContext()))
+}
+
+// CreateKioskDevice registers a kiosk and returns its key. The key cannot be
+// retrieved again.
+func CreateKioskDevice(c *fiber.Ctx) error {
+	var req KioskDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		return ReturnBadRequest(c, "name is required")
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	device := db.KioskDevice{Name: req.Name, CreatedBy: userEmail}
+	key, err := db.CreateKioskDevice(c.UserContext(), &device)
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to register kiosk device")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"device": device,
+		"key":    key,
+	})
+}
+
+// KioskAuthMiddleware authenticates kiosk devices by their key instead of a
+// user JWT. The request then acts on the school the device belongs to.
+func KioskAuthMiddleware(c *fiber.Ctx) error {
+	key := c.Get(KioskKeyHeader)
+	if key == "" {
+		return ReturnUnauthorized(c, "Missing kiosk key")
+	}
+
+	device, err := db.GetKioskDeviceByKey(db.AcrossSchools(c.UserContext()), key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ReturnUnauthorized(c, "Invalid kiosk key")
+	}
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to verify kiosk key")
+	}
+
+	c.Locals("kiosk_device", device)
+	c.SetUserContext(db.WithSchool(c.UserContext(), device.SchoolID))
+	return c.Next()
+}
+
+func validateKioskSwipe(req KioskSwipeRequest, index int, requireTime bool) error {
+	if strings.TrimSpace(req.CardID) == "" {
+		return fmt.Errorf("swipe %d: card_id is required", index)
+	}
+
+	if requireTime && req.SwipedAt == nil {
+		return fmt.Errorf("swipe %d: swiped_at is required", index)
+	}
+
+	if req.SwipedAt != nil && req.SwipedAt.After(time.Now().Add(time.Minute)) {
+		return fmt.Errorf("swipe %d: swiped_at cannot be in the future", index)
+	}
+
+	return nil
+}
+
+func recordKioskSwipes(c *fiber.Ctx, requests []KioskSwipeRequest) error {
+	device := c.Locals("kiosk_device").(db.KioskDevice)
+
+	swipes := make([]db.KioskSwipe, 0, len(requests))
+	for _, req := range requests {
+		swipedAt := time.Now()
+		if req.SwipedAt != nil {
+			swipedAt = *req.SwipedAt
+		}
+		swipes = append(swipes, db.KioskSwipe{CardID: req.CardID, SwipedAt: swipedAt.Truncate(time.Second)})
+	}
+
+	results, err := db.RecordKioskSwipes(c.UserContext(), device, swipes)
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to record swipes")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(results)
+}
+
+// RecordKioskSwipe records a single live swipe. swiped_at defaults to now.
+func RecordKioskSwipe(c *fiber.Ctx) error {
+	var req KioskSwipeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if err := validateKioskSwipe(req, 0, false); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	return recordKioskSwipes(c, []KioskSwipeRequest{req})
+}
+
+// RecordKioskSwipeBatch accepts swipes buffered while the kiosk was offline.
+// Each swipe must carry the time it happened.
+func RecordKioskSwipeBatch(c *fiber.Ctx) error {
+	var requests []KioskSwipeRequest
+	if err := c.BodyParser(&requests); err != nil {
+		return ReturnBadRequest(c, "Invalid request body. Expected JSON array of swipes")
+	}
+
+	if len(requests) == 0 {
+		return ReturnBadRequest(c, "At least one swipe is required")
+	}
+	metrics.BatchSize.WithLabelValues("kiosk_swipes").Observe(float64(len(requests)))
+
+	if len(requests) > maxKioskSwipeBatch {
+		return ReturnBadRequest(c, fmt.Sprintf("At most %d swipes can be sent at once", maxKioskSwipeBatch))
+	}
+
+	for i, req := range requests {
+		if err := validateKioskSwipe(req, i, true); err != nil {
+			return ReturnBadRequest(c, err.Error())
+		}
+	}
+
+	return recordKioskSwipes(c, requests)
+}
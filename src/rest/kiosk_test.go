@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"skulla-api/db"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func registerTestKiosk(t *testing.T, app *fiber.App) string {
+	resp, err := makeRequest(app, "POST", "/kiosk-devices", testTeacherEmail, map[string]interface{}{"name": "Main gate"})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var body struct {
+		Key string `json:"key"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	return body.Key
+}
+
+func scheduleTestClassEveryDay(t *testing.T, app *fiber.App) {
+	var sessions []map[string]interface{}
+	for weekday := 1; weekday <= 7; weekday++ {
+		sessions = append(sessions, map[string]interface{}{"weekday": weekday, "start_time": "08:00", "end_time": "09:00"})
+	}
+
+	resp, err := makeRequest(app, "PUT", "/student-classes/1/schedule", testTeacherEmail, sessions)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func makeKioskRequest(t *testing.T, app *fiber.App, path string, key string, body interface{}) (int, []db.SwipeResult) {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(KioskKeyHeader, key)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var results []db.SwipeResult
+	json.NewDecoder(resp.Body).Decode(&results)
+	return resp.StatusCode, results
+}
+
+func yesterdayAt(hour int, minute int) time.Time {
+	yesterday := time.Now().AddDate(0, 0, -1)
+	return time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), hour, minute, 0, 0, time.Local)
+}
+
+func TestKioskSwipeBatch(t *testing.T) {
+	app := setupTestApp(t)
+	key := registerTestKiosk(t, app)
+	scheduleTestClassEveryDay(t, app)
+
+	swipes := []map[string]interface{}{
+		{"card_id": "CARD-JANE", "swiped_at": yesterdayAt(8, 30)},
+		{"card_id": "CARD-JOHN", "swiped_at": yesterdayAt(7, 50)},
+		{"card_id": "CARD-UNKNOWN", "swiped_at": yesterdayAt(7, 55)},
+		{"card_id": "CARD-JOHN", "swiped_at": yesterdayAt(8, 40)},
+	}
+
+	code, results := makeKioskRequest(t, app, "/kiosk/swipes/batch", key, swipes)
+	if code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	john, unknown, jane, johnAgain := results[0], results[1], results[2], results[3]
+	if john.CardID != "CARD-JOHN" || len(john.Attendances) != 1 || john.Attendances[0].RegistrationID != 1 || john.Attendances[0].Status != "PRESENT" {
+		t.Errorf("Expected John to be PRESENT in Math 101, got %+v", john)
+	}
+	if !unknown.UnknownCard || unknown.StudentID != nil {
+		t.Errorf("Expected unknown card, got %+v", unknown)
+	}
+	if jane.CardID != "CARD-JANE" || len(jane.Attendances) != 1 || jane.Attendances[0].Status != "LATE" {
+		t.Errorf("Expected Jane to be LATE in Math 101, got %+v", jane)
+	}
+	if len(johnAgain.Attendances) != 0 {
+		t.Errorf("Expected second swipe not to create attendance, got %+v", johnAgain)
+	}
+
+	code, results = makeKioskRequest(t, app, "/kiosk/swipes/batch", key, swipes)
+	if code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	for _, result := range results {
+		if !result.Duplicate {
+			t.Errorf("Expected re-uploaded swipe to be a duplicate, got %+v", result)
+		}
+	}
+}
+
+func TestKioskSwipe_KeepsRecordedAttendance(t *testing.T) {
+	app := setupTestApp(t)
+	key := registerTestKiosk(t, app)
+	scheduleTestClassEveryDay(t, app)
+
+	date := yesterdayAt(0, 0).Format("2006-01-02")
+	reqBody := map[string]interface{}{"registration_id": 1, "date": date, "status": "EXCUSED"}
+	if resp, _ := makeRequest(app, "POST", "/attendance", testTeacherEmail, reqBody); resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.Code)
+	}
+
+	code, results := makeKioskRequest(t, app, "/kiosk/swipes", key, map[string]interface{}{"card_id": "CARD-JOHN", "swiped_at": yesterdayAt(7, 45)})
+	if code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	if len(results) != 1 || len(results[0].Attendances) != 0 {
+		t.Errorf("Expected existing attendance to be kept, got %+v", results)
+	}
+}
+
+func TestKioskSwipe_Unauthorized(t *testing.T) {
+	app := setupTestApp(t)
+
+	for _, key := range []string{"", "wrong-key"} {
+		code, _ := makeKioskRequest(t, app, "/kiosk/swipes", key, map[string]interface{}{"card_id": "CARD-JOHN"})
+		if code != fiber.StatusUnauthorized {
+			t.Errorf("Expected status 401 for key %q, got %d", key, code)
+		}
+	}
+}
+
+func TestKioskDevices_RequireStaff(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		name     string
+		email    string
+		method   string
+		expected int
+	}{
+		{"student registers device", testStudentEmail, "POST", fiber.StatusUnauthorized},
+		{"student lists devices", testStudentEmail, "GET", fiber.StatusUnauthorized},
+		{"staff registers device", testTeacherEmail2, "POST", fiber.StatusCreated},
+		{"staff lists devices", testTeacherEmail2, "GET", fiber.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := makeRequest(app, tc.method, "/kiosk-devices", tc.email, map[string]interface{}{"name": "Side gate"})
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tc.expected, resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestKioskSwipeBatch_RequiresTimestamps(t *testing.T) {
+	app := setupTestApp(t)
+	key := registerTestKiosk(t, app)
+
+	code, _ := makeKioskRequest(t, app, "/kiosk/swipes/batch", key, []map[string]interface{}{{"card_id": "CARD-JOHN"}})
+	if code != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", code)
+	}
+}
+
+func TestReplaceClassSchedule_Validation(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		path     string
+		email    string
+		body     []map[string]interface{}
+		expected int
+	}{
+		{"/student-classes/1/schedule", testTeacherEmail, []map[string]interface{}{{"weekday": 0, "start_time": "08:00", "end_time": "09:00"}}, fiber.StatusBadRequest},
+		{"/student-classes/1/schedule", testTeacherEmail, []map[string]interface{}{{"weekday": 1, "start_time": "8am", "end_time": "09:00"}}, fiber.StatusBadRequest},
+		{"/student-classes/1/schedule", testTeacherEmail, []map[string]interface{}{{"weekday": 1, "start_time": "09:00", "end_time": "08:00"}}, fiber.StatusBadRequest},
+		{"/student-classes/4/schedule", testTeacherEmail, []map[string]interface{}{}, fiber.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		resp, err := makeRequest(app, "PUT", tc.path, tc.email, tc.body)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != tc.expected {
+			t.Errorf("Expected status %d for %v, got %d", tc.expected, tc.body, resp.Code)
+		}
+	}
+}
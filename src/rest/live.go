@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"bufio"
+	"skulla-api/db"
+	"skulla-api/live"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// liveHeartbeatInterval keeps idle connections open through proxies and lets
+// the server notice clients that went away.
+const liveHeartbeatInterval = 15 * time.Second
+
+// StreamAttendanceBoard streams attendance events for the caller's classes as
+// Server-Sent Events.
+func StreamAttendanceBoard(c *fiber.Ctx) error {
+	studentClassID, err := ParseOptionalUintQueryParam(c, "student_class_id")
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	studentClassIDs := teacherStudentClassIDs(c.UserContext(), userEmail)
+	if studentClassID != nil {
+		if !slices.Contains(studentClassIDs, *studentClassID) {
+			return ReturnUnauthorized(c, "User does not have permission to access student class")
+		}
+		studentClassIDs = []uint{*studentClassID}
+	}
+
+	visible := make(map[uint]bool)
+	for _, id := range studentClassIDs {
+		visible[id] = true
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	// Subscribe before taking the snapshot so no commit falls between the two.
+	events, cancel := live.Subscribe()
+	snapshot := db.GetClassCompletions(c.UserContext(), studentClassIDs, time.Now().Format("2006-01-02"))
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		for _, completion := range snapshot {
+			event := live.Event{Type: live.EventSnapshot, StudentClassID: completion.StudentClassID, Data: completion}
+			if err := live.Write(w, event); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(liveHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, open := <-events:
+				if !open {
+					return
+				}
+				if !visible[event.StudentClassID] {
+					continue
+				}
+				if err := live.Write(w, event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := w.WriteString(": heartbeat\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"skulla-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type liveTestEvent struct {
+	event string
+	data  string
+}
+
+// openLiveStream serves app on a local listener and opens the live board
+// stream, since app.Test cannot read a response that never ends.
+func openLiveStream(t *testing.T, app *fiber.App, path string) <-chan liveTestEvent {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.ShutdownWithTimeout(time.Second) })
+
+	req, _ := http.NewRequest("GET", "http://"+listener.Addr().String()+path, nil)
+	req.Header.Set("Authorization", "Bearer "+createTestJWT(testTeacherEmail))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", contentType)
+	}
+
+	events := make(chan liveTestEvent, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var event liveTestEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event.event != "":
+				events <- event
+				event = liveTestEvent{}
+			}
+		}
+		close(events)
+	}()
+	return events
+}
+
+func nextLiveEvent(t *testing.T, events <-chan liveTestEvent) liveTestEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for live event")
+		return liveTestEvent{}
+	}
+}
+
+func TestStreamAttendanceBoard(t *testing.T) {
+	app := setupTestApp(t)
+	today := time.Now().Format("2006-01-02")
+
+	events := openLiveStream(t, app, "/attendance/live?student_class_id=1")
+
+	snapshot := nextLiveEvent(t, events)
+	if snapshot.event != "snapshot" {
+		t.Fatalf("Expected initial snapshot, got %s", snapshot.event)
+	}
+	var completion db.ClassCompletion
+	json.Unmarshal([]byte(snapshot.data), &completion)
+	if completion.StudentClassID != 1 || completion.Registrations != 3 || completion.Recorded != 0 || completion.Date != today {
+		t.Errorf("Unexpected snapshot: %+v", completion)
+	}
+
+	// Physics 101 is outside the requested class and must not be streamed.
+	for _, registrationID := range []uint{4, 1} {
+		reqBody := map[string]interface{}{"registration_id": registrationID, "date": today, "status": "PRESENT"}
+		resp, err := makeRequest(app, "POST", "/attendance", testTeacherEmail, reqBody)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.Code)
+		}
+	}
+
+	event := nextLiveEvent(t, events)
+	if event.event != "attendance" {
+		t.Fatalf("Expected attendance event, got %s", event.event)
+	}
+	var payload db.AttendanceEventPayload
+	json.Unmarshal([]byte(event.data), &payload)
+	if payload.RegistrationID != 1 || payload.StudentClassID != 1 || payload.Status != "PRESENT" || payload.RecordedBy != testTeacherEmail {
+		t.Errorf("Unexpected attendance event: %+v", payload)
+	}
+}
+
+func TestStreamAttendanceBoard_Unauthorized(t *testing.T) {
+	app := setupTestApp(t)
+
+	resp, err := makeRequest(app, "GET", "/attendance/live?student_class_id=4", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.Code)
+	}
+}
+
+func TestGetClassCompletions(t *testing.T) {
+	setupTestApp(t)
+
+	completions := db.GetClassCompletions(testSchoolContext(), []uint{1, 3}, "2024-01-16")
+	if len(completions) != 2 {
+		t.Fatalf("Expected 2 completions, got %d", len(completions))
+	}
+
+	math, physics := completions[0], completions[1]
+	if math.StudentClassName != "Math 101" || math.Registrations != 3 || math.Recorded != 2 {
+		t.Errorf("Unexpected Math 101 completion: %+v", math)
+	}
+	if physics.StudentClassName != "Physics 101" || physics.Registrations != 2 || physics.Recorded != 1 || physics.Percentage != 50 {
+		t.Errorf("Unexpected Physics 101 completion: %+v", physics)
+	}
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"skulla-api/metrics"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func readMetric(t *testing.T, metric prometheus.Metric) *dto.Metric {
+	var value dto.Metric
+	if err := metric.Write(&value); err != nil {
+		t.Fatalf("Failed to read metric: %v", err)
+	}
+	return &value
+}
+
+func TestMetrics_BulkAttendance(t *testing.T) {
+	app := setupTestApp(t)
+
+	present := metrics.AttendanceRecordsWritten.WithLabelValues("PRESENT")
+	absent := metrics.AttendanceRecordsWritten.WithLabelValues("ABSENT")
+	batches := metrics.BatchSize.WithLabelValues("attendance_bulk").(prometheus.Histogram)
+	presentBefore := readMetric(t, present).GetCounter().GetValue()
+	absentBefore := readMetric(t, absent).GetCounter().GetValue()
+	batchesBefore := readMetric(t, batches).GetHistogram()
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-02-01", "status": "PRESENT"},
+		{"registration_id": 2, "date": "2024-02-01", "status": "PRESENT"},
+		{"registration_id": 3, "date": "2024-02-01", "status": "ABSENT"},
+	}
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if written := readMetric(t, present).GetCounter().GetValue() - presentBefore; written != 2 {
+		t.Errorf("Expected 2 PRESENT records counted, got %v", written)
+	}
+	if written := readMetric(t, absent).GetCounter().GetValue() - absentBefore; written != 1 {
+		t.Errorf("Expected 1 ABSENT record counted, got %v", written)
+	}
+
+	batchesAfter := readMetric(t, batches).GetHistogram()
+	if batchesAfter.GetSampleCount()-batchesBefore.GetSampleCount() != 1 || batchesAfter.GetSampleSum()-batchesBefore.GetSampleSum() != 3 {
+		t.Errorf("Expected one batch of 3 records, got %d batches totalling %v", batchesAfter.GetSampleCount()-batchesBefore.GetSampleCount(), batchesAfter.GetSampleSum()-batchesBefore.GetSampleSum())
+	}
+
+	queries := metrics.DBQueryDuration.WithLabelValues("create", "Attendance").(prometheus.Histogram)
+	if readMetric(t, queries).GetHistogram().GetSampleCount() == 0 {
+		t.Error("Expected the attendance inserts to be timed")
+	}
+}
+
+func TestMetrics_RejectedBulkAttendanceIsNotCounted(t *testing.T) {
+	app := setupTestApp(t)
+
+	present := metrics.AttendanceRecordsWritten.WithLabelValues("PRESENT")
+	before := readMetric(t, present).GetCounter().GetValue()
+
+	reqBody := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-02-02", "status": "PRESENT"},
+		{"registration_id": 4, "date": "2024-02-02", "status": "INVALID"},
+	}
+	resp, _ := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if written := readMetric(t, present).GetCounter().GetValue() - before; written != 0 {
+		t.Errorf("Expected no records counted for a rejected batch, got %v", written)
+	}
+}
@@ -2,6 +2,8 @@ package rest
 
 import (
 	"skulla-api/db"
+	"slices"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -17,13 +19,13 @@ func ListRegistrations(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, err.Error())
 	}
 
-	courseID, err := db.GetStudentClassCourseID(studentClassId)
+	courseID, err := db.GetStudentClassCourseID(c.UserContext(), studentClassId)
 	if err != nil {
 		return ReturnBadRequest(c, "Student class not found")
 	}
 
-	if db.IsTeacherEmailBelongToCourse(userEmail, int(courseID)) {
-		registrations := db.ListRegistrations(int(studentClassId))
+	if db.IsTeacherEmailBelongToCourse(c.UserContext(), userEmail, int(courseID)) || slices.Contains(db.DelegatedStudentClassIDs(c.UserContext(), userEmail, time.Now()), studentClassId) {
+		registrations := db.ListRegistrations(c.UserContext(), int(studentClassId))
 		return c.JSON(registrations)
 	}
 
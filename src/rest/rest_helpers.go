@@ -1,11 +1,13 @@
 package rest
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 )
 
 func ParseUintQueryParam(c *fiber.Ctx, paramName string, required bool) (uint, error) {
@@ -40,6 +42,20 @@ func ParseOptionalUintQueryParam(c *fiber.Ctx, paramName string) (*uint, error)
 	return &val, nil
 }
 
+func ParseBoolQueryParam(c *fiber.Ctx, paramName string) (bool, error) {
+	paramStr := c.Query(paramName)
+	if paramStr == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(paramStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s format. Use true or false", paramName)
+	}
+
+	return parsed, nil
+}
+
 func ParseDateQueryParam(c *fiber.Ctx, paramName string) (*time.Time, error) {
 	dateStr := c.Query(paramName)
 	if dateStr == "" {
@@ -67,6 +83,21 @@ func ValidateDateString(dateStr, paramName string) error {
 	return nil
 }
 
+// maxReportRangeDays caps the dates a report covers, since its trends hold an
+// entry for every week and month of the range.
+const maxReportRangeDays = 366
+
+// ValidateReportRange rejects a report range of more than maxReportRangeDays
+// days. Both dates must already be valid.
+func ValidateReportRange(startDate, endDate string) error {
+	start, _ := time.Parse("2006-01-02", startDate)
+	end, _ := time.Parse("2006-01-02", endDate)
+	if end.After(start.AddDate(0, 0, maxReportRangeDays-1)) {
+		return fmt.Errorf("date range must not span more than %d days", maxReportRangeDays)
+	}
+	return nil
+}
+
 func GetDateRangeWithDefaults(startDate, endDate string) (string, string) {
 	now := time.Now()
 
@@ -104,3 +135,22 @@ func ReturnUnauthorized(c *fiber.Ctx, message string) error {
 		"error": message,
 	})
 }
+
+func ReturnConflict(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// ReturnError writes err as a JSON error response, using the status of a
+// *fiber.Error and 500 for anything else.
+func ReturnError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	log.Error(err)
+	return ReturnInternalError(c, "Internal server error")
+}
@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"context"
 	"skulla-api/db"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -22,12 +24,36 @@ func ListStudentClass(c *fiber.Ctx) error {
 		return ReturnBadRequest(c, err.Error())
 	}
 
-	var courses = db.ListCoursesByTeacherEmail(userEmail)
+	courseIds := ownCourseIDs(c.UserContext(), userEmail)
+	delegatedIDs := db.DelegatedStudentClassIDs(c.UserContext(), userEmail, time.Now())
+	studentClass := db.ListStudentClasses(c.UserContext(), courseIds, delegatedIDs, startDate, endDate)
+	return c.JSON(studentClass)
+}
+
+// teacherStudentClassIDs returns the classes userEmail teaches or was
+// delegated.
+func teacherStudentClassIDs(ctx context.Context, userEmail string) []uint {
+	var studentClassIDs []uint
+	for _, studentClass := range db.ListStudentClasses(ctx, ownCourseIDs(ctx, userEmail), db.DelegatedStudentClassIDs(ctx, userEmail, time.Now()), nil, nil) {
+		studentClassIDs = append(studentClassIDs, studentClass.ID)
+	}
+	return studentClassIDs
+}
+
+// ownedStudentClassIDs returns the IDs of every student class belonging to a
+// course taught by userEmail, leaving out delegated classes.
+func ownedStudentClassIDs(ctx context.Context, userEmail string) []uint {
+	var studentClassIDs []uint
+	for _, studentClass := range db.ListStudentClasses(ctx, ownCourseIDs(ctx, userEmail), nil, nil, nil) {
+		studentClassIDs = append(studentClassIDs, studentClass.ID)
+	}
+	return studentClassIDs
+}
+
+func ownCourseIDs(ctx context.Context, userEmail string) []uint {
 	var courseIds []uint
-	for _, course := range courses {
+	for _, course := range db.ListCoursesByTeacherEmail(ctx, userEmail) {
 		courseIds = append(courseIds, course.ID)
 	}
-
-	studentClass := db.ListStudentClasses(courseIds, startDate, endDate)
-	return c.JSON(studentClass)
+	return courseIds
 }
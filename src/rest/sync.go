@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"fmt"
+	"skulla-api/db"
+	"skulla-api/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	maxSyncMutations   = 500
+	syncAttendancePage = 1000
+)
+
+type SyncMutationRequest struct {
+	ClientID        string     `json:"client_id"`
+	ClientTimestamp *time.Time `json:"client_timestamp"`
+	BaseVersion     int        `json:"base_version"`
+	RegistrationID  uint       `json:"registration_id"`
+	Date            string     `json:"date"`
+	Status          string     `json:"status"`
+	Remarks         string     `json:"remarks"`
+}
+
+type SyncRequest struct {
+	Cursor    string                `json:"cursor"`
+	Mutations []SyncMutationRequest `json:"mutations"`
+}
+
+type SyncResponse struct {
+	Results    []db.MutationResult  `json:"results"`
+	Conflicts  []db.MutationResult  `json:"conflicts"`
+	Attendance []db.AttendanceState `json:"attendance"`
+	Roster     []db.SyncRosterClass `json:"roster,omitempty"`
+	Cursor     string               `json:"cursor"`
+	HasMore    bool                 `json:"has_more"`
+}
+
+// parseSyncCursor splits a cursor of the form <change id>.<roster fingerprint>.
+// An empty cursor asks for everything.
+func parseSyncCursor(cursor string) (uint, string, error) {
+	if cursor == "" {
+		return 0, "", nil
+	}
+	changeID, fingerprint, found := strings.Cut(cursor, ".")
+	parsed, err := strconv.ParseUint(changeID, 10, 64)
+	if !found || err != nil {
+		return 0, "", fmt.Errorf("invalid cursor")
+	}
+	return uint(parsed), fingerprint, nil
+}
+
+func validateSyncMutation(req SyncMutationRequest, index int) error {
+	if req.ClientID == "" || len(req.ClientID) > 100 {
+		return fmt.Errorf("mutation %d: client_id is required and must be at most 100 characters", index)
+	}
+
+	if req.ClientTimestamp == nil {
+		return fmt.Errorf("mutation %d: client_timestamp is required", index)
+	}
+
+	if req.BaseVersion < 0 {
+		return fmt.Errorf("mutation %d: base_version cannot be negative", index)
+	}
+
+	if req.RegistrationID == 0 {
+		return fmt.Errorf("mutation %d: registration_id is required", index)
+	}
+
+	if req.Date == "" {
+		return fmt.Errorf("mutation %d: date is required", index)
+	}
+
+	if err := ValidateDateString(req.Date, "date"); err != nil {
+		return fmt.Errorf("mutation %d: %v", index, err)
+	}
+
+	if !validStatuses[req.Status] {
+		return fmt.Errorf("mutation %d: status must be one of: PRESENT, ABSENT, LATE, EXCUSED", index)
+	}
+
+	return nil
+}
+
+// Sync applies offline mutations and returns the changes since the cursor.
+func Sync(c *fiber.Ctx) error {
+	var req SyncRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	after, fingerprint, err := parseSyncCursor(req.Cursor)
+	if err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	if len(req.Mutations) > 0 {
+		metrics.BatchSize.WithLabelValues("sync").Observe(float64(len(req.Mutations)))
+	}
+	if len(req.Mutations) > maxSyncMutations {
+		return ReturnBadRequest(c, fmt.Sprintf("At most %d mutations can be sent at once", maxSyncMutations))
+	}
+
+	for i, mutation := range req.Mutations {
+		if err := validateSyncMutation(mutation, i); err != nil {
+			return ReturnBadRequest(c, err.Error())
+		}
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnUnauthorized(c, "Unable to extract user email from token")
+	}
+
+	studentClassIDs := teacherStudentClassIDs(c.UserContext(), userEmail)
+
+	response := SyncResponse{
+		Results:   []db.MutationResult{},
+		Conflicts: []db.MutationResult{},
+	}
+
+	if len(req.Mutations) > 0 {
+		mutations := make([]db.AttendanceMutation, 0, len(req.Mutations))
+		for _, mutation := range req.Mutations {
+			mutations = append(mutations, db.AttendanceMutation{
+				ClientID:        mutation.ClientID,
+				ClientTimestamp: *mutation.ClientTimestamp,
+				BaseVersion:     mutation.BaseVersion,
+				RegistrationID:  mutation.RegistrationID,
+				Date:            mutation.Date,
+				Status:          mutation.Status,
+				Remarks:         mutation.Remarks,
+			})
+		}
+
+		response.Results, err = db.ApplyAttendanceMutations(c.UserContext(), mutations, studentClassIDs, userEmail)
+		if err != nil {
+			log.Error(err)
+			return ReturnInternalError(c, "Failed to apply mutations. All mutations have been rolled back.")
+		}
+
+		for _, result := range response.Results {
+			if result.Outcome == db.SyncConflict {
+				response.Conflicts = append(response.Conflicts, result)
+			}
+		}
+	}
+
+	roster := db.GetRoster(c.UserContext(), studentClassIDs)
+	currentFingerprint := db.RosterFingerprint(roster)
+	if currentFingerprint != fingerprint {
+		response.Roster = roster
+	}
+
+	var cursor uint
+	response.Attendance, cursor, response.HasMore = db.ListAttendanceChanges(c.UserContext(), studentClassIDs, after, time.Now().Add(-settings.Sync.ChangeLag), syncAttendancePage)
+	response.Cursor = fmt.Sprintf("%d.%s", cursor, currentFingerprint)
+
+	return c.JSON(response)
+}
@@ -0,0 +1,217 @@
+package rest
+
+import (
+	"encoding/json"
+	"skulla-api/db"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type syncTestResponse struct {
+	Results []struct {
+		ClientID string `json:"client_id"`
+		Outcome  string `json:"outcome"`
+		Version  int    `json:"version"`
+		Server   *struct {
+			Status  string `json:"status"`
+			Version int    `json:"version"`
+		} `json:"server"`
+	} `json:"results"`
+	Conflicts  []json.RawMessage `json:"conflicts"`
+	Attendance []struct {
+		RegistrationID uint   `json:"registration_id"`
+		Date           string `json:"date"`
+		Status         string `json:"status"`
+		Version        int    `json:"version"`
+	} `json:"attendance"`
+	Roster []struct {
+		StudentClassID uint `json:"student_class_id"`
+	} `json:"roster"`
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"has_more"`
+}
+
+var syncBaseTime = time.Date(2024, 1, 20, 8, 0, 0, 0, time.UTC)
+
+func syncMutation(clientID string, offset time.Duration, baseVersion int, registrationID uint, date string, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"client_id":        clientID,
+		"client_timestamp": syncBaseTime.Add(offset),
+		"base_version":     baseVersion,
+		"registration_id":  registrationID,
+		"date":             date,
+		"status":           status,
+	}
+}
+
+func syncAs(t *testing.T, app *fiber.App, email string, cursor string, mutations []map[string]interface{}) syncTestResponse {
+	resp, err := makeRequest(app, "POST", "/sync", email, map[string]interface{}{"cursor": cursor, "mutations": mutations})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	var body syncTestResponse
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	return body
+}
+
+func TestSync_InitialAndDelta(t *testing.T) {
+	app := setupTestApp(t)
+
+	initial := syncAs(t, app, testTeacherEmail, "", nil)
+	if len(initial.Roster) != 3 {
+		t.Errorf("Expected roster of 3 classes, got %d", len(initial.Roster))
+	}
+	if len(initial.Attendance) != 7 || initial.HasMore {
+		t.Errorf("Expected all 7 attendance records, got %d (has_more %v)", len(initial.Attendance), initial.HasMore)
+	}
+
+	unchanged := syncAs(t, app, testTeacherEmail, initial.Cursor, nil)
+	if len(unchanged.Roster) != 0 || len(unchanged.Attendance) != 0 {
+		t.Errorf("Expected empty delta, got roster %d and attendance %d", len(unchanged.Roster), len(unchanged.Attendance))
+	}
+
+	reqBody := map[string]interface{}{"registration_id": 2, "date": "2024-01-17", "status": "ABSENT"}
+	if resp, _ := makeRequest(app, "POST", "/attendance", testTeacherEmail, reqBody); resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.Code)
+	}
+
+	delta := syncAs(t, app, testTeacherEmail, unchanged.Cursor, nil)
+	if len(delta.Attendance) != 1 || delta.Attendance[0].RegistrationID != 2 || delta.Attendance[0].Version != 1 {
+		t.Errorf("Expected the new record in the delta, got %+v", delta.Attendance)
+	}
+}
+
+func TestSync_Mutations(t *testing.T) {
+	app := setupTestApp(t)
+
+	body := syncAs(t, app, testTeacherEmail, "", []map[string]interface{}{
+		syncMutation("new", 0, 0, 3, "2024-01-15", "ABSENT"),
+		syncMutation("stale", time.Minute, 0, 1, "2024-01-15", "ABSENT"),
+		syncMutation("converged", 2*time.Minute, 0, 4, "2024-01-15", "PRESENT"),
+		syncMutation("first-edit", 3*time.Minute, 1, 2, "2024-01-15", "LATE"),
+		syncMutation("second-edit", 4*time.Minute, 1, 2, "2024-01-15", "EXCUSED"),
+	})
+
+	expected := map[string]struct {
+		outcome string
+		version int
+	}{
+		"new":         {"APPLIED", 1},
+		"stale":       {"CONFLICT", 1},
+		"converged":   {"APPLIED", 1},
+		"first-edit":  {"APPLIED", 2},
+		"second-edit": {"APPLIED", 3},
+	}
+	for _, result := range body.Results {
+		if want := expected[result.ClientID]; result.Outcome != want.outcome || result.Version != want.version {
+			t.Errorf("%s: expected %s at version %d, got %s at version %d", result.ClientID, want.outcome, want.version, result.Outcome, result.Version)
+		}
+		if result.ClientID == "stale" && (result.Server == nil || result.Server.Status != "PRESENT") {
+			t.Errorf("Expected conflict to carry the server record, got %+v", result.Server)
+		}
+	}
+	if len(body.Conflicts) != 1 {
+		t.Errorf("Expected 1 conflict, got %d", len(body.Conflicts))
+	}
+
+	for _, attendance := range body.Attendance {
+		if attendance.RegistrationID == 2 && attendance.Date == "2024-01-15" && (attendance.Status != "EXCUSED" || attendance.Version != 3) {
+			t.Errorf("Expected chained edits to end as EXCUSED at version 3, got %+v", attendance)
+		}
+	}
+
+	retry := syncAs(t, app, testTeacherEmail, body.Cursor, []map[string]interface{}{
+		syncMutation("new", 0, 0, 3, "2024-01-15", "ABSENT"),
+	})
+	if len(retry.Results) != 1 || retry.Results[0].Outcome != "DUPLICATE" || retry.Results[0].Version != 1 {
+		t.Errorf("Expected retried mutation to be a duplicate, got %+v", retry.Results)
+	}
+	if len(retry.Attendance) != 0 {
+		t.Errorf("Expected retried mutation not to write, got %+v", retry.Attendance)
+	}
+}
+
+func TestSync_ClientIDsArePerUser(t *testing.T) {
+	app := setupTestApp(t)
+	createTestDelegation(t, app, map[string]interface{}{
+		"grantee_email":     testTeacherEmail2,
+		"student_class_ids": []uint{1},
+		"valid_to":          time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	})
+
+	first := syncAs(t, app, testTeacherEmail, "", []map[string]interface{}{
+		syncMutation("m1", 0, 0, 3, "2024-01-18", "ABSENT"),
+	})
+	second := syncAs(t, app, testTeacherEmail2, "", []map[string]interface{}{
+		syncMutation("m1", 0, 0, 2, "2024-01-18", "ABSENT"),
+	})
+
+	for _, body := range []syncTestResponse{first, second} {
+		if len(body.Results) != 1 || body.Results[0].Outcome != "APPLIED" {
+			t.Errorf("Expected the mutation to be applied, got %+v", body.Results)
+		}
+	}
+}
+
+func TestSync_HoldsBackUnsettledChanges(t *testing.T) {
+	setupTestApp(t)
+
+	if attendances, _, _ := db.ListAttendanceChanges(testSchoolContext(), []uint{1}, 0, time.Now().Add(-time.Hour), 100); len(attendances) != 0 {
+		t.Errorf("Expected changes younger than the lag to be held back, got %d", len(attendances))
+	}
+	if attendances, _, _ := db.ListAttendanceChanges(testSchoolContext(), []uint{1}, 0, time.Now(), 100); len(attendances) != 5 {
+		t.Errorf("Expected the 5 settled records of class 1, got %d", len(attendances))
+	}
+}
+
+func TestSync_RejectsOtherClasses(t *testing.T) {
+	app := setupTestApp(t)
+
+	body := syncAs(t, app, testTeacherEmail2, "", []map[string]interface{}{
+		syncMutation("foreign", 0, 1, 1, "2024-01-15", "ABSENT"),
+		syncMutation("missing", 0, 0, 999, "2024-01-15", "ABSENT"),
+	})
+
+	for _, result := range body.Results {
+		if result.Outcome != "REJECTED" {
+			t.Errorf("%s: expected REJECTED, got %s", result.ClientID, result.Outcome)
+		}
+	}
+	if len(body.Attendance) != 0 {
+		t.Errorf("Expected no attendance for a teacher without registrations, got %d", len(body.Attendance))
+	}
+}
+
+func TestSync_ValidationErrors(t *testing.T) {
+	app := setupTestApp(t)
+
+	missingTimestamp := syncMutation("a", 0, 0, 1, "2024-01-15", "PRESENT")
+	delete(missingTimestamp, "client_timestamp")
+
+	testCases := []map[string]interface{}{
+		{"cursor": "not-a-cursor"},
+		{"mutations": []map[string]interface{}{missingTimestamp}},
+		{"mutations": []map[string]interface{}{syncMutation("", 0, 0, 1, "2024-01-15", "PRESENT")}},
+		{"mutations": []map[string]interface{}{syncMutation("a", 0, -1, 1, "2024-01-15", "PRESENT")}},
+		{"mutations": []map[string]interface{}{syncMutation("a", 0, 0, 1, "15/01/2024", "PRESENT")}},
+		{"mutations": []map[string]interface{}{syncMutation("a", 0, 0, 1, "", "PRESENT")}},
+		{"mutations": []map[string]interface{}{syncMutation("a", 0, 0, 1, "2024-01-15", "HERE")}},
+	}
+
+	for _, reqBody := range testCases {
+		resp, err := makeRequest(app, "POST", "/sync", testTeacherEmail, reqBody)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400 for %v, got %d", reqBody, resp.Code)
+		}
+	}
+}
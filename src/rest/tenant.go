@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"errors"
+	"skulla-api/db"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// schoolClaim returns the school_id claim of the token, read either from the
+// top level or from Supabase's app_metadata.
+func schoolClaim(claims jwt.MapClaims) (uint, bool) {
+	value, exists := claims["school_id"]
+	if !exists {
+		if metadata, ok := claims["app_metadata"].(map[string]interface{}); ok {
+			value, exists = metadata["school_id"]
+		}
+	}
+	if !exists {
+		return 0, false
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return uint(v), v > 0 && v == float64(uint(v))
+	case string:
+		id, err := strconv.ParseUint(v, 10, 32)
+		return uint(id), err == nil && id > 0
+	}
+	return 0, false
+}
+
+// requestSubdomain returns the part of the host in front of the base domain.
+func requestSubdomain(host string) string {
+	baseDomain := settings.Tenant.BaseDomain
+	if baseDomain == "" {
+		return ""
+	}
+	subdomain, found := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !found || strings.Contains(subdomain, ".") {
+		return ""
+	}
+	return subdomain
+}
+
+// resolveSchool finds the school from the token's school_id claim. The
+// subdomain only has to agree with it.
+func resolveSchool(c *fiber.Ctx, claims jwt.MapClaims) (uint, error) {
+	schoolID, hasClaim := schoolClaim(claims)
+	if !hasClaim {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, "Unable to determine school")
+	}
+
+	if subdomain := requestSubdomain(c.Hostname()); subdomain != "" {
+		school, err := db.GetSchoolBySubdomain(c.UserContext(), subdomain)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fiber.NewError(fiber.StatusUnauthorized, "Unknown school")
+		}
+		if err != nil {
+			log.Error(err)
+			return 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to resolve school")
+		}
+		if school.ID != schoolID {
+			return 0, fiber.NewError(fiber.StatusUnauthorized, "Token does not belong to this school")
+		}
+		return school.ID, nil
+	}
+
+	if _, err := db.GetSchool(c.UserContext(), schoolID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, "Unknown school")
+	} else if err != nil {
+		log.Error(err)
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to resolve school")
+	}
+	return schoolID, nil
+}
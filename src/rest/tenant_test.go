@@ -0,0 +1,189 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"skulla-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// otherSchoolMarkers appear only in rows seeded for the other school.
+var otherSchoolMarkers = []string{"Other school", "Biology", "Other Guardian", "other.example.com", "Other kiosk", "Other Student"}
+
+func otherSchoolContext() context.Context {
+	return db.WithSchool(context.Background(), otherSchoolID)
+}
+
+func TestTenant_EndpointsDoNotReachOtherSchool(t *testing.T) {
+	app := setupTestApp(t)
+
+	window := db.CheckInWindow{StudentClassID: 101, Date: "2024-01-15", Secret: "secret", OpensAt: time.Now(), LateAfter: time.Now(), ClosesAt: time.Now().Add(time.Hour)}
+	if err := db.CreateCheckInWindow(otherSchoolContext(), &window); err != nil {
+		t.Fatalf("Failed to create check-in window: %v", err)
+	}
+
+	testCases := []struct {
+		method   string
+		path     string
+		email    string
+		body     interface{}
+		expected int
+	}{
+		{"GET", "/student-classes", testTeacherEmail, nil, fiber.StatusOK},
+		{"GET", "/student-classes/101/schedule", testTeacherEmail, nil, fiber.StatusUnauthorized},
+		{"PUT", "/student-classes/101/schedule", testTeacherEmail, []map[string]interface{}{}, fiber.StatusUnauthorized},
+		{"GET", "/student-classes/101/registers", testTeacherEmail, nil, fiber.StatusUnauthorized},
+		{"POST", "/student-classes/101/registers/2024-01-15/submit", testTeacherEmail, nil, fiber.StatusUnauthorized},
+		{"POST", "/student-classes/101/registers/2024-01-15/lock", testTeacherEmail, nil, fiber.StatusUnauthorized},
+		{"GET", "/registrations?studentClassId=101", testTeacherEmail, nil, fiber.StatusBadRequest},
+		{"GET", "/attendance?registration_id=101&date=2024-01-15", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"POST", "/attendance", testTeacherEmail, map[string]interface{}{"registration_id": 101, "date": "2024-01-16", "status": "PRESENT"}, fiber.StatusNotFound},
+		{"POST", "/attendance/bulk", testTeacherEmail, []map[string]interface{}{{"registration_id": 101, "date": "2024-01-16", "status": "PRESENT"}}, fiber.StatusBadRequest},
+		{"POST", "/attendance/class", testTeacherEmail, map[string]interface{}{"student_class_id": 101, "date": "2024-01-16", "status": "PRESENT"}, fiber.StatusUnauthorized},
+		{"POST", "/sync", testTeacherEmail, map[string]interface{}{}, fiber.StatusOK},
+		{"GET", "/attendance/report?student_id=101&start_date=2024-01-01&end_date=2024-01-31&include_drafts=true", testTeacherEmail, nil, fiber.StatusOK},
+		{"GET", "/attendance/class-report?student_class_id=101&start_date=2024-01-01&end_date=2024-01-31&include_drafts=true", testTeacherEmail, nil, fiber.StatusOK},
+		{"GET", "/attendance/course-report?course_id=101&start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"GET", "/attendance/school-report?start_date=2024-01-01&end_date=2024-01-31&include_drafts=true", testTeacherEmail, nil, fiber.StatusOK},
+		{"GET", "/attendance/live?student_class_id=101", testTeacherEmail, nil, fiber.StatusUnauthorized},
+		{"POST", "/check-in-windows", testTeacherEmail, map[string]interface{}{"student_class_id": 101}, fiber.StatusUnauthorized},
+		{"GET", "/check-in-windows/1/token", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"GET", "/check-in-windows/1/qr", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"POST", "/check-in", testStudentEmail, map[string]interface{}{"token": "1.0.invalid", "device_id": "phone"}, fiber.StatusBadRequest},
+		{"GET", "/kiosk-devices", testTeacherEmail, nil, fiber.StatusOK},
+		{"GET", "/alerts", testTeacherEmail, nil, fiber.StatusOK},
+		{"POST", "/alerts/101/acknowledge", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"GET", "/guardians?student_id=101", testTeacherEmail, nil, fiber.StatusUnauthorized},
+		{"POST", "/guardians", testTeacherEmail, map[string]interface{}{"student_id": 101, "name": "Intruder"}, fiber.StatusNotFound},
+		{"PUT", "/guardians/101", testTeacherEmail, map[string]interface{}{"student_id": 1, "name": "Intruder"}, fiber.StatusNotFound},
+		{"GET", "/delegations", testTeacherEmail, nil, fiber.StatusOK},
+		{"POST", "/delegations", testTeacherEmail, map[string]interface{}{"grantee_email": testTeacherEmail2, "student_class_ids": []uint{101}, "valid_to": time.Now().Add(time.Hour).Format(time.RFC3339)}, fiber.StatusUnauthorized},
+		{"POST", "/delegations/101/revoke", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"GET", "/webhooks", testTeacherEmail, nil, fiber.StatusOK},
+		{"DELETE", "/webhooks/101", testTeacherEmail, nil, fiber.StatusNotFound},
+		{"GET", "/webhooks/dead-letters", testTeacherEmail, nil, fiber.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		resp, err := makeRequest(app, testCase.method, testCase.path, testCase.email, testCase.body)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", testCase.method, testCase.path, err)
+		}
+
+		if resp.Code != testCase.expected {
+			t.Errorf("%s %s: Expected status %d, got %d. Body: %s", testCase.method, testCase.path, testCase.expected, resp.Code, resp.Body.String())
+		}
+		for _, marker := range otherSchoolMarkers {
+			if strings.Contains(resp.Body.String(), marker) {
+				t.Errorf("%s %s: Response leaks %q from the other school: %s", testCase.method, testCase.path, marker, resp.Body.String())
+			}
+		}
+	}
+
+	state, _ := db.GetAttendanceState(otherSchoolContext(), 101, "2024-01-16")
+	if state != nil {
+		t.Errorf("Expected no attendance written to the other school, got %+v", state)
+	}
+}
+
+func TestTenant_OtherSchoolSeesOnlyItsOwnData(t *testing.T) {
+	app := setupTestApp(t)
+
+	headers := map[string]string{"Authorization": "Bearer " + createTestJWTForSchool(testTeacherEmail, otherSchoolID)}
+	resp, err := makeRequestWithHeaders(app, "GET", "/student-classes", "", nil, headers)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var classes []db.StudentClass
+	json.Unmarshal(resp.Body.Bytes(), &classes)
+	if len(classes) != 1 || classes[0].ID != 101 || classes[0].SchoolID != otherSchoolID {
+		t.Errorf("Expected only the other school's class, got %+v", classes)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", "", map[string]interface{}{"registration_id": 1, "date": "2024-01-16", "status": "PRESENT"}, headers)
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for a registration of another school, got %d", resp.Code)
+	}
+
+	resp, _ = makeRequestWithHeaders(app, "POST", "/attendance", "", map[string]interface{}{"registration_id": 101, "date": "2024-01-16", "status": "PRESENT"}, headers)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 101, "2024-01-16"); state != nil {
+		t.Errorf("Expected the other school's attendance to be invisible to the test school")
+	}
+	if state, _ := db.GetAttendanceState(otherSchoolContext(), 101, "2024-01-16"); state == nil || state.Status != "PRESENT" {
+		t.Errorf("Expected the attendance in the other school, got %+v", state)
+	}
+}
+
+func TestTenant_KioskActsOnItsOwnSchool(t *testing.T) {
+	app := setupTestApp(t)
+
+	device := db.KioskDevice{Name: "Gate"}
+	key, err := db.CreateKioskDevice(otherSchoolContext(), &device)
+	if err != nil {
+		t.Fatalf("Failed to create kiosk device: %v", err)
+	}
+
+	swipedAt := time.Now()
+	schedule := []db.ClassSchedule{{StudentClassID: 101, Weekday: int(swipedAt.Weekday()), StartTime: "00:00", EndTime: "23:59"}}
+	if err := db.ReplaceClassSchedule(otherSchoolContext(), 101, schedule); err != nil {
+		t.Fatalf("Failed to save schedule: %v", err)
+	}
+
+	resp, err := makeRequestWithHeaders(app, "POST", "/kiosk/swipes", "", map[string]interface{}{"card_id": "CARD-JOHN"}, map[string]string{KioskKeyHeader: key})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK && resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected the swipe to be accepted, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	date := swipedAt.Format("2006-01-02")
+	if state, _ := db.GetAttendanceState(otherSchoolContext(), 101, date); state == nil {
+		t.Errorf("Expected the swipe to mark the other school's registration")
+	}
+	if state, _ := db.GetAttendanceState(testSchoolContext(), 1, date); state != nil {
+		t.Errorf("Expected the swipe not to mark the test school's registration, got %+v", state)
+	}
+}
+
+func TestTenant_ResolvesSchoolFromClaimOrSubdomain(t *testing.T) {
+	app := setupTestApp(t)
+	settings.Tenant.BaseDomain = "api.test"
+
+	withoutSchool := createTestJWTForSchool(testTeacherEmail, 0)
+	testCases := []struct {
+		host     string
+		token    string
+		expected int
+	}{
+		{"", withoutSchool, fiber.StatusUnauthorized},
+		{"", createTestJWTForSchool(testTeacherEmail, 99), fiber.StatusUnauthorized},
+		{"other.api.test", withoutSchool, fiber.StatusUnauthorized},
+		{"other.api.test", createTestJWTForSchool(testTeacherEmail, otherSchoolID), fiber.StatusOK},
+		{"other.api.test", createTestJWT(testTeacherEmail), fiber.StatusUnauthorized},
+		{"unknown.api.test", withoutSchool, fiber.StatusUnauthorized},
+	}
+
+	for i, testCase := range testCases {
+		headers := map[string]string{"Authorization": "Bearer " + testCase.token}
+		if testCase.host != "" {
+			headers["Host"] = testCase.host
+		}
+		resp, err := makeRequestWithHeaders(app, "GET", "/student-classes", "", nil, headers)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		if resp.Code != testCase.expected {
+			t.Errorf("Test case %d: Expected status %d, got %d. Body: %s", i, testCase.expected, resp.Code, resp.Body.String())
+		}
+	}
+}
@@ -2,38 +2,79 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http/httptest"
 	"os"
+	"skulla-api/config"
 	"skulla-api/db"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/log"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 const testTeacherEmail = "teacher@test.com"
 const testTeacherEmail2 = "teacher2@test.com"
+const testStudentEmail = "john@test.com"
+const testStudentEmail2 = "jane@test.com"
 
+// testRoles is the role in the tokens of the test users. The first teacher
+// also administers the school.
+var testRoles = map[string]string{
+	testTeacherEmail:  RoleAdmin,
+	testTeacherEmail2: RoleStaff,
+	testStudentEmail:  "student",
+	testStudentEmail2: "student",
+}
+
+const testSchoolID = 1
+const otherSchoolID = 2
+
+// testSchoolContext scopes direct db calls in tests to the school the test
+// requests act on.
+func testSchoolContext() context.Context {
+	return db.WithSchool(context.Background(), testSchoolID)
+}
+
+// setupTestDB opens and migrates SQLite, or the database of TEST_POSTGRES_DSN
+// or TEST_MYSQL_DSN.
 func setupTestDB() (*gorm.DB, error) {
-	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	dialector := sqlite.Open(":memory:")
+	dsn, usePostgres := os.LookupEnv("TEST_POSTGRES_DSN")
+	if usePostgres {
+		dialector = postgres.Open(dsn)
+	}
+	if mysqlDSN, useMySQL := os.LookupEnv("TEST_MYSQL_DSN"); useMySQL && !usePostgres {
+		var err error
+		if dialector, err = mysqlTestDialector(mysqlDSN); err != nil {
+			return nil, err
+		}
+	}
+
+	testDB, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 
-	err = testDB.AutoMigrate(
-		&db.Course{},
-		&db.Period{},
-		&db.StudentClass{},
-		&db.Student{},
-		&db.Registration{},
-		&db.Attendance{},
-	)
+	if usePostgres {
+		if err := testDB.Exec("DROP SCHEMA public CASCADE").Error; err != nil {
+			return nil, err
+		}
+		if err := testDB.Exec("CREATE SCHEMA public").Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err = testDB.AutoMigrate(db.Models()...)
 	if err != nil {
 		return nil, err
 	}
@@ -41,9 +82,70 @@ func setupTestDB() (*gorm.DB, error) {
 	return testDB, nil
 }
 
+// mysqlTestDatabase is recreated on the server of TEST_MYSQL_DSN for every test.
+const mysqlTestDatabase = "rest_test"
+
+// mysqlTestDialector empties mysqlTestDatabase on the server of dsn, e.g.
+// "root:root@tcp(localhost:3306)/", and connects to it the way the API does.
+func mysqlTestDialector(dsn string) (gorm.Dialector, error) {
+	server, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	if sqlDB, err := server.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	for _, statement := range []string{
+		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", mysqlTestDatabase),
+		fmt.Sprintf("CREATE DATABASE `%s`", mysqlTestDatabase),
+	} {
+		if err := server.Exec(statement).Error; err != nil {
+			return nil, err
+		}
+	}
+	return db.Dialector(config.Database{Driver: db.DriverMySQL, URL: dsn + mysqlTestDatabase + "?parseTime=True"})
+}
+
+// resetSequences moves PostgreSQL's id sequences past the seeded rows, which
+// are created with explicit IDs.
+func resetSequences(testDB *gorm.DB) error {
+	if testDB.Dialector.Name() != db.DriverPostgres {
+		return nil
+	}
+
+	for _, model := range db.Models() {
+		if !testDB.Migrator().HasColumn(model, "id") {
+			continue
+		}
+		table := model.(interface{ TableName() string }).TableName()
+		err := testDB.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('"%s"', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM "%s"`, table, table)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func seedTestData(testDB *gorm.DB) error {
 	now := time.Now()
 
+	schools := []db.School{
+		{ID: testSchoolID, Name: "Test School", Subdomain: "test"},
+		{ID: otherSchoolID, Name: "Other School", Subdomain: "other"},
+	}
+	for _, school := range schools {
+		if err := testDB.Create(&school).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := seedOtherSchool(testDB.WithContext(db.WithSchool(context.Background(), otherSchoolID)), now); err != nil {
+		return err
+	}
+
+	testDB = testDB.WithContext(testSchoolContext())
+
 	courses := []db.Course{
 		{ID: 1, Name: "Mathematics", TeacherEmail: testTeacherEmail},
 		{ID: 2, Name: "Physics", TeacherEmail: testTeacherEmail},
@@ -80,8 +182,8 @@ func seedTestData(testDB *gorm.DB) error {
 	}
 
 	students := []db.Student{
-		{ID: 1, FirstName: "John", LastName: "Doe"},
-		{ID: 2, FirstName: "Jane", LastName: "Smith"},
+		{ID: 1, FirstName: "John", LastName: "Doe", Email: testStudentEmail, CardID: "CARD-JOHN"},
+		{ID: 2, FirstName: "Jane", LastName: "Smith", Email: testStudentEmail2, CardID: "CARD-JANE"},
 		{ID: 3, FirstName: "Bob", LastName: "Johnson"},
 	}
 	for _, student := range students {
@@ -116,39 +218,98 @@ func seedTestData(testDB *gorm.DB) error {
 		if err := testDB.Create(&att).Error; err != nil {
 			return err
 		}
+		change := db.AttendanceChange{RegistrationID: att.RegistrationID, Date: att.Date}
+		if err := testDB.Create(&change).Error; err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func setupTestApp(t *testing.T) *fiber.App {
-	err := os.Setenv("TEST_MODE", "true")
-	if err != nil {
-		log.Error(err.Error())
-		return nil
+// seedOtherSchool seeds a second school that shares emails and card IDs with
+// the test school.
+func seedOtherSchool(testDB *gorm.DB, now time.Time) error {
+	rows := []interface{}{
+		&db.Course{ID: 101, Name: "Biology", TeacherEmail: testTeacherEmail},
+		&db.Period{ID: 101, Start: now.AddDate(0, -2, 0), End: now.AddDate(0, 2, 0)},
+		&db.StudentClass{ID: 101, Name: "Biology 101", CourseID: 101, PeriodId: 101},
+		&db.Student{ID: 101, FirstName: "Other", LastName: "Student", Email: testStudentEmail, CardID: "CARD-JOHN"},
+		&db.Registration{ID: 101, StudentID: 101, StudentClassID: 101, Status: "ACTIVE"},
+		&db.Attendance{ID: 101, RegistrationID: 101, Date: "2024-01-15", Status: "ABSENT", Remarks: "Other school"},
+		&db.AttendanceChange{RegistrationID: 101, Date: "2024-01-15"},
+		&db.Guardian{ID: 101, StudentID: 101, Name: "Other Guardian", Email: "other@test.com", Channels: "EMAIL"},
+		&db.Alert{ID: 101, RegistrationID: 101, StudentClassID: 101, Rule: "consecutive_absences", Severity: "WARNING", Message: "Other school", TriggeredOn: "2024-01-15"},
+		&db.WebhookSubscription{ID: 101, URL: "https://other.example.com/hook", Secret: "0123456789abcdef", Events: "attendance.recorded"},
+		&db.KioskDevice{ID: 101, Name: "Other kiosk", KeyHash: "other-school-key-hash"},
+		&db.ClassSchedule{ID: 101, StudentClassID: 101, Weekday: 1, StartTime: "08:00", EndTime: "09:00"},
+		&db.Delegation{ID: 101, GrantorEmail: testTeacherEmail, GranteeEmail: testTeacherEmail2, ValidFrom: now.AddDate(0, 0, -1), ValidTo: now.AddDate(0, 0, 1),
+			StudentClasses: []db.DelegationStudentClass{{StudentClassID: 101}}},
 	}
+	for _, row := range rows {
+		if err := testDB.Create(row).Error; err != nil {
+			return err
+		}
+	}
+	return db.RebuildDailyClassAttendanceSummaries(testDB.Statement.Context)
+}
+
+// testConfig is the default configuration in test mode, so tokens signed
+// with "test-secret" are accepted.
+func testConfig() config.Config {
+	testConfig := config.Default()
+	testConfig.Auth.TestMode = true
+	testConfig.Webhooks.AllowPrivateHosts = true
+	testConfig.Sync.ChangeLag = 0
+	return testConfig
+}
 
+func setupTestApp(t *testing.T, middleware ...fiber.Handler) *fiber.App {
 	testDB, err := setupTestDB()
 	if err != nil {
 		t.Fatalf("Failed to setup test database: %v", err)
 	}
 
+	db.SetDB(testDB)
+	t.Cleanup(func() {
+		if sqlDB, err := testDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
 	if err := seedTestData(testDB); err != nil {
 		t.Fatalf("Failed to seed test data: %v", err)
 	}
 
-	db.SetDB(testDB)
+	if err := resetSequences(testDB); err != nil {
+		t.Fatalf("Failed to reset sequences: %v", err)
+	}
+
+	if err := db.RebuildDailyClassAttendanceSummaries(testSchoolContext()); err != nil {
+		t.Fatalf("Failed to build attendance summaries: %v", err)
+	}
 
 	app := fiber.New()
-	Init(app)
+	for _, handler := range middleware {
+		app.Use(handler)
+	}
+	Init(app, testConfig())
 
 	return app
 }
 
 func createTestJWT(email string) string {
+	return createTestJWTForSchool(email, testSchoolID)
+}
+
+func createTestJWTForSchool(email string, schoolID uint) string {
 	claims := jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
+		"email":     email,
+		"school_id": schoolID,
+		"exp":       time.Now().Add(time.Hour * 24).Unix(),
+	}
+	if role, ok := testRoles[email]; ok {
+		claims["app_metadata"] = map[string]interface{}{"role": role}
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, _ := token.SignedString([]byte("test-secret"))
@@ -156,6 +317,10 @@ func createTestJWT(email string) string {
 }
 
 func makeRequest(app *fiber.App, method, path, authEmail string, body interface{}) (*httptest.ResponseRecorder, error) {
+	return makeRequestWithHeaders(app, method, path, authEmail, body, nil)
+}
+
+func makeRequestWithHeaders(app *fiber.App, method, path, authEmail string, body interface{}, headers map[string]string) (*httptest.ResponseRecorder, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -172,12 +337,23 @@ func makeRequest(app *fiber.App, method, path, authEmail string, body interface{
 		req.Header.Set("Authorization", "Bearer "+createTestJWT(authEmail))
 	}
 
+	for name, value := range headers {
+		if name == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
 	resp, err := app.Test(req, -1)
 	if err != nil {
 		return nil, err
 	}
 
 	rec := httptest.NewRecorder()
+	for name, values := range resp.Header {
+		rec.Header()[name] = values
+	}
 	bodyBytes, _ := io.ReadAll(resp.Body)
 	rec.Body.Write(bodyBytes)
 	rec.Code = resp.StatusCode
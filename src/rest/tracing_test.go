@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"skulla-api/metrics"
+	"skulla-api/tracing"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTestTracing records every span in memory until the test ends.
+func setupTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func TestTracing_RequestSpans(t *testing.T) {
+	exporter := setupTestTracing(t)
+	app := setupTestApp(t, tracing.Middleware)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	resp, err := makeRequestWithHeaders(app, "GET", "/attendance/class-report?student_class_id=1&start_date=2024-01-01&end_date=2024-01-31", testTeacherEmail, nil, map[string]string{"traceparent": traceparent})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	spans := exporter.GetSpans()
+	server := findSpan(spans, "GET /attendance/class-report")
+	if server == nil {
+		t.Fatalf("Expected a span named after the route, got %v", spans)
+	}
+	if server.SpanKind != trace.SpanKindServer || server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected a server span continuing the incoming trace, got kind %v in trace %s", server.SpanKind, server.SpanContext.TraceID())
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" || !server.Parent.IsRemote() {
+		t.Errorf("Expected the server span to be a child of the caller's span, got parent %s", server.Parent.SpanID())
+	}
+
+	auth := findSpan(spans, "auth")
+	if auth == nil || auth.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Fatalf("Expected an auth span under the server span, got %v", auth)
+	}
+
+	queries := 0
+	for _, span := range spans {
+		if span.SpanKind != trace.SpanKindClient || span.SpanContext.TraceID() != server.SpanContext.TraceID() {
+			continue
+		}
+		if parent := span.Parent.SpanID(); parent != server.SpanContext.SpanID() && parent != auth.SpanContext.SpanID() {
+			t.Errorf("Expected query span %q under the request, got parent %s", span.Name, parent)
+		}
+		queries++
+	}
+	if queries == 0 {
+		t.Error("Expected the queries of the request to be traced")
+	}
+}
+
+func TestTracing_UnmatchedRouteAndErrors(t *testing.T) {
+	exporter := setupTestTracing(t)
+	app := setupTestApp(t, tracing.Middleware)
+
+	resp, err := makeRequest(app, "GET", "/attendance/class-report", "", nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+	if _, err := makeRequest(app, "GET", "/no-such-route", testTeacherEmail, nil); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if auth := findSpan(spans, "auth"); auth == nil || auth.Status.Code != codes.Error {
+		t.Errorf("Expected a failed auth span for a missing token, got %v", auth)
+	}
+	if server := findSpan(spans, "HTTP GET"); server == nil {
+		t.Errorf("Expected an unmatched request to keep its generic span name, got %v", spans)
+	}
+}
+
+func TestTracing_JWKSFetch(t *testing.T) {
+	exporter := setupTestTracing(t)
+	setupTestApp(t)
+
+	var receivedTraceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedTraceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"keys": []}`))
+	}))
+	defer server.Close()
+	settings.Auth.SupabaseURL = server.URL
+
+	failures := metrics.JWKSFetchFailures
+	failuresBefore := readMetric(t, failures).GetCounter().GetValue()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	if err := fetchSupabasePublicKey(ctx); err == nil {
+		t.Fatal("Expected an empty key set to be refused")
+	}
+	parent.End()
+
+	fetch := findSpan(exporter.GetSpans(), "GET JWKS")
+	if fetch == nil {
+		t.Fatal("Expected a span for the JWKS fetch")
+	}
+	if fetch.SpanKind != trace.SpanKindClient || fetch.Parent.SpanID() != parent.SpanContext().SpanID() || fetch.Status.Code != codes.Error {
+		t.Errorf("Expected a failed client span under the caller's span, got %+v", fetch)
+	}
+	if receivedTraceparent == "" || receivedTraceparent[36:52] != fetch.SpanContext.SpanID().String() {
+		t.Errorf("Expected the JWKS request to carry the fetch span's traceparent, got %q", receivedTraceparent)
+	}
+	if readMetric(t, failures).GetCounter().GetValue()-failuresBefore != 1 {
+		t.Error("Expected the failed fetch to be counted")
+	}
+}
@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"skulla-api/db"
+	"skulla-api/webhook"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type WebhookSubscriptionRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+var validWebhookEvents = map[string]bool{
+	db.EventAttendanceRecorded: true,
+	db.EventAttendanceUpdated:  true,
+	db.EventAlertRaised:        true,
+}
+
+func validateWebhookSubscriptionRequest(ctx context.Context, req WebhookSubscriptionRequest) error {
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	if len(req.Secret) < 16 {
+		return fmt.Errorf("secret must be at least 16 characters")
+	}
+
+	if len(req.Events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+
+	for _, event := range req.Events {
+		if !validWebhookEvents[event] {
+			return fmt.Errorf("events must be any of: attendance.recorded, attendance.updated, alert.raised")
+		}
+	}
+
+	if !settings.Webhooks.AllowPrivateHosts {
+		if err := webhook.CheckHost(ctx, parsed.Hostname()); err != nil {
+			return fmt.Errorf("url must not point to a loopback, private or link-local address")
+		}
+	}
+
+	return nil
+}
+
+func ListWebhookSubscriptions(c *fiber.Ctx) error {
+	subscriptions := db.ListWebhookSubscriptions(c.UserContext())
+	return c.JSON(subscriptions)
+}
+
+func CreateWebhookSubscription(c *fiber.Ctx) error {
+	var req WebhookSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ReturnBadRequest(c, "Invalid request body")
+	}
+
+	if err := validateWebhookSubscriptionRequest(c.UserContext(), req); err != nil {
+		return ReturnBadRequest(c, err.Error())
+	}
+
+	userEmail, err := GetUserEmailFromToken(c)
+	if err != nil {
+		return ReturnUnauthorized(c, "Unable to extract user email from token")
+	}
+
+	subscription := db.WebhookSubscription{
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    strings.Join(req.Events, ","),
+		CreatedBy: userEmail,
+	}
+	if err := db.CreateWebhookSubscription(c.UserContext(), &subscription); err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to create webhook subscription")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(subscription)
+}
+
+func DeleteWebhookSubscription(c *fiber.Ctx) error {
+	subscriptionID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ReturnBadRequest(c, "invalid id format")
+	}
+
+	deleted, err := db.DeleteWebhookSubscription(c.UserContext(), uint(subscriptionID))
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to delete webhook subscription")
+	}
+	if !deleted {
+		return ReturnNotFound(c, "Webhook subscription not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func ListDeadWebhookDeliveries(c *fiber.Ctx) error {
+	deliveries := db.ListDeadWebhookDeliveries(c.UserContext())
+	return c.JSON(deliveries)
+}
+
+func ReplayWebhookDelivery(c *fiber.Ctx) error {
+	deliveryID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ReturnBadRequest(c, "invalid id format")
+	}
+
+	replayed, err := db.ReplayWebhookDelivery(c.UserContext(), uint(deliveryID))
+	if err != nil {
+		log.Error(err)
+		return ReturnInternalError(c, "Failed to replay webhook delivery")
+	}
+	if !replayed {
+		return ReturnNotFound(c, "Dead webhook delivery not found")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":     "Webhook delivery queued for replay",
+		"delivery_id": deliveryID,
+	})
+}
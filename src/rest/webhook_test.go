@@ -0,0 +1,318 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"skulla-api/db"
+	"skulla-api/webhook"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+type receivedWebhook struct {
+	event     string
+	signature string
+	envelope  webhook.Envelope
+	body      []byte
+}
+
+func startWebhookReceiver(t *testing.T, status int) (string, *[]receivedWebhook) {
+	var received []receivedWebhook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var envelope webhook.Envelope
+		json.Unmarshal(body, &envelope)
+		received = append(received, receivedWebhook{
+			event:     r.Header.Get(webhook.EventHeader),
+			signature: r.Header.Get(webhook.SignatureHeader),
+			envelope:  envelope,
+			body:      body,
+		})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &received
+}
+
+func subscribeTestWebhook(t *testing.T, app *fiber.App, url string, events []string) {
+	reqBody := map[string]interface{}{"url": url, "secret": testWebhookSecret, "events": events}
+
+	resp, err := makeRequest(app, "POST", "/webhooks", testTeacherEmail, reqBody)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestCreateWebhookSubscription_ValidationErrors(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []map[string]interface{}{
+		{"url": "not-a-url", "secret": testWebhookSecret, "events": []string{"attendance.recorded"}},
+		{"url": "https://lms.test/hook", "secret": "short", "events": []string{"attendance.recorded"}},
+		{"url": "https://lms.test/hook", "secret": testWebhookSecret, "events": []string{}},
+		{"url": "https://lms.test/hook", "secret": testWebhookSecret, "events": []string{"student.created"}},
+	}
+
+	for i, reqBody := range testCases {
+		resp, err := makeRequest(app, "POST", "/webhooks", testTeacherEmail, reqBody)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+
+		if resp.Code != fiber.StatusBadRequest {
+			t.Errorf("Test case %d: Expected status 400, got %d", i, resp.Code)
+		}
+	}
+}
+
+func TestCreateWebhookSubscription_RejectsPrivateHosts(t *testing.T) {
+	app := setupTestApp(t)
+	settings.Webhooks.AllowPrivateHosts = false
+
+	for _, url := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.20/hook",
+		"http://169.254.169.254/latest/meta-data",
+	} {
+		reqBody := map[string]interface{}{"url": url, "secret": testWebhookSecret, "events": []string{db.EventAttendanceRecorded}}
+		resp, err := makeRequest(app, "POST", "/webhooks", testTeacherEmail, reqBody)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if resp.Code != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, resp.Code)
+		}
+	}
+}
+
+func TestWebhooks_RequireAdmin(t *testing.T) {
+	app := setupTestApp(t)
+
+	testCases := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/webhooks"},
+		{"POST", "/webhooks"},
+		{"DELETE", "/webhooks/1"},
+		{"GET", "/webhooks/dead-letters"},
+		{"POST", "/webhooks/deliveries/1/replay"},
+	}
+
+	for _, email := range []string{testTeacherEmail2, testStudentEmail} {
+		for _, tc := range testCases {
+			resp, err := makeRequest(app, tc.method, tc.path, email, nil)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Code != fiber.StatusUnauthorized {
+				t.Errorf("%s %s as %s: expected status 401, got %d", tc.method, tc.path, email, resp.Code)
+			}
+		}
+	}
+}
+
+func TestWebhooks_DeliversSignedAttendanceEvents(t *testing.T) {
+	app := setupTestApp(t)
+	url, received := startWebhookReceiver(t, http.StatusOK)
+	subscribeTestWebhook(t, app, url, []string{db.EventAttendanceRecorded, db.EventAttendanceUpdated})
+
+	records := []map[string]interface{}{
+		{"registration_id": 1, "date": "2024-01-20", "status": "PRESENT"},
+		{"registration_id": 1, "date": "2024-01-15", "status": "LATE"},
+	}
+
+	resp, err := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, records)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	dispatcher := &webhook.Dispatcher{MaxAttempts: 3, AllowPrivateHosts: true}
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", delivered)
+	}
+
+	if len(*received) != 2 {
+		t.Fatalf("Expected 2 webhooks received, got %d", len(*received))
+	}
+
+	recorded, updated := (*received)[0], (*received)[1]
+	if recorded.event != db.EventAttendanceRecorded || updated.event != db.EventAttendanceUpdated {
+		t.Errorf("Unexpected events: %s, %s", recorded.event, updated.event)
+	}
+
+	var payload db.AttendanceEventPayload
+	if err := json.Unmarshal(updated.envelope.Data, &payload); err != nil {
+		t.Fatalf("Failed to parse payload: %v", err)
+	}
+	if payload.PreviousStatus != "PRESENT" || payload.Status != "LATE" || payload.RecordedBy != testTeacherEmail {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+
+	for _, hook := range *received {
+		if hook.signature != webhook.Sign(testWebhookSecret, hook.body) {
+			t.Errorf("Invalid signature for %s", hook.event)
+		}
+	}
+
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != 0 {
+		t.Errorf("Expected no redelivery, got %d", delivered)
+	}
+}
+
+func TestWebhooks_SkipsDeliveriesClaimedByAnotherDispatcher(t *testing.T) {
+	app := setupTestApp(t)
+	url, received := startWebhookReceiver(t, http.StatusOK)
+	subscribeTestWebhook(t, app, url, []string{db.EventAttendanceRecorded})
+
+	records := []map[string]interface{}{{"registration_id": 1, "date": "2024-01-20", "status": "PRESENT"}}
+	resp, _ := makeRequest(app, "POST", "/attendance/bulk", testTeacherEmail, records)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if _, err := db.FanOutOutboxEvents(testSchoolContext()); err != nil {
+		t.Fatalf("Failed to fan out outbox events: %v", err)
+	}
+	now := time.Now()
+	deliveries := db.ListDueWebhookDeliveries(testSchoolContext(), now)
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 due delivery, got %d", len(deliveries))
+	}
+	if claimed, err := db.ClaimWebhookDelivery(testSchoolContext(), deliveries[0].ID, now, now.Add(time.Minute)); err != nil || !claimed {
+		t.Fatalf("Expected to claim the delivery, got %v, %v", claimed, err)
+	}
+	if claimed, _ := db.ClaimWebhookDelivery(testSchoolContext(), deliveries[0].ID, now, now.Add(time.Minute)); claimed {
+		t.Fatal("Expected a claimed delivery not to be claimed again")
+	}
+
+	dispatcher := &webhook.Dispatcher{MaxAttempts: 3, AllowPrivateHosts: true}
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != 0 || len(*received) != 0 {
+		t.Errorf("Expected the claimed delivery to be left alone, got %d delivered", delivered)
+	}
+
+	dispatcher.Now = func() time.Time { return now.Add(2 * time.Minute) }
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != 1 {
+		t.Errorf("Expected the delivery to be retried once the claim expired, got %d", delivered)
+	}
+}
+
+func TestWebhooks_HeldBackUntilRegisterIsSubmitted(t *testing.T) {
+	app := setupTestApp(t)
+	url, received := startWebhookReceiver(t, http.StatusOK)
+	subscribeTestWebhook(t, app, url, []string{db.EventAttendanceRecorded})
+
+	reqBody := map[string]interface{}{"student_class_id": 1, "date": "2024-02-01", "copy_from": "2024-01-16", "status": "PRESENT"}
+	resp, _ := makeRequest(app, "POST", "/attendance/class", testTeacherEmail, reqBody)
+	if resp.Code != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	dispatcher := &webhook.Dispatcher{MaxAttempts: 3, AllowPrivateHosts: true}
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != 0 {
+		t.Fatalf("Expected no webhooks for a draft register, got %d", delivered)
+	}
+
+	resp, _ = makeRequest(app, "POST", "/student-classes/1/registers/2024-02-01/submit", testTeacherEmail, nil)
+	if resp.Code != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != 3 {
+		t.Fatalf("Expected a webhook per registration after submitting, got %d", delivered)
+	}
+	var payload db.AttendanceEventPayload
+	json.Unmarshal((*received)[0].envelope.Data, &payload)
+	if payload.RegistrationID != 1 || payload.Status != "ABSENT" || payload.Remarks != "Sick" || payload.RecordedBy != testTeacherEmail {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestWebhooks_DeadLetterAndReplay(t *testing.T) {
+	app := setupTestApp(t)
+	url, received := startWebhookReceiver(t, http.StatusInternalServerError)
+	subscribeTestWebhook(t, app, url, []string{db.EventAttendanceRecorded})
+
+	reqBody := map[string]interface{}{"registration_id": 1, "date": "2024-01-20", "status": "PRESENT"}
+	if _, err := makeRequest(app, "POST", "/attendance", testTeacherEmail, reqBody); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	dispatcher := &webhook.Dispatcher{MaxAttempts: 1, AllowPrivateHosts: true}
+	dispatcher.Dispatch(testSchoolContext())
+	if len(*received) != 1 {
+		t.Fatalf("Expected 1 delivery attempt, got %d", len(*received))
+	}
+
+	resp, err := makeRequest(app, "GET", "/webhooks/dead-letters", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var dead []db.WebhookDelivery
+	if err := json.Unmarshal(resp.Body.Bytes(), &dead); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 1 || dead[0].LastError == "" {
+		t.Fatalf("Expected 1 dead delivery, got %+v", dead)
+	}
+
+	replayPath := fmt.Sprintf("/webhooks/deliveries/%d/replay", dead[0].ID)
+	resp, err = makeRequest(app, "POST", replayPath, testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d. Body: %s", resp.Code, resp.Body.String())
+	}
+
+	dispatcher.Dispatch(testSchoolContext())
+	if len(*received) != 2 {
+		t.Errorf("Expected the replayed delivery to be attempted again, got %d attempts", len(*received))
+	}
+
+	resp, err = makeRequest(app, "POST", "/webhooks/deliveries/9999/replay", testTeacherEmail, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Code != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestEvaluateAlerts_WritesAlertRaisedEvents(t *testing.T) {
+	app := setupTestApp(t)
+	url, received := startWebhookReceiver(t, http.StatusOK)
+	subscribeTestWebhook(t, app, url, []string{db.EventAlertRaised})
+
+	alerts := evaluateTestAlerts(t)
+
+	dispatcher := &webhook.Dispatcher{MaxAttempts: 3, AllowPrivateHosts: true}
+	if delivered := dispatcher.Dispatch(testSchoolContext()); delivered != len(alerts) {
+		t.Errorf("Expected %d alert webhooks, got %d", len(alerts), delivered)
+	}
+
+	for _, hook := range *received {
+		if hook.event != db.EventAlertRaised {
+			t.Errorf("Unexpected event %s", hook.event)
+		}
+	}
+}
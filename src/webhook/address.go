@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for webhook URLs that point into the
+// server's own network.
+var ErrPrivateAddress = errors.New("webhook host must be a public address")
+
+// PublicIP reports whether ip is routable on the internet, so that a webhook
+// cannot reach loopback, private, link-local or cloud metadata addresses.
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// CheckHost returns ErrPrivateAddress when host is localhost or resolves to
+// an address that is not public. A host that does not resolve is accepted,
+// since the dispatcher checks the address again on every delivery.
+func CheckHost(ctx context.Context, host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !PublicIP(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, address := range addresses {
+		if !PublicIP(address.IP) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// publicClient refuses to connect to addresses that are not public. The
+// check runs on the resolved address of every connection, including
+// redirects, so a host cannot be rebound to a private address after its
+// subscription was validated.
+var publicClient = newPublicClient()
+
+// privateClient connects anywhere and is used when AllowPrivateHosts is set.
+var privateClient = &http.Client{Timeout: 10 * time.Second}
+
+func newPublicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
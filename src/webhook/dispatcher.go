@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"skulla-api/db"
+	"strconv"
+	"time"
+)
+
+const (
+	EventHeader     = "X-Omniscience-Event"
+	DeliveryHeader  = "X-Omniscience-Delivery"
+	SignatureHeader = "X-Omniscience-Signature"
+)
+
+const maxRetryDelay = 24 * time.Hour
+
+// deliveryLease is how long a claimed delivery is held back from other
+// dispatchers before it is retried.
+const deliveryLease = 5 * time.Minute
+
+type Envelope struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the value of the signature header for body: the hex-encoded
+// HMAC-SHA256 of the body keyed with the subscription secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher fans outbox events out to subscriptions and delivers them.
+// Without a Client it only connects to public addresses, unless
+// AllowPrivateHosts is set.
+type Dispatcher struct {
+	MaxAttempts       int
+	AllowPrivateHosts bool
+	Client            *http.Client
+	Now               func() time.Time
+}
+
+func (d *Dispatcher) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// retryDelay doubles with every attempt: 1, 2, 4, ... minutes, capped at a day.
+func retryDelay(attempt int) time.Duration {
+	delay := time.Minute << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// Dispatch delivers every due webhook and returns how many succeeded.
+func (d *Dispatcher) Dispatch(ctx context.Context) int {
+	if _, err := db.FanOutOutboxEvents(ctx); err != nil {
+		log.Println("Failed to fan out outbox events:", err)
+	}
+
+	now := d.now()
+	delivered := 0
+	for _, delivery := range db.ListDueWebhookDeliveries(ctx, now) {
+		claimed, err := db.ClaimWebhookDelivery(ctx, delivery.ID, now, now.Add(deliveryLease))
+		if err != nil {
+			log.Println("Failed to claim webhook delivery:", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := d.deliver(ctx, delivery); err != nil {
+			log.Printf("Failed to deliver webhook %d to %s: %v", delivery.ID, delivery.Subscription.URL, err)
+			next := now.Add(retryDelay(delivery.Attempts + 1))
+			if markErr := db.MarkWebhookDeliveryFailed(ctx, delivery.ID, err, next, d.MaxAttempts); markErr != nil {
+				log.Println("Failed to record webhook failure:", markErr)
+			}
+			continue
+		}
+
+		if err := db.MarkWebhookDeliveryDelivered(ctx, delivery.ID, now); err != nil {
+			log.Println("Failed to record webhook delivery:", err)
+		}
+		delivered++
+	}
+	return delivered
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery db.WebhookDelivery) error {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.OutboxEvent.ID,
+		Type:      delivery.OutboxEvent.EventType,
+		CreatedAt: delivery.OutboxEvent.CreatedAt,
+		Data:      json.RawMessage(delivery.OutboxEvent.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.OutboxEvent.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Subscription.Secret, body))
+
+	client := d.Client
+	if client == nil && d.AllowPrivateHosts {
+		client = privateClient
+	} else if client == nil {
+		client = publicClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
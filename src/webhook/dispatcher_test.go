@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"skulla-api/db"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// Reference value computed with: printf '{"id":1}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=03def589620c813f198fd03d7967e292b163ef0435ebf43071ce0e9519763cb7"
+	if signature := Sign("secret", []byte(`{"id":1}`)); signature != expected {
+		t.Errorf("Expected %s, got %s", expected, signature)
+	}
+
+	if Sign("secret", []byte("a")) == Sign("other", []byte("a")) {
+		t.Error("Expected different secrets to produce different signatures")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	testCases := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		5:  16 * time.Minute,
+		20: maxRetryDelay,
+		80: maxRetryDelay,
+	}
+
+	for attempt, expected := range testCases {
+		if delay := retryDelay(attempt); delay != expected {
+			t.Errorf("Attempt %d: expected %v, got %v", attempt, expected, delay)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	testCases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.0.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"0.0.0.0":         false,
+		"::ffff:10.0.0.1": false,
+	}
+
+	for address, expected := range testCases {
+		if public := PublicIP(net.ParseIP(address)); public != expected {
+			t.Errorf("%s: expected public %v, got %v", address, expected, public)
+		}
+	}
+}
+
+func TestDeliver_RefusesPrivateAddresses(t *testing.T) {
+	received := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer server.Close()
+
+	delivery := db.WebhookDelivery{
+		Subscription: db.WebhookSubscription{URL: server.URL, Secret: "secret"},
+		OutboxEvent:  db.OutboxEvent{ID: 1, EventType: db.EventAttendanceRecorded, Payload: "{}"},
+	}
+
+	dispatcher := &Dispatcher{MaxAttempts: 1}
+	if err := dispatcher.deliver(context.Background(), delivery); !errors.Is(err, ErrPrivateAddress) || received {
+		t.Errorf("Expected delivery to a loopback address to be refused, got %v", err)
+	}
+
+	dispatcher.AllowPrivateHosts = true
+	if err := dispatcher.deliver(context.Background(), delivery); err != nil || !received {
+		t.Errorf("Expected delivery when private hosts are allowed, got %v", err)
+	}
+}